@@ -0,0 +1,211 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Config holds every setting of the backend. Each field is named by its
+// environment variable; a config file uses the same names in lower case.
+type Config struct {
+	Port      int    `env:"PORT"`
+	DBURL     string `env:"DB_URL"`
+	JWTSecret string `env:"JWT_SECRET"`
+
+	// PublicURL is where the embedding service can fetch uploads from
+	// outside of a request. It defaults to localhost on PORT.
+	PublicURL   string `env:"PUBLIC_URL"`
+	AutoMigrate bool   `env:"AUTO_MIGRATE"`
+
+	SMTPHost string `env:"SMTP_HOST"`
+	SMTPPort string `env:"SMTP_PORT"`
+	SMTPUser string `env:"SMTP_USER"`
+	SMTPPass string `env:"SMTP_PASS"`
+
+	MaxUploadBytes         int64  `env:"MAX_UPLOAD_BYTES"`
+	BatchUploadMaxFiles    int    `env:"BATCH_UPLOAD_MAX_FILES"`
+	BatchUploadConcurrency int    `env:"BATCH_UPLOAD_CONCURRENCY"`
+	TusUploadDir           string `env:"TUS_UPLOAD_DIR"`
+	VideoKeyframes         int    `env:"VIDEO_KEYFRAMES"`
+	// VideoTimeout bounds probing a video and rendering its frames.
+	VideoTimeout time.Duration `env:"VIDEO_TIMEOUT"`
+	// TusUploadExpiry is how long a tus upload is kept after its last
+	// request, finished or not.
+	TusUploadExpiry time.Duration `env:"TUS_UPLOAD_EXPIRY"`
+
+	AnalysisWorkers       int           `env:"ANALYSIS_WORKERS"`
+	AnalysisQueueSize     int           `env:"ANALYSIS_QUEUE_SIZE"`
+	AnalysisJobTimeout    time.Duration `env:"ANALYSIS_JOB_TIMEOUT"`
+	AnalysisModelVersion  string        `env:"ANALYSIS_MODEL_VERSION"`
+	AnalysisMaxSegments   int           `env:"ANALYSIS_MAX_SEGMENTS"`
+	AnalyzeMaxBytes       int64         `env:"ANALYZE_MAX_BYTES"`
+	AnalyzeConnectTimeout time.Duration `env:"ANALYZE_CONNECT_TIMEOUT"`
+	AnalyzeReadTimeout    time.Duration `env:"ANALYZE_READ_TIMEOUT"`
+	AnalyzeMaxDuration    time.Duration `env:"ANALYZE_MAX_DURATION"`
+	AnalyzeExtractors     string        `env:"ANALYZE_YTDLP_EXTRACTORS"`
+
+	InferenceDriver string `env:"INFERENCE_DRIVER"`
+	InferenceURL    string `env:"INFERENCE_URL"`
+	InferenceScript string `env:"INFERENCE_SCRIPT"`
+
+	EmbeddingServiceURL string `env:"EMBEDDING_SERVICE_URL"`
+	// EmbedTimeout bounds a single call; captioning a large photo on CPU
+	// can take a while.
+	EmbedTimeout         time.Duration `env:"EMBED_TIMEOUT"`
+	EmbedBreakerFailures int           `env:"EMBED_BREAKER_FAILURES"`
+	EmbedBreakerCooldown time.Duration `env:"EMBED_BREAKER_COOLDOWN"`
+	// EmbedBatchSize is the most photos sent in one /embed_batch call; 1
+	// turns batching off. EmbedBatchWaitMS is how long the first photo of a
+	// batch waits for others.
+	EmbedBatchSize   int `env:"EMBED_BATCH_SIZE"`
+	EmbedBatchWaitMS int `env:"EMBED_BATCH_WAIT_MS"`
+
+	SearchKeywordWeight  float64 `env:"SEARCH_KEYWORD_WEIGHT"`
+	SearchSemanticWeight float64 `env:"SEARCH_SEMANTIC_WEIGHT"`
+	SearchRRFK           float64 `env:"SEARCH_RRF_K"`
+
+	VectorBackend    string `env:"VECTOR_BACKEND"`
+	QdrantURL        string `env:"QDRANT_URL"`
+	QdrantCollection string `env:"QDRANT_COLLECTION"`
+	QdrantAPIKey     string `env:"QDRANT_API_KEY"`
+	PgVectorTable    string `env:"PGVECTOR_TABLE"`
+
+	ReindexConcurrency int `env:"REINDEX_CONCURRENCY"`
+
+	// FsckGrace is how old an unreferenced file must be to count as an
+	// orphan, so uploads still being recorded are left alone. Orphans are
+	// quarantined to FsckQuarantineDir, outside uploads/ so they aren't
+	// served.
+	FsckGrace         time.Duration `env:"FSCK_GRACE"`
+	FsckQuarantineDir string        `env:"FSCK_QUARANTINE_DIR"`
+}
+
+// minJWTSecret is the shortest JWT_SECRET accepted: HS256 wants a key at
+// least as long as its 256-bit hash.
+const minJWTSecret = 32
+
+// Defaults returns the settings used for anything left unset. DB_URL and
+// JWT_SECRET have no default.
+func Defaults() *Config {
+	return &Config{
+		Port: 8000,
+
+		MaxUploadBytes:         200 << 20,
+		BatchUploadMaxFiles:    50,
+		BatchUploadConcurrency: 4,
+		TusUploadDir:           "tus_uploads",
+		VideoKeyframes:         4,
+		VideoTimeout:           2 * time.Minute,
+		TusUploadExpiry:        24 * time.Hour,
+
+		AnalysisWorkers:       2,
+		AnalysisQueueSize:     100,
+		AnalysisJobTimeout:    10 * time.Minute,
+		AnalysisModelVersion:  "v1",
+		AnalysisMaxSegments:   2000,
+		AnalyzeMaxBytes:       100 << 20,
+		AnalyzeConnectTimeout: 5 * time.Second,
+		AnalyzeReadTimeout:    30 * time.Second,
+		AnalyzeMaxDuration:    time.Hour,
+		AnalyzeExtractors:     "youtube,soundcloud,vimeo",
+
+		InferenceDriver: "subprocess",
+		InferenceScript: "microservice/main.py",
+
+		EmbeddingServiceURL:  "http://localhost:5000",
+		EmbedTimeout:         2 * time.Minute,
+		EmbedBreakerFailures: 5,
+		EmbedBreakerCooldown: 30 * time.Second,
+		EmbedBatchSize:       8,
+		EmbedBatchWaitMS:     20,
+
+		SearchKeywordWeight:  1,
+		SearchSemanticWeight: 1,
+		SearchRRFK:           60,
+
+		VectorBackend:    "qdrant",
+		QdrantURL:        "http://127.0.0.1:6333",
+		QdrantCollection: "photos",
+		PgVectorTable:    "photo_embeddings",
+
+		ReindexConcurrency: 4,
+		FsckGrace:          time.Hour,
+		FsckQuarantineDir:  "quarantine",
+	}
+}
+
+// normalize fills in the settings derived from others and tidies values
+// compared case-insensitively.
+func (c *Config) normalize() {
+	c.VectorBackend = strings.ToLower(c.VectorBackend)
+	c.InferenceDriver = strings.ToLower(c.InferenceDriver)
+	c.EmbeddingServiceURL = strings.TrimRight(c.EmbeddingServiceURL, "/")
+	c.PublicURL = strings.TrimRight(c.PublicURL, "/")
+	if c.PublicURL == "" {
+		c.PublicURL = fmt.Sprintf("http://localhost:%d", c.Port)
+	}
+}
+
+// Validate reports every invalid setting at once.
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(c.DBURL != "", "DB_URL is required")
+	check(c.JWTSecret != "", "JWT_SECRET is required")
+	check(c.JWTSecret == "" || len(c.JWTSecret) >= minJWTSecret,
+		"JWT_SECRET must be at least %d characters, got %d", minJWTSecret, len(c.JWTSecret))
+	check(c.Port > 0 && c.Port <= 65535, "PORT must be between 1 and 65535")
+
+	positive := map[string]int64{
+		"MAX_UPLOAD_BYTES":         c.MaxUploadBytes,
+		"BATCH_UPLOAD_MAX_FILES":   int64(c.BatchUploadMaxFiles),
+		"BATCH_UPLOAD_CONCURRENCY": int64(c.BatchUploadConcurrency),
+		"VIDEO_KEYFRAMES":          int64(c.VideoKeyframes),
+		"VIDEO_TIMEOUT":            int64(c.VideoTimeout),
+		"TUS_UPLOAD_EXPIRY":        int64(c.TusUploadExpiry),
+		"ANALYSIS_WORKERS":         int64(c.AnalysisWorkers),
+		"ANALYSIS_QUEUE_SIZE":      int64(c.AnalysisQueueSize),
+		"ANALYSIS_JOB_TIMEOUT":     int64(c.AnalysisJobTimeout),
+		"ANALYSIS_MAX_SEGMENTS":    int64(c.AnalysisMaxSegments),
+		"ANALYZE_MAX_BYTES":        c.AnalyzeMaxBytes,
+		"ANALYZE_CONNECT_TIMEOUT":  int64(c.AnalyzeConnectTimeout),
+		"ANALYZE_READ_TIMEOUT":     int64(c.AnalyzeReadTimeout),
+		"ANALYZE_MAX_DURATION":     int64(c.AnalyzeMaxDuration),
+		"EMBED_TIMEOUT":            int64(c.EmbedTimeout),
+		"EMBED_BREAKER_FAILURES":   int64(c.EmbedBreakerFailures),
+		"EMBED_BREAKER_COOLDOWN":   int64(c.EmbedBreakerCooldown),
+		"EMBED_BATCH_SIZE":         int64(c.EmbedBatchSize),
+		"REINDEX_CONCURRENCY":      int64(c.ReindexConcurrency),
+	}
+	for _, name := range slices.Sorted(maps.Keys(positive)) {
+		check(positive[name] > 0, "%s must be positive", name)
+	}
+	check(c.EmbedBatchWaitMS >= 0, "EMBED_BATCH_WAIT_MS must not be negative")
+	check(c.FsckGrace >= 0, "FSCK_GRACE must not be negative")
+	check(c.SearchKeywordWeight >= 0, "SEARCH_KEYWORD_WEIGHT must not be negative")
+	check(c.SearchSemanticWeight >= 0, "SEARCH_SEMANTIC_WEIGHT must not be negative")
+	check(c.SearchRRFK > 0, "SEARCH_RRF_K must be positive")
+
+	switch c.VectorBackend {
+	case "qdrant", "pgvector", "memory":
+	default:
+		check(false, "VECTOR_BACKEND must be qdrant, pgvector or memory, got %q", c.VectorBackend)
+	}
+	switch c.InferenceDriver {
+	case "subprocess", "fake":
+	case "http":
+		check(c.InferenceURL != "", "INFERENCE_URL is required when INFERENCE_DRIVER is http")
+	default:
+		check(false, "INFERENCE_DRIVER must be subprocess, http or fake, got %q", c.InferenceDriver)
+	}
+	return errors.Join(errs...)
+}
@@ -10,11 +10,7 @@ import (
 
 var DB *pgxpool.Pool
 
-func ConnectPSQL() {
-	connURL := os.Getenv("DB_URL")
-
-	fmt.Println(connURL)
-
+func ConnectPSQL(connURL string) {
 	var err error
 	DB, err = pgxpool.New(context.Background(), connURL)
 
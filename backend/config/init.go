@@ -1,6 +0,0 @@
-package config
-
-func Init() {
-	LoadEnv()
-	ConnectPSQL()
-}
@@ -0,0 +1,148 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testSecret = "0123456789abcdef0123456789abcdef"
+
+func TestLoadPrecedence(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string // config.yaml, or config.toml when it starts with "#toml"
+		dotenv  string
+		env     map[string]string
+		check   func(*Config) bool
+		wantErr string
+	}{
+		{
+			name:  "defaults",
+			check: func(c *Config) bool { return c.Port == 8000 && c.PublicURL == "http://localhost:8000" },
+		},
+		{
+			name:  "file over defaults",
+			file:  "port: 9000\nembed_timeout: 45s\n",
+			check: func(c *Config) bool { return c.Port == 9000 && c.EmbedTimeout == 45*time.Second },
+		},
+		{
+			name:   ".env over the file",
+			file:   "port: 9000\nqdrant_collection: from_file\n",
+			dotenv: "PORT=9100\n",
+			check: func(c *Config) bool {
+				return c.Port == 9100 && c.QdrantCollection == "from_file"
+			},
+		},
+		{
+			name:   "environment over .env",
+			file:   "port: 9000\n",
+			dotenv: "PORT=9100\nREINDEX_CONCURRENCY=7\n",
+			env:    map[string]string{"PORT": "9200"},
+			check: func(c *Config) bool {
+				return c.Port == 9200 && c.ReindexConcurrency == 7 && c.PublicURL == "http://localhost:9200"
+			},
+		},
+		{
+			name:   "empty values are unset",
+			dotenv: "PORT=9100\n",
+			env:    map[string]string{"PORT": ""},
+			check:  func(c *Config) bool { return c.Port == 9100 },
+		},
+		{
+			name:   "CONFIG_FILE from .env",
+			file:   "port: 9000\n",
+			dotenv: "CONFIG_FILE=config.yaml\n",
+			env:    map[string]string{"CONFIG_FILE": ""},
+			check:  func(c *Config) bool { return c.Port == 9000 },
+		},
+		{
+			name: "toml with a list",
+			file: "#toml\nanalyze_ytdlp_extractors = [\"youtube\", \"vimeo\"]\nsearch_rrf_k = 10.5\nauto_migrate = true\n",
+			check: func(c *Config) bool {
+				return c.AnalyzeExtractors == "youtube,vimeo" && c.SearchRRFK == 10.5 && c.AutoMigrate
+			},
+		},
+		{
+			name: "normalized",
+			env:  map[string]string{"VECTOR_BACKEND": "PgVector", "PUBLIC_URL": "https://photos.example.com/"},
+			check: func(c *Config) bool {
+				return c.VectorBackend == "pgvector" && c.PublicURL == "https://photos.example.com"
+			},
+		},
+		{name: "unknown file setting", file: "prot: 9000\n", wantErr: `unknown setting "prot"`},
+		{name: "nested file setting", file: "port:\n  http: 1\n", wantErr: "port must be a single value"},
+		{name: "bad value names its source", env: map[string]string{"EMBED_TIMEOUT": "soon"}, wantErr: "EMBED_TIMEOUT from the environment"},
+		{name: "bad .env value", dotenv: "PORT=eighty\n", wantErr: "PORT from .env"},
+		{name: "validated", env: map[string]string{"JWT_SECRET": "short"}, wantErr: "JWT_SECRET must be at least 32 characters"},
+		{name: "missing required", env: map[string]string{"DB_URL": ""}, wantErr: "DB_URL is required"},
+		{name: "http inference needs a url", env: map[string]string{"INFERENCE_DRIVER": "http"}, wantErr: "INFERENCE_URL is required"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			chdir(t, dir)
+
+			env := map[string]string{"DB_URL": "postgres://localhost/memora", "JWT_SECRET": testSecret}
+			for name := range settings() {
+				if _, ok := env[name]; !ok {
+					env[name] = ""
+				}
+			}
+			env["CONFIG_FILE"] = ""
+			if tt.file != "" {
+				name := "config.yaml"
+				if strings.HasPrefix(tt.file, "#toml") {
+					name = "config.toml"
+				}
+				writeFile(t, filepath.Join(dir, name), tt.file)
+				env["CONFIG_FILE"] = name
+			}
+			if tt.dotenv != "" {
+				writeFile(t, filepath.Join(dir, ".env"), tt.dotenv)
+			}
+			for k, v := range tt.env {
+				env[k] = v
+			}
+			for k, v := range env {
+				t.Setenv(k, v)
+			}
+
+			cfg, err := Load()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got error %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !tt.check(cfg) {
+				t.Errorf("got %+v", cfg)
+			}
+		})
+	}
+}
+
+func chdir(t *testing.T, dir string) {
+	t.Helper()
+	prev, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(prev) })
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
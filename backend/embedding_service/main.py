@@ -5,9 +5,9 @@ from flask import Flask, request, jsonify
 from PIL import Image
 from sentence_transformers import SentenceTransformer
 from transformers import BlipProcessor, BlipForConditionalGeneration
-from qdrant_client import QdrantClient
-from qdrant_client.http.exceptions import UnexpectedResponse
 
+# This service only turns images and text into vectors. Storing and searching
+# them is done by the Go backend through its vector index.
 app = Flask(__name__)
 
 processor = BlipProcessor.from_pretrained("Salesforce/blip-image-captioning-large")
@@ -15,49 +15,32 @@ blip = BlipForConditionalGeneration.from_pretrained("Salesforce/blip-image-capti
 
 clip_model = SentenceTransformer("clip-ViT-L-14")
 
-QDRANT_URL = os.getenv("QDRANT_URL", "http://127.0.0.1:6333")
-COLLECTION = os.getenv("COLLECTION_NAME", "photos")
-RESET_FLAG = os.getenv("RESET_PHOTOS_COLLECTION", "false").lower() == "true"
+def load_image(src):
+    if src.startswith("http"):
+        resp = requests.get(src)
+        return Image.open(io.BytesIO(resp.content)).convert("RGB")
+    return Image.open(src).convert("RGB")
 
-qdrant = QdrantClient(url=QDRANT_URL)
-
-dummy_embedding = clip_model.encode("test", convert_to_numpy=True)
-embedding_size = len(dummy_embedding)
-
-vectors_config = {
-    "size": embedding_size,
-    "distance": "Cosine",
-}
-
-if RESET_FLAG:
-    qdrant.recreate_collection(collection_name=COLLECTION, vectors_config=vectors_config)
-else:
-    try:
-        qdrant.get_collection(collection_name=COLLECTION)
-    except UnexpectedResponse:
-        qdrant.create_collection(collection_name=COLLECTION, vectors_config=vectors_config)
-
-count = qdrant.count(collection_name=COLLECTION).count
-print(f"Qdrant collection {COLLECTION} has {count} points")
-
-def create_multimodal_embedding(image, caption, note="", city=""):
-    img_emb = clip_model.encode([image], convert_to_numpy=True)[0]
-    
+def embedding_text(caption, note="", city=""):
     text_parts = [caption]
     if note.strip():
         text_parts.append(note.strip())
     if city.strip():
         text_parts.append(f"location: {city.strip()}")
-    
-    combined_text = " | ".join(text_parts)
-    text_emb = clip_model.encode([combined_text], convert_to_numpy=True)[0]
-    
+    return " | ".join(text_parts)
+
+def combine_embeddings(img_emb, text_emb):
     combined = 0.7 * img_emb + 0.3 * text_emb
-    
     return combined.tolist()
 
-MIN_SIMILARITY = float(os.getenv("SEARCH_MIN_SIMILARITY", "0.0"))
-K_RESULTS = int(os.getenv("K_RESULTS", "50"))
+def create_multimodal_embedding(images, caption, note="", city=""):
+    img_emb = clip_model.encode(images, convert_to_numpy=True).mean(axis=0)
+    text_emb = clip_model.encode([embedding_text(caption, note, city)], convert_to_numpy=True)[0]
+    return combine_embeddings(img_emb, text_emb)
+
+@app.route("/health", methods=["GET"])
+def health():
+    return jsonify({"status": "ok"}), 200
 
 @app.route("/embed", methods=["POST"])
 def embed():
@@ -65,63 +48,77 @@ def embed():
     img_src = data["image_path"]
     note = data.get("note", "")
     city = data.get("city", "")
-    point_id = data["id"]
-
-    if img_src.startswith("http"):
-        resp = requests.get(img_src)
-        image = Image.open(io.BytesIO(resp.content)).convert("RGB")
-    else:
-        image = Image.open(img_src).convert("RGB")
-
-    inputs = processor(images=image, return_tensors="pt")
-    out = blip.generate(**inputs)
-    caption = processor.decode(out[0], skip_special_tokens=True)
-
-    vect = create_multimodal_embedding(image, caption, note, city)
-
-    payload = {
-        "note": note,
-        "caption": caption,
-        "city": city,
-    }
-
-    qdrant.upsert(
-        collection_name=COLLECTION,
-        points=[{"id": point_id, "vector": vect, "payload": payload}],
-    )
-    return jsonify({"status": "ok"}), 200
 
-@app.route("/search", methods=["GET"])
-def search():
-    query = request.args.get("q", "").strip()
-    k = int(request.args.get("k", "50"))
-
-    text_embedding = clip_model.encode([query], convert_to_numpy=True)[0]
-    qvec = text_embedding.tolist()
-
-    query_filter = None
-
-    resp = qdrant.search(
-        collection_name=COLLECTION,
-        query_vector=qvec,
-        query_filter=query_filter,
-        limit=k,
-        with_payload=True,
-        score_threshold=MIN_SIMILARITY,
-    )
-
-    hits = []
-    for h in resp:
-        print(h)
-        hits.append({
-            "id": h.id,
-            "score": round(h.score, 4),
-            "note": h.payload.get("note"),
-            "caption": h.payload.get("caption"),
-            "city": h.payload.get("city"),
-        })
-    
-    return jsonify(hits[:10]), 200
+    # Videos send their keyframes in image_paths; image_path is the poster
+    # frame used for the caption.
+    image = load_image(img_src)
+    frames = [load_image(src) for src in data.get("image_paths") or []] or [image]
+
+    # A caption written by the user replaces the generated one.
+    caption = (data.get("caption") or "").strip()
+    if not caption:
+        inputs = processor(images=image, return_tensors="pt")
+        out = blip.generate(**inputs)
+        caption = processor.decode(out[0], skip_special_tokens=True)
+
+    vect = create_multimodal_embedding(frames, caption, note, city)
+    return jsonify({"vector": vect, "caption": caption, "dims": len(vect)}), 200
+
+# Takes {"items": [...]} with the same fields as /embed and answers with one
+# result per item, in order: {"vector", "caption", "dims"} or {"error"}. Captioning
+# and CLIP encoding run once for the whole batch.
+@app.route("/embed_batch", methods=["POST"])
+def embed_batch():
+    items = (request.json or {}).get("items") or []
+    results = [None] * len(items)
+
+    loaded = []
+    for i, item in enumerate(items):
+        try:
+            image = load_image(item["image_path"])
+            frames = [load_image(src) for src in item.get("image_paths") or []] or [image]
+            loaded.append((i, item, image, frames))
+        except Exception as e:
+            results[i] = {"error": f"could not load image: {e}"}
+
+    if loaded:
+        captions = [(item.get("caption") or "").strip() for _, item, _, _ in loaded]
+        todo = [n for n, caption in enumerate(captions) if not caption]
+        if todo:
+            inputs = processor(images=[loaded[n][2] for n in todo], return_tensors="pt")
+            out = blip.generate(**inputs)
+            for n, caption in zip(todo, processor.batch_decode(out, skip_special_tokens=True)):
+                captions[n] = caption
+
+        frame_embs = clip_model.encode([f for _, _, _, frames in loaded for f in frames], convert_to_numpy=True)
+        text_embs = clip_model.encode(
+            [embedding_text(caption, item.get("note", ""), item.get("city", ""))
+             for (_, item, _, _), caption in zip(loaded, captions)],
+            convert_to_numpy=True,
+        )
+
+        offset = 0
+        for n, ((i, _, _, frames), caption) in enumerate(zip(loaded, captions)):
+            img_emb = frame_embs[offset:offset + len(frames)].mean(axis=0)
+            offset += len(frames)
+            vect = combine_embeddings(img_emb, text_embs[n])
+            results[i] = {"vector": vect, "caption": caption, "dims": len(vect)}
+
+    return jsonify({"results": results}), 200
+
+@app.route("/embed_text", methods=["POST"])
+def embed_text():
+    text = (request.json or {}).get("text", "").strip()
+    vect = clip_model.encode([text], convert_to_numpy=True)[0]
+    return jsonify({"vector": vect.tolist(), "dims": len(vect)}), 200
+
+@app.route("/embed_image", methods=["POST"])
+def embed_image():
+    if "image" not in request.files:
+        return jsonify({"error": "image is required"}), 400
+    image = Image.open(request.files["image"].stream).convert("RGB")
+    vect = clip_model.encode([image], convert_to_numpy=True)[0]
+    return jsonify({"vector": vect.tolist(), "dims": len(vect)}), 200
 
 if __name__ == "__main__":
-    app.run(host="0.0.0.0", port=5000)
\ No newline at end of file
+    app.run(host="0.0.0.0", port=5000)
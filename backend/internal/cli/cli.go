@@ -0,0 +1,67 @@
+// Package cli implements the maintenance subcommands of the memora binary.
+package cli
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/Pranjal095/Memora/backend/config"
+)
+
+type command struct {
+	usage string
+	run   func(cfg *config.Config, args []string) error
+}
+
+var commands = map[string]command{
+	"fsck": {
+		usage: "check photo rows, uploaded files and vectors against each other",
+		run:   fsck,
+	},
+	"import-qdrant": {
+		usage: "copy the vectors of a Qdrant collection into the pgvector table",
+		run:   importQdrant,
+	},
+	"migrate": {
+		usage: "apply (up), revert (down) or list (status) schema migrations",
+		run:   migrateCmd,
+	},
+	"reindex": {
+		usage: "re-embed photos, e.g. after the embedding model changes",
+		run:   reindex,
+	},
+}
+
+// Run executes the subcommand named by args[0] with cfg and returns the
+// process exit code.
+func Run(cfg *config.Config, args []string) int {
+	cmd, ok := commands[args[0]]
+	if !ok {
+		if args[0] != "help" && args[0] != "-h" && args[0] != "--help" {
+			fmt.Fprintf(os.Stderr, "unknown command %q\n\n", args[0])
+		}
+		printUsage()
+		return 2
+	}
+
+	if err := cmd.run(cfg, args[1:]); err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", args[0], err)
+		return 1
+	}
+	return 0
+}
+
+func printUsage() {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintln(os.Stderr, "usage: memora [command] [flags]")
+	fmt.Fprintln(os.Stderr, "\nWithout a command the API server is started.\n\nCommands:")
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-16s %s\n", name, commands[name].usage)
+	}
+}
@@ -0,0 +1,91 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/Pranjal095/Memora/backend/config"
+	"github.com/Pranjal095/Memora/backend/internal/helpers"
+	"github.com/Pranjal095/Memora/backend/internal/repository"
+	"github.com/Pranjal095/Memora/backend/internal/schema"
+)
+
+// fsck reports drift between photo rows, files under uploads/ and the vector
+// index, and repairs it when asked. It fails when problems are left over so
+// it can run from cron.
+func fsck(cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("fsck", flag.ContinueOnError)
+	repairFlag := fs.String("repair", "", "comma separated repairs: reembed, delete, quarantine")
+	asJSON := fs.Bool("json", false, "print the report as JSON")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var repair []string
+	if *repairFlag != "" {
+		for _, mode := range strings.Split(*repairFlag, ",") {
+			repair = append(repair, strings.TrimSpace(mode))
+		}
+	}
+	if err := helpers.ValidateFsckRepair(repair); err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	report, err := helpers.CheckConsistency(ctx, cfg, repository.NewPgPhotos(config.DB), repair)
+	if report != nil {
+		if *asJSON {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			enc.Encode(report)
+		} else {
+			printFsckReport(report, cfg.FsckQuarantineDir)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	left := len(report.MissingFiles) +
+		len(report.MissingVectors) - report.Reembedded +
+		len(report.OrphanFiles) - report.DeletedFiles - report.Quarantined +
+		len(report.OrphanVectors) - report.DeletedVectors
+	if left > 0 {
+		return fmt.Errorf("%d problems left", left)
+	}
+	return nil
+}
+
+func printFsckReport(r *schema.FsckReport, quarantine string) {
+	fmt.Printf("checked %d photos, %d files, %d vectors\n", r.Photos, r.Files, r.Vectors)
+
+	fmt.Printf("\nrows with missing files: %d\n", len(r.MissingFiles))
+	for _, m := range r.MissingFiles {
+		fmt.Printf("  photo %d (user %d): %s\n", m.PhotoID, m.UserID, m.Path)
+	}
+	fmt.Printf("\norphan files: %d\n", len(r.OrphanFiles))
+	for _, path := range r.OrphanFiles {
+		fmt.Printf("  %s\n", path)
+	}
+	fmt.Printf("\nrows without vectors: %d\n", len(r.MissingVectors))
+	for _, id := range r.MissingVectors {
+		fmt.Printf("  photo %d\n", id)
+	}
+	fmt.Printf("\nvectors without rows: %d\n", len(r.OrphanVectors))
+	for _, id := range r.OrphanVectors {
+		fmt.Printf("  point %d\n", id)
+	}
+
+	if r.Reembedded+r.ReembedFailed+r.DeletedFiles+r.DeletedVectors+r.Quarantined > 0 {
+		fmt.Printf("\nre-embedded %d (%d failed), deleted %d files and %d vectors, quarantined %d files in %s\n",
+			r.Reembedded, r.ReembedFailed, r.DeletedFiles, r.DeletedVectors, r.Quarantined, quarantine)
+	}
+}
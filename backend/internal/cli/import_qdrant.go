@@ -0,0 +1,63 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os/signal"
+	"syscall"
+
+	"github.com/Pranjal095/Memora/backend/config"
+	"github.com/Pranjal095/Memora/backend/internal/helpers"
+	"github.com/Pranjal095/Memora/backend/internal/vectorindex"
+)
+
+// importQdrant scrolls a Qdrant collection and upserts every point into the
+// pgvector table. It is idempotent, so an interrupted run can be restarted,
+// optionally from the last id it printed.
+func importQdrant(cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("import-qdrant", flag.ContinueOnError)
+	collection := fs.String("collection", cfg.QdrantCollection, "Qdrant collection to read")
+	table := fs.String("table", cfg.PgVectorTable, "pgvector table to write")
+	batch := fs.Int("batch", 256, "points per request")
+	from := fs.Int64("from", 0, "resume from this point id")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *batch < 1 {
+		return fmt.Errorf("batch must be positive")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	src := helpers.NewQdrantIndex(cfg, *collection)
+	dst := vectorindex.NewPgVector(config.DB, *table)
+
+	total, err := src.Count(ctx, vectorindex.Filter{})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("copying %d points from %s to %s\n", total, *collection, *table)
+
+	var copied int64
+	for next := *from; ; {
+		points, after, err := src.Scroll(ctx, vectorindex.Filter{}, next, *batch)
+		if err != nil {
+			return fmt.Errorf("scroll from %d: %w", next, err)
+		}
+		if err := dst.Upsert(ctx, points...); err != nil {
+			return fmt.Errorf("upsert batch starting at %d: %w", next, err)
+		}
+
+		copied += int64(len(points))
+		fmt.Printf("copied %d/%d (next id %d)\n", copied, total, after)
+		if after == 0 {
+			break
+		}
+		next = after
+	}
+
+	fmt.Println("done")
+	return nil
+}
@@ -0,0 +1,77 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/Pranjal095/Memora/backend/config"
+	"github.com/Pranjal095/Memora/backend/internal/migrate"
+)
+
+// migrateCmd applies, reverts or lists the embedded schema migrations.
+func migrateCmd(_ *config.Config, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: memora migrate up|down [--steps N]|status")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	printMigration := func(verb string) func(migrate.Migration) {
+		return func(m migrate.Migration) {
+			fmt.Printf("%s %04d_%s\n", verb, m.Version, m.Name)
+		}
+	}
+
+	switch args[0] {
+	case "up":
+		ran, err := migrate.Up(ctx, config.DB, printMigration("applying"))
+		if err != nil {
+			return err
+		}
+		if len(ran) == 0 {
+			fmt.Println("already up to date")
+		}
+		return nil
+
+	case "down":
+		fs := flag.NewFlagSet("migrate down", flag.ContinueOnError)
+		steps := fs.Int("steps", 1, "number of migrations to revert")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		if *steps < 1 {
+			return fmt.Errorf("steps must be positive")
+		}
+		reverted, err := migrate.Down(ctx, config.DB, *steps, printMigration("reverting"))
+		if err != nil {
+			return err
+		}
+		if len(reverted) == 0 {
+			fmt.Println("nothing to revert")
+		}
+		return nil
+
+	case "status":
+		statuses, err := migrate.Statuses(ctx, config.DB)
+		if err != nil {
+			return err
+		}
+		for _, s := range statuses {
+			state := "pending"
+			if s.Applied {
+				state = "applied " + s.AppliedAt.Format(time.RFC3339)
+			}
+			if s.Modified {
+				state += " (modified since applied)"
+			}
+			fmt.Printf("%04d_%-24s %s\n", s.Version, s.Name, state)
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown migrate command %q, want up, down or status", args[0])
+}
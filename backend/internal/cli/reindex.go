@@ -0,0 +1,91 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/Pranjal095/Memora/backend/config"
+	"github.com/Pranjal095/Memora/backend/internal/helpers"
+	"github.com/Pranjal095/Memora/backend/internal/repository"
+	"github.com/Pranjal095/Memora/backend/internal/schema"
+)
+
+// reindex re-embeds photos into the vector index. Interrupting it pauses the
+// run; --resume continues from its last checkpoint.
+func reindex(cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("reindex", flag.ContinueOnError)
+	userID := fs.Int64("user", 0, "only re-embed this user's photos")
+	from := fs.String("from", "", "only photos uploaded on or after this date (YYYY-MM-DD)")
+	to := fs.String("to", "", "only photos uploaded before this date (YYYY-MM-DD)")
+	resume := fs.Int64("resume", 0, "resume the run with this id")
+	concurrency := fs.Int("concurrency", cfg.ReindexConcurrency, "photos embedded at once")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *concurrency < 1 {
+		return fmt.Errorf("concurrency must be positive")
+	}
+
+	req := schema.ReindexRequest{UserID: *userID}
+	for _, d := range []struct {
+		flag string
+		dst  **time.Time
+	}{{*from, &req.From}, {*to, &req.To}} {
+		if d.flag == "" {
+			continue
+		}
+		t, err := time.Parse("2006-01-02", d.flag)
+		if err != nil {
+			return fmt.Errorf("invalid date %q", d.flag)
+		}
+		*d.dst = &t
+	}
+	if *resume > 0 && (req.UserID > 0 || req.From != nil || req.To != nil) {
+		return fmt.Errorf("--resume keeps the scope of the original run")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	lock, err := helpers.LockReindex(ctx)
+	if err != nil {
+		return err
+	}
+	defer lock.Release()
+
+	id := *resume
+	if id == 0 {
+		run, err := helpers.CreateReindexRun(ctx, req)
+		if err != nil {
+			return err
+		}
+		id = run.ID
+	}
+
+	target := "in place"
+	if run, err := helpers.GetReindexRun(ctx, id); err != nil {
+		return err
+	} else if run.Target != nil {
+		target = "into " + *run.Target
+	}
+	fmt.Printf("reindex run %d, writing %s\n", id, target)
+
+	err = helpers.RunReindex(ctx, cfg, repository.NewPgPhotos(config.DB), id, *concurrency, func(run schema.ReindexRun) {
+		fmt.Printf("embedded %d/%d, %d failed (checkpoint %d)\n", run.Done, run.Total, run.Failed, run.CheckpointID)
+	})
+	if errors.Is(err, context.Canceled) {
+		fmt.Printf("paused, continue with: memora reindex --resume %d\n", id)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("done")
+	return nil
+}
@@ -19,7 +19,7 @@ type otpEntry struct {
 
 var otpStore = make(map[string]otpEntry)
 
-func Setup2FA(c *gin.Context) {
+func (h *Handler) Setup2FA(c *gin.Context) {
 	var req struct {
 		Username string `json:"username" binding:"required"`
 	}
@@ -28,7 +28,7 @@ func Setup2FA(c *gin.Context) {
 		return
 	}
 
-	user, err := helpers.GetUserByUsername(c.Request.Context(), req.Username)
+	user, err := h.users.GetByUsername(c.Request.Context(), req.Username)
 	if err != nil {
 		c.JSON(404, gin.H{"error": "user not found"})
 		return
@@ -40,7 +40,7 @@ func Setup2FA(c *gin.Context) {
 		ExpiresAt: time.Now().Add(5 * time.Minute),
 	}
 
-	if err := helpers.SendOTPEmail(user.Email, code); err != nil {
+	if err := helpers.SendOTPEmail(h.cfg, user.Email, code); err != nil {
 		c.JSON(500, gin.H{"error": "failed to send OTP"})
 		return
 	}
@@ -48,7 +48,7 @@ func Setup2FA(c *gin.Context) {
 	c.JSON(200, gin.H{"message": "OTP sent"})
 }
 
-func Verify2FA(c *gin.Context) {
+func (h *Handler) Verify2FA(c *gin.Context) {
 	var req struct {
 		Username string `json:"username" binding:"required"`
 		Code     string `json:"code" binding:"required"`
@@ -65,13 +65,13 @@ func Verify2FA(c *gin.Context) {
 	}
 	delete(otpStore, req.Username)
 
-	user, err := helpers.GetUserByUsername(c.Request.Context(), req.Username)
+	user, err := h.users.GetByUsername(c.Request.Context(), req.Username)
 	if err != nil {
 		c.JSON(500, gin.H{"error": "could not find user"})
 		return
 	}
 
-	token, err := helpers.GenerateJWT(user.ID)
+	token, err := helpers.GenerateJWT(h.cfg.JWTSecret, user.ID)
 	if err != nil {
 		c.JSON(500, gin.H{"error": "could not generate token"})
 		return
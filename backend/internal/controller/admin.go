@@ -0,0 +1,184 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Pranjal095/Memora/backend/internal/helpers"
+	"github.com/Pranjal095/Memora/backend/internal/schema"
+)
+
+func InvalidateAnalysisCache(c *gin.Context) {
+	deleted, err := helpers.InvalidateAnalysisResults(
+		c.Request.Context(),
+		c.Query("model_version"),
+		c.Query("stale") == "true",
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not invalidate analysis cache"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"deleted": deleted, "current_model_version": helpers.Classifier().ModelVersion()})
+}
+
+func InferenceStats(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"driver": helpers.Classifier().Name(),
+		"stats":  helpers.InferenceStats(),
+	})
+}
+
+func (h *Handler) StartReindex(c *gin.Context) {
+	var req schema.ReindexRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+			return
+		}
+	}
+	if req.UserID < 0 || req.Concurrency < 0 || req.Concurrency > 32 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user_id must be positive and concurrency at most 32"})
+		return
+	}
+	if req.From != nil && req.To != nil && !req.To.After(*req.From) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "to must be after from"})
+		return
+	}
+
+	run, err := helpers.StartReindex(c.Request.Context(), h.cfg, h.photos, req)
+	if errors.Is(err, helpers.ErrReindexRunning) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not start reindex"})
+		return
+	}
+	c.JSON(http.StatusAccepted, run)
+}
+
+func ListReindexRuns(c *gin.Context) {
+	runs, err := helpers.ListReindexRuns(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not query reindex runs"})
+		return
+	}
+	c.JSON(http.StatusOK, runs)
+}
+
+func GetReindexRun(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid run id"})
+		return
+	}
+
+	run, err := helpers.GetReindexRun(c.Request.Context(), id)
+	if errors.Is(err, helpers.ErrReindexNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not query reindex run"})
+		return
+	}
+	c.JSON(http.StatusOK, run)
+}
+
+func (h *Handler) ResumeReindex(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid run id"})
+		return
+	}
+	concurrency, err := strconv.Atoi(c.DefaultQuery("concurrency", "0"))
+	if err != nil || concurrency < 0 || concurrency > 32 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "concurrency must be between 1 and 32"})
+		return
+	}
+
+	run, err := helpers.ResumeReindex(c.Request.Context(), h.cfg, h.photos, id, concurrency)
+	switch {
+	case errors.Is(err, helpers.ErrReindexNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, helpers.ErrReindexRunning), errors.Is(err, helpers.ErrReindexFinished):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not resume reindex"})
+	default:
+		c.JSON(http.StatusAccepted, run)
+	}
+}
+
+func PauseReindex(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid run id"})
+		return
+	}
+
+	err = helpers.PauseReindex(c.Request.Context(), id)
+	switch {
+	case errors.Is(err, helpers.ErrReindexNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, helpers.ErrReindexNotActive):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not pause reindex"})
+	default:
+		c.JSON(http.StatusAccepted, gin.H{"message": "pause requested"})
+	}
+}
+
+func (h *Handler) StartFsck(c *gin.Context) {
+	var req schema.FsckRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+			return
+		}
+	}
+
+	run, err := helpers.StartFsck(c.Request.Context(), h.cfg, h.photos, req.Repair)
+	switch {
+	case errors.Is(err, helpers.ErrFsckRepair):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, helpers.ErrFsckRunning):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not start consistency check"})
+	default:
+		c.JSON(http.StatusAccepted, run)
+	}
+}
+
+func ListFsckRuns(c *gin.Context) {
+	runs, err := helpers.ListFsckRuns(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not query consistency checks"})
+		return
+	}
+	c.JSON(http.StatusOK, runs)
+}
+
+func GetFsckRun(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid check id"})
+		return
+	}
+
+	run, err := helpers.GetFsckRun(c.Request.Context(), id)
+	if errors.Is(err, helpers.ErrFsckNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not query consistency check"})
+		return
+	}
+	c.JSON(http.StatusOK, run)
+}
@@ -0,0 +1,173 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Pranjal095/Memora/backend/internal/helpers"
+	"github.com/Pranjal095/Memora/backend/internal/schema"
+)
+
+func (h *Handler) SubmitAnalysis(c *gin.Context) {
+	userID := c.GetString("userID")
+	var task helpers.AnalysisTask
+	var sourceURL, filename string
+	var opts schema.SegmentOptions
+
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		form, err := h.readUploadForm(c, 1, "audio")
+		if err != nil {
+			c.JSON(uploadErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		if len(form.Files) == 0 {
+			form.Cleanup()
+			c.JSON(http.StatusBadRequest, gin.H{"error": "audio file is required"})
+			return
+		}
+		task.FilePath = form.Files[0].TempPath
+		if _, err := helpers.CheckAudioFile(c.Request.Context(), task.FilePath, h.cfg.AnalyzeMaxDuration); err != nil {
+			form.Cleanup()
+			c.JSON(uploadErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		filename = helpers.SanitizeFilename(form.Files[0].Filename, "")
+		if opts, err = segmentOptionsFromForm(form); err != nil {
+			form.Cleanup()
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	} else {
+		var req schema.AnalyzeRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if u, err := url.Parse(req.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "url must be http or https"})
+			return
+		}
+		if err := helpers.CheckPublicHost(c.Request.Context(), req.URL); errors.Is(err, helpers.ErrForbiddenDestination) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		task.URL, sourceURL = req.URL, req.URL
+		opts = req.SegmentOptions
+	}
+
+	opts, err := helpers.NormalizeSegmentOptions(opts)
+	if err != nil {
+		helpers.DiscardAnalysisTask(task)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	task.Options = opts
+
+	id, err := helpers.CreateAnalysisJob(c.Request.Context(), userID, sourceURL, filename, opts)
+	if err != nil {
+		helpers.DiscardAnalysisTask(task)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not create analysis job"})
+		return
+	}
+
+	task.JobID = id
+	if err := helpers.EnqueueAnalysis(task); err != nil {
+		helpers.DiscardAnalysisTask(task)
+		_ = helpers.CancelAnalysisJob(c.Request.Context(), id, userID)
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return
+	}
+
+	job, err := helpers.GetAnalysisJob(c.Request.Context(), id, userID)
+	if err != nil {
+		c.JSON(http.StatusAccepted, gin.H{"id": id, "status": helpers.JobQueued})
+		return
+	}
+	c.JSON(http.StatusAccepted, job)
+}
+
+func segmentOptionsFromForm(form *uploadForm) (schema.SegmentOptions, error) {
+	var opts schema.SegmentOptions
+	floats := map[string]*float64{
+		"window_seconds": &opts.WindowSeconds,
+		"stride_seconds": &opts.StrideSeconds,
+		"threshold":      &opts.Threshold,
+	}
+	for name, dst := range floats {
+		if v := form.Value(name); v != "" {
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return opts, fmt.Errorf("%s must be a number", name)
+			}
+			*dst = f
+		}
+	}
+	if v := form.Value("flagged_only"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return opts, errors.New("flagged_only must be a boolean")
+		}
+		opts.FlaggedOnly = b
+	}
+	return opts, nil
+}
+
+func GetAnalysis(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid job id"})
+		return
+	}
+
+	job, err := helpers.GetAnalysisJob(c.Request.Context(), id, c.GetString("userID"))
+	if errors.Is(err, helpers.ErrJobNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not query analysis job"})
+		return
+	}
+	c.JSON(http.StatusOK, job)
+}
+
+func ListAnalyses(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit < 1 || limit > 200 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 200"})
+		return
+	}
+
+	jobs, err := helpers.ListAnalysisJobs(c.Request.Context(), c.GetString("userID"), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not query analysis jobs"})
+		return
+	}
+	c.JSON(http.StatusOK, jobs)
+}
+
+func CancelAnalysis(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid job id"})
+		return
+	}
+
+	err = helpers.CancelAnalysisJob(c.Request.Context(), id, c.GetString("userID"))
+	switch {
+	case errors.Is(err, helpers.ErrJobNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, helpers.ErrJobFinished):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not cancel analysis job"})
+	default:
+		c.JSON(http.StatusAccepted, gin.H{"message": "cancellation requested"})
+	}
+}
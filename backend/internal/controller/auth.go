@@ -2,15 +2,16 @@ package controller
 
 import (
 	"context"
+	"errors"
 	"net/http"
-	"strings"
 
 	"github.com/Pranjal095/Memora/backend/internal/helpers"
+	"github.com/Pranjal095/Memora/backend/internal/repository"
 	"github.com/Pranjal095/Memora/backend/internal/schema"
 	"github.com/gin-gonic/gin"
 )
 
-func Signup(c *gin.Context) {
+func (h *Handler) Signup(c *gin.Context) {
 	var req schema.SignupRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -18,12 +19,13 @@ func Signup(c *gin.Context) {
 	}
 	if err := helpers.CreateUser(
 		context.Background(),
+		h.users,
 		req.Username,
 		req.Email,
 		req.Password,
 	); err != nil {
-		if strings.Contains(err.Error(), "duplicate") || strings.Contains(err.Error(), "unique") {
-			c.JSON(http.StatusConflict, gin.H{"error": "username or email already in use"})
+		if errors.Is(err, repository.ErrUserExists) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
 		} else {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
 		}
@@ -32,7 +34,7 @@ func Signup(c *gin.Context) {
 	c.JSON(http.StatusCreated, gin.H{"message": "user created"})
 }
 
-func Login(c *gin.Context) {
+func (h *Handler) Login(c *gin.Context) {
 	var req schema.LoginRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -40,6 +42,7 @@ func Login(c *gin.Context) {
 	}
 	userID, err := helpers.AuthenticateUser(
 		context.Background(),
+		h.users,
 		req.Username,
 		req.Password,
 	)
@@ -47,7 +50,7 @@ func Login(c *gin.Context) {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
 		return
 	}
-	token, err := helpers.GenerateJWT(userID)
+	token, err := helpers.GenerateJWT(h.cfg.JWTSecret, userID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not create token"})
 		return
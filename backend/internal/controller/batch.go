@@ -0,0 +1,118 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Pranjal095/Memora/backend/internal/helpers"
+	"github.com/Pranjal095/Memora/backend/internal/schema"
+)
+
+// AddPhotosBatch stores every photo[] file of a multipart form and reports
+// how each one went, by its position. Notes pair with files by position too:
+// the i-th note[] belongs to the i-th photo[], counting files that fail, so
+// a client sends an empty note[] for a photo without one. Notes past the
+// last file are ignored and files past the last note get none.
+func (h *Handler) AddPhotosBatch(c *gin.Context) {
+	userID := c.GetString("userID")
+	form, err := h.readUploadForm(c, h.cfg.BatchUploadMaxFiles, "photo[]")
+	if err != nil {
+		c.JSON(uploadErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	defer form.Cleanup()
+
+	if len(form.Files) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "at least one photo[] file is required"})
+		return
+	}
+
+	c.JSON(http.StatusOK, schema.BatchUploadResponse{Results: h.processBatch(c, userID, form)})
+}
+
+func (h *Handler) processBatch(c *gin.Context, userID string, form *uploadForm) []schema.BatchUploadResult {
+	notes := form.Fields["note[]"]
+	baseURL := requestBaseURL(c)
+	results := make([]schema.BatchUploadResult, len(form.Files))
+
+	// Identical files inside one batch are stored once; later copies only
+	// point at the first one.
+	firstByHash := make(map[string]int)
+	infos := make([]helpers.MediaInfo, len(form.Files))
+	for i, file := range form.Files {
+		results[i] = schema.BatchUploadResult{Index: i, Filename: file.Filename}
+		info, err := helpers.InspectMedia(file.TempPath)
+		if err != nil {
+			results[i].Error = err.Error()
+			continue
+		}
+		infos[i] = info
+		if _, seen := firstByHash[info.Hash]; !seen {
+			firstByHash[info.Hash] = i
+		}
+	}
+
+	sem := make(chan struct{}, h.cfg.BatchUploadConcurrency)
+	var wg sync.WaitGroup
+	for i, file := range form.Files {
+		if results[i].Error != "" || firstByHash[infos[i].Hash] != i {
+			continue
+		}
+		var note string
+		if i < len(notes) {
+			note = notes[i]
+		}
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(res *schema.BatchUploadResult, file uploadedFile, info helpers.MediaInfo, note string) {
+			defer func() { <-sem; wg.Done() }()
+
+			existing, err := h.photos.FindByHash(c.Request.Context(), userID, info.Hash)
+			if err != nil {
+				res.Error = "could not check for duplicates"
+				return
+			}
+			if existing != 0 {
+				res.ID, res.Duplicate = existing, true
+				return
+			}
+
+			dst, err := helpers.SavePhotoFile(file.TempPath, file.Filename, userID, info)
+			if err != nil {
+				res.Error = "could not save file"
+				return
+			}
+			photo, err := h.storePhoto(c.Request.Context(), baseURL, userID, dst, note, info, nil)
+			if err != nil {
+				os.Remove(dst)
+			}
+			if errors.Is(err, helpers.ErrUnsupportedType) {
+				res.Error = err.Error()
+				return
+			}
+			if err != nil {
+				res.Error = "could not save metadata"
+				return
+			}
+			res.ID, res.Photo = photo.ID, &photo
+		}(&results[i], file, infos[i], note)
+	}
+	wg.Wait()
+
+	for i := range results {
+		if first := firstByHash[infos[i].Hash]; results[i].Error == "" && first != i {
+			if results[first].Error != "" {
+				results[i].Error = results[first].Error
+			} else {
+				results[i].ID, results[i].Duplicate = results[first].ID, true
+			}
+		}
+	}
+
+	return results
+}
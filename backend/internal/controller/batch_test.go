@@ -0,0 +1,202 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Pranjal095/Memora/backend/internal/helpers"
+	"github.com/Pranjal095/Memora/backend/internal/repository"
+	"github.com/Pranjal095/Memora/backend/internal/schema"
+)
+
+// pngOf encodes a one pixel image of c.
+func pngOf(t *testing.T, c color.Color) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, 1, 1))
+	img.Set(0, 0, c)
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+type batchFile struct {
+	name string
+	data []byte
+}
+
+// batchFixture serves /photos/batch for ada over in-memory repositories,
+// saving files under a temporary working directory.
+func batchFixture(t *testing.T) (*gin.Engine, repository.PhotoRepository) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	photos := repository.NewMemoryPhotos()
+	h := New(testConfig(), repository.NewMemoryUsers(), photos)
+	r := gin.New()
+	r.POST("/photos/batch", func(c *gin.Context) { c.Set("userID", "1") }, h.AddPhotosBatch)
+	return r, photos
+}
+
+func postBatch(t *testing.T, r *gin.Engine, files []batchFile, notes ...string) schema.BatchUploadResponse {
+	t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for _, f := range files {
+		fw, err := mw.CreateFormFile("photo[]", f.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fw.Write(f.data)
+	}
+	for _, note := range notes {
+		mw.WriteField("note[]", note)
+	}
+	mw.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/photos/batch", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("got %d %s", w.Code, w.Body)
+	}
+	var resp schema.BatchUploadResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Results) != len(files) {
+		t.Fatalf("got %d results for %d files", len(resp.Results), len(files))
+	}
+	return resp
+}
+
+func TestAddPhotosBatch(t *testing.T) {
+	r, _ := batchFixture(t)
+	red, blue := pngOf(t, color.RGBA{R: 255, A: 255}), pngOf(t, color.RGBA{B: 255, A: 255})
+
+	resp := postBatch(t, r, []batchFile{
+		{"red.png", red},
+		{"notes.txt", []byte("not a photo at all")},
+		{"blue.png", blue},
+		{"red again.png", red},
+	}, "sunset", "ignored", "sea", "copy")
+	res := resp.Results
+
+	for i, want := range []string{"red.png", "notes.txt", "blue.png", "red again.png"} {
+		if res[i].Index != i || res[i].Filename != want {
+			t.Errorf("result %d is %d %q, want %q", i, res[i].Index, res[i].Filename, want)
+		}
+	}
+	if res[1].Error == "" || res[1].ID != 0 {
+		t.Errorf("the text file was accepted: %+v", res[1])
+	}
+	for _, i := range []int{0, 2} {
+		if res[i].Error != "" || res[i].ID == 0 || res[i].Duplicate || res[i].Photo == nil {
+			t.Fatalf("result %d: %+v", i, res[i])
+		}
+	}
+	if res[0].ID == res[2].ID {
+		t.Errorf("two photos were stored as %d", res[0].ID)
+	}
+	// Notes go by position, so the failed file still takes one.
+	if *res[0].Photo.Note != "sunset" || *res[2].Photo.Note != "sea" {
+		t.Errorf("notes %q and %q, want sunset and sea", *res[0].Photo.Note, *res[2].Photo.Note)
+	}
+	// A copy inside the batch points at the first one instead of being
+	// stored again.
+	if res[3].Error != "" || !res[3].Duplicate || res[3].ID != res[0].ID || res[3].Photo != nil {
+		t.Errorf("copy: %+v", res[3])
+	}
+
+	saved, _ := filepath.Glob(filepath.Join("uploads", "*"))
+	if len(saved) != 2 {
+		t.Errorf("saved %v, want two files", saved)
+	}
+}
+
+func TestAddPhotosBatchKnownPhoto(t *testing.T) {
+	r, photos := batchFixture(t)
+	red := pngOf(t, color.RGBA{R: 255, A: 255})
+
+	path := filepath.Join(t.TempDir(), "red.png")
+	if err := os.WriteFile(path, red, 0644); err != nil {
+		t.Fatal(err)
+	}
+	info, err := helpers.InspectMedia(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := photos.Create(context.Background(), repository.NewPhoto{
+		UserID: "1", URL: "uploads/red.png", MediaType: "image", ContentHash: info.Hash,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res := postBatch(t, r, []batchFile{{"red.png", red}, {"copy.png", red}}).Results
+	for i := range res {
+		if res[i].Error != "" || !res[i].Duplicate || res[i].ID != id {
+			t.Errorf("result %d: %+v, want a duplicate of %d", i, res[i], id)
+		}
+	}
+	if saved, _ := filepath.Glob(filepath.Join("uploads", "*")); len(saved) != 0 {
+		t.Errorf("saved %v for a photo that was already stored", saved)
+	}
+}
+
+func TestAddPhotosBatchNotesCount(t *testing.T) {
+	files := []batchFile{
+		{"red.png", pngOf(t, color.RGBA{R: 255, A: 255})},
+		{"green.png", pngOf(t, color.RGBA{G: 255, A: 255})},
+		{"blue.png", pngOf(t, color.RGBA{B: 255, A: 255})},
+	}
+
+	tests := []struct {
+		name  string
+		notes []string
+		want  []string
+	}{
+		{"fewer notes", []string{"one"}, []string{"one", "", ""}},
+		{"more notes", []string{"one", "two", "three", "four"}, []string{"one", "two", "three"}},
+		{"no notes", nil, []string{"", "", ""}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Each run uploads the same files, so each needs a fresh library.
+			r, _ := batchFixture(t)
+
+			res := postBatch(t, r, files, tt.notes...).Results
+			for i, want := range tt.want {
+				if res[i].Error != "" || res[i].Photo == nil {
+					t.Fatalf("result %d: %+v", i, res[i])
+				}
+				if got := *res[i].Photo.Note; got != want {
+					t.Errorf("%s: note %q, want %q", files[i].name, got, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,18 @@
+package controller
+
+import (
+	"github.com/Pranjal095/Memora/backend/config"
+	"github.com/Pranjal095/Memora/backend/internal/repository"
+)
+
+// Handler serves the routes that read or write users and photos, through
+// the repositories it was given, or that depend on the configuration.
+type Handler struct {
+	cfg    *config.Config
+	users  repository.UserRepository
+	photos repository.PhotoRepository
+}
+
+func New(cfg *config.Config, users repository.UserRepository, photos repository.PhotoRepository) *Handler {
+	return &Handler{cfg: cfg, users: users, photos: photos}
+}
@@ -0,0 +1,40 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Pranjal095/Memora/backend/config"
+	"github.com/Pranjal095/Memora/backend/internal/helpers"
+)
+
+// Readyz reports the state of the server's dependencies. It fails only when
+// the database is unreachable: without the embedding service uploads and
+// keyword search still work, so an open breaker marks the server degraded.
+func Readyz(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 2*time.Second)
+	defer cancel()
+
+	status, code := "ready", http.StatusOK
+	database := "ok"
+	if err := config.DB.Ping(ctx); err != nil {
+		database = err.Error()
+		status, code = "unavailable", http.StatusServiceUnavailable
+	}
+
+	embed := helpers.EmbedBreakerState()
+	if embed.State != helpers.BreakerClosed && code == http.StatusOK {
+		status = "degraded"
+	}
+
+	c.JSON(code, gin.H{
+		"status": status,
+		"checks": gin.H{
+			"database":          database,
+			"embedding_service": embed,
+		},
+	})
+}
@@ -2,105 +2,246 @@ package controller
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"io"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/Pranjal095/Memora/backend/internal/helpers"
+	"github.com/Pranjal095/Memora/backend/internal/repository"
 	"github.com/Pranjal095/Memora/backend/internal/schema"
 	"github.com/gin-gonic/gin"
 	exif "github.com/rwcarlsen/goexif/exif"
 )
 
-func AddPhoto(c *gin.Context) {
+func (h *Handler) AddPhoto(c *gin.Context) {
 	userID := c.GetString("userID")
-	file, err := c.FormFile("photo")
+	form, err := h.readUploadForm(c, 1, "photo")
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "photo file is required"})
+		c.JSON(uploadErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
+	defer form.Cleanup()
 
-	src, err := file.Open()
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not read file"})
+	if len(form.Files) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "photo file is required"})
 		return
 	}
-	defer src.Close()
+	file := form.Files[0]
 
-	fileContent, err := io.ReadAll(src)
+	info, err := helpers.InspectMedia(file.TempPath)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not read file content"})
+		c.JSON(uploadErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
-	dst, err := helpers.SavePhotoFile(file.Filename, userID, fileContent)
+	dst, err := helpers.SavePhotoFile(file.TempPath, file.Filename, userID, info)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not save file"})
 		return
 	}
 
-	note := c.PostForm("note")
-
-	id, err := helpers.CreatePhotoRecord(context.Background(), userID, dst, note)
+	photo, err := h.storePhoto(c.Request.Context(), requestBaseURL(c), userID, dst, form.Value("note"), info, nil)
+	if err != nil {
+		os.Remove(dst)
+	}
+	if errors.Is(err, helpers.ErrUnsupportedType) {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not save metadata"})
 		return
 	}
 
-	var city string
-	if f, err := os.Open(dst); err == nil {
-		if x, err := exif.Decode(f); err == nil {
-			if lat, lon, err := x.LatLong(); err == nil {
-				city, _ = reverseGeocode(lat, lon)
-			}
-		}
-		f.Close()
-	}
+	c.JSON(http.StatusCreated, photo)
+}
 
+func requestBaseURL(c *gin.Context) string {
 	protocol := "http"
 	if c.Request.TLS != nil {
 		protocol = "https"
 	}
-	fullURL := fmt.Sprintf("%s://%s/%s", protocol, c.Request.Host, dst)
+	return fmt.Sprintf("%s://%s", protocol, c.Request.Host)
+}
 
+// errPhotoKept is returned by storePhoto when link failed but the photo
+// could not be removed again, so its file has to stay where it is.
+var errPhotoKept = errors.New("photo was stored but could not be linked")
+
+// storePhoto records a file already written under uploads/ and queues it for
+// embedding. Every upload path ends here so they all behave the same. link,
+// if set, is called with the new photo id before the photo is embedded; if
+// it fails the photo is removed again. Unless the error is errPhotoKept, the
+// file at dst is left to the caller when storePhoto fails. c bounds the
+// video processing, which runs while the client waits.
+func (h *Handler) storePhoto(c context.Context, baseURL, userID, dst, note string, info helpers.MediaInfo, link func(photoID int64) error) (schema.PhotoResponse, error) {
+	if info.MediaType() == "video" {
+		if err := helpers.PrepareVideo(c, dst, h.cfg.VideoKeyframes, h.cfg.VideoTimeout, &info); err != nil {
+			return schema.PhotoResponse{}, err
+		}
+	}
+
+	if info.MediaType() == "image" {
+		info.City, info.Country = photoPlace(dst)
+	}
+
+	id, err := helpers.CreatePhotoRecord(context.Background(), h.photos, userID, dst, note, info)
+	if err != nil {
+		helpers.RemoveVideoFrames(info.Poster, info.Keyframes)
+		return schema.PhotoResponse{}, err
+	}
+	if link != nil {
+		if err := link(id); err != nil {
+			if derr := h.photos.Delete(context.Background(), userID, id); derr != nil {
+				return schema.PhotoResponse{}, fmt.Errorf("%w: %v (removing photo %d: %v)", errPhotoKept, err, id, derr)
+			}
+			helpers.RemoveVideoFrames(info.Poster, info.Keyframes)
+			return schema.PhotoResponse{}, err
+		}
+	}
+
+	fullURL := helpers.BuildFullURL(baseURL, dst)
+
+	embedSrc := fullURL
+	var frames []string
+	if info.Poster != "" {
+		embedSrc = helpers.BuildFullURL(baseURL, info.Poster)
+		for _, kf := range info.Keyframes {
+			frames = append(frames, helpers.BuildFullURL(baseURL, kf))
+		}
+	}
+
+	createdAt := time.Now()
+	embedReq := helpers.EmbedRequest{
+		ImagePath:  embedSrc,
+		ImagePaths: frames,
+		Note:       note,
+		City:       info.City,
+		Country:    info.Country,
+		MediaType:  info.MediaType(),
+		UserID:     userID,
+		CreatedAt:  createdAt.Unix(),
+		ID:         id,
+	}
 	go func() {
-		_ = helpers.SendToEmbedService(
-			context.Background(),
-			fullURL,
-			note,
-			city,
-			id,
-		)
+		_, _ = helpers.SendToEmbedService(context.Background(), h.photos, embedReq)
 	}()
 
-	c.JSON(http.StatusCreated, schema.PhotoResponse{
+	photo := schema.PhotoResponse{
 		ID:        id,
 		URL:       fullURL,
 		Note:      &note,
-		CreatedAt: time.Now().Format(time.RFC3339),
-	})
+		MimeType:  info.MimeType,
+		SizeBytes: info.Size,
+		MediaType: info.MediaType(),
+		CreatedAt: createdAt.Format(time.RFC3339),
+	}
+	if info.Width > 0 && info.Height > 0 {
+		photo.Width, photo.Height = &info.Width, &info.Height
+	}
+	if info.Poster != "" {
+		posterURL := helpers.BuildFullURL(baseURL, info.Poster)
+		photo.DurationMs, photo.PosterURL = &info.DurationMs, &posterURL
+	}
+	if info.City != "" {
+		photo.City = &info.City
+	}
+	if info.Country != "" {
+		photo.Country = &info.Country
+	}
+	return photo, nil
+}
+
+func absolutePhotoURLs(baseURL string, p *schema.PhotoResponse) {
+	p.URL = helpers.BuildFullURL(baseURL, p.URL)
+	if p.PosterURL != nil {
+		posterURL := helpers.BuildFullURL(baseURL, *p.PosterURL)
+		p.PosterURL = &posterURL
+	}
 }
 
-func ListPhotos(c *gin.Context) {
+// photoPlace returns the city and country of the photo's EXIF position.
+func photoPlace(path string) (string, string) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", ""
+	}
+	defer f.Close()
+
+	x, err := exif.Decode(f)
+	if err != nil {
+		return "", ""
+	}
+	lat, lon, err := x.LatLong()
+	if err != nil {
+		return "", ""
+	}
+	city, country, _ := reverseGeocode(lat, lon)
+	return city, country
+}
+
+func (h *Handler) ListPhotos(c *gin.Context) {
 	userID := c.GetString("userID")
 
-	photos, err := helpers.GetUserPhotos(context.Background(), userID)
+	photos, err := h.photos.ListByUser(context.Background(), userID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not query photos"})
 		return
 	}
 
-	protocol := "http"
-	if c.Request.TLS != nil {
-		protocol = "https"
-	}
-	baseURL := fmt.Sprintf("%s://%s", protocol, c.Request.Host)
+	baseURL := requestBaseURL(c)
 
 	for i := range photos {
-		photos[i].URL = helpers.BuildFullURL(baseURL, photos[i].URL)
+		absolutePhotoURLs(baseURL, &photos[i])
 	}
 
 	c.JSON(http.StatusOK, photos)
 }
+
+// UpdatePhoto overrides the generated caption of a photo and has it
+// embedded again with the new one.
+func (h *Handler) UpdatePhoto(c *gin.Context) {
+	userID := c.GetString("userID")
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid photo id"})
+		return
+	}
+
+	var req schema.UpdatePhotoRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.Caption == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "caption is required"})
+		return
+	}
+	caption := strings.TrimSpace(*req.Caption)
+	if len(caption) > 1000 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "caption must be at most 1000 characters"})
+		return
+	}
+
+	photo, err := h.photos.UpdateCaption(c.Request.Context(), userID, id, caption)
+	if errors.Is(err, repository.ErrPhotoNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not update photo"})
+		return
+	}
+
+	embedReq, err := helpers.PhotoEmbedRequest(c.Request.Context(), h.photos, h.cfg.PublicURL, userID, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not queue photo for embedding"})
+		return
+	}
+	go func() {
+		_, _ = helpers.SendToEmbedService(context.Background(), h.photos, embedReq)
+	}()
+
+	absolutePhotoURLs(requestBaseURL(c), &photo)
+	c.JSON(http.StatusOK, photo)
+}
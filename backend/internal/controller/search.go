@@ -0,0 +1,386 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Pranjal095/Memora/backend/internal/helpers"
+	"github.com/Pranjal095/Memora/backend/internal/schema"
+)
+
+// SearchPhotos ranks the user's photos against q by full-text match on
+// notes, captions and places, by CLIP similarity, or by both fused together.
+func (h *Handler) SearchPhotos(c *gin.Context) {
+	userID := c.GetString("userID")
+	q := strings.TrimSpace(c.Query("q"))
+	if q == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "q is required"})
+		return
+	}
+
+	mode := c.DefaultQuery("mode", helpers.SearchHybrid)
+	if mode != helpers.SearchKeyword && mode != helpers.SearchSemantic && mode != helpers.SearchHybrid {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "mode must be keyword, semantic or hybrid"})
+		return
+	}
+
+	filters, offset, limit, fieldErrs := parseSearchParams(c)
+	if len(fieldErrs) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid search parameters", "fields": fieldErrs})
+		return
+	}
+
+	text, chips := h.interpretSearchQuery(c, userID, q, &filters)
+
+	// Each leg ranks enough candidates to cover the requested page, so a page
+	// is always cut from the same fused ordering.
+	window := offset + limit + 1
+
+	var hits []helpers.FusedHit
+	var err error
+	if text == "" {
+		// The whole query was dates and places: list what matches, newest first.
+		hits, err = helpers.FilterPhotos(c.Request.Context(), h.photos, userID, filters, window)
+	} else {
+		hits, err = h.rankSearch(c, userID, text, mode, filters, window)
+	}
+	if errors.Is(err, helpers.ErrInvalidUserID) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "search failed"})
+		return
+	}
+
+	results, err := h.hydrateSearchResults(c, userID, hits, filters)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not load search results"})
+		return
+	}
+
+	resp := schema.SearchResponse{
+		Query:       q,
+		Text:        text,
+		Mode:        mode,
+		Filters:     filters,
+		Interpreted: chips,
+		Offset:      offset,
+		Limit:       limit,
+	}
+	if len(results) > offset+limit {
+		next := offset + limit
+		resp.NextOffset = &next
+		results = results[:offset+limit]
+	}
+	resp.Results = results[min(offset, len(results)):]
+	c.JSON(http.StatusOK, resp)
+}
+
+// interpretSearchQuery moves dates and the user's known places out of q and
+// into filters, unless interpret=false. Filters given explicitly win, and
+// ignore=date,city,country leaves those phrases in the text, which is how a
+// client drops a chip.
+func (h *Handler) interpretSearchQuery(c *gin.Context, userID, q string, filters *schema.SearchFilters) (string, []schema.InterpretedFilter) {
+	chips := []schema.InterpretedFilter{}
+	if interpret, err := strconv.ParseBool(c.DefaultQuery("interpret", "true")); err == nil && !interpret {
+		return q, chips
+	}
+
+	skip := make(map[string]bool)
+	for _, field := range strings.Split(c.Query("ignore"), ",") {
+		skip[strings.TrimSpace(field)] = true
+	}
+	skip[helpers.InterpretDate] = skip[helpers.InterpretDate] || filters.From != nil || filters.To != nil
+	skip[helpers.InterpretCity] = skip[helpers.InterpretCity] || filters.City != ""
+	skip[helpers.InterpretCountry] = skip[helpers.InterpretCountry] || filters.Country != ""
+
+	cities, countries, err := h.photos.Places(c.Request.Context(), userID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "search: %v\n", err)
+	}
+
+	text, found, interpreted := helpers.InterpretQuery(q, time.Now(), cities, countries, skip)
+	if found.From != nil {
+		filters.From, filters.To = found.From, found.To
+	}
+	if found.City != "" {
+		filters.City = found.City
+	}
+	if found.Country != "" {
+		filters.Country = found.Country
+	}
+	return text, append(chips, interpreted...)
+}
+
+// rankSearch runs the keyword and semantic legs that mode asks for and fuses
+// them. A hybrid search still answers from one side when the other fails.
+func (h *Handler) rankSearch(c *gin.Context, userID, text, mode string, filters schema.SearchFilters, window int) ([]helpers.FusedHit, error) {
+	var (
+		keyword, semantic       []helpers.SearchHit
+		keywordErr, semanticErr error
+		wg                      sync.WaitGroup
+	)
+	if mode != helpers.SearchSemantic {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			keyword, keywordErr = helpers.KeywordSearch(c.Request.Context(), h.photos, userID, text, filters, window)
+		}()
+	}
+	if mode != helpers.SearchKeyword {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			semantic, semanticErr = helpers.SemanticSearch(c.Request.Context(), userID, text, filters, window)
+		}()
+	}
+	wg.Wait()
+
+	if errors.Is(semanticErr, helpers.ErrInvalidUserID) {
+		return nil, semanticErr
+	}
+	if keywordErr != nil && (mode == helpers.SearchKeyword || semanticErr != nil) ||
+		semanticErr != nil && mode == helpers.SearchSemantic {
+		fmt.Fprintf(os.Stderr, "search failed: keyword=%v semantic=%v\n", keywordErr, semanticErr)
+		return nil, errors.Join(keywordErr, semanticErr)
+	}
+	if keywordErr != nil || semanticErr != nil {
+		fmt.Fprintf(os.Stderr, "hybrid search degraded: keyword=%v semantic=%v\n", keywordErr, semanticErr)
+	}
+	return helpers.FuseRanks(keyword, semantic, helpers.RankWeights{
+		Keyword:  h.cfg.SearchKeywordWeight,
+		Semantic: h.cfg.SearchSemanticWeight,
+		K:        h.cfg.SearchRRFK,
+	}), nil
+}
+
+// parseSearchParams reads the filter and paging parameters, collecting a
+// message per invalid field. k is accepted as an alias of limit.
+func parseSearchParams(c *gin.Context) (schema.SearchFilters, int, int, map[string]string) {
+	var f schema.SearchFilters
+	errs := make(map[string]string)
+
+	if v := c.Query("from"); v != "" {
+		if t, _, err := parseSearchDate(v); err != nil {
+			errs["from"] = "must be a date (YYYY-MM-DD) or RFC 3339 timestamp"
+		} else {
+			f.From = &t
+		}
+	}
+	if v := c.Query("to"); v != "" {
+		t, dateOnly, err := parseSearchDate(v)
+		// A plain date includes the whole day.
+		if dateOnly {
+			t = t.AddDate(0, 0, 1)
+		}
+		switch {
+		case err != nil:
+			errs["to"] = "must be a date (YYYY-MM-DD) or RFC 3339 timestamp"
+		case f.From != nil && !t.After(*f.From):
+			errs["to"] = "must be after from"
+		default:
+			f.To = &t
+		}
+	}
+
+	f.City = strings.TrimSpace(c.Query("city"))
+	f.Country = strings.TrimSpace(c.Query("country"))
+
+	if v := c.Query("media_type"); v != "" {
+		if v != "image" && v != "video" {
+			errs["media_type"] = "must be image or video"
+		} else {
+			f.MediaType = v
+		}
+	}
+	if v := c.Query("has_note"); v != "" {
+		if b, err := strconv.ParseBool(v); err != nil {
+			errs["has_note"] = "must be true or false"
+		} else {
+			f.HasNote = &b
+		}
+	}
+	if c.Query("album_id") != "" {
+		errs["album_id"] = "albums are not supported"
+	}
+
+	limitParam := c.Query("limit")
+	if limitParam == "" {
+		limitParam = c.DefaultQuery("k", "50")
+	}
+	limit, err := strconv.Atoi(limitParam)
+	if err != nil || limit < 1 || limit > 200 {
+		errs["limit"] = "must be between 1 and 200"
+	}
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 || offset > 1000 {
+		errs["offset"] = "must be between 0 and 1000"
+	}
+
+	return f, offset, limit, errs
+}
+
+func parseSearchDate(v string) (time.Time, bool, error) {
+	if t, err := time.Parse(time.DateOnly, v); err == nil {
+		return t, true, nil
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	return t, false, err
+}
+
+// hydrateSearchResults loads the ranked photos that belong to the user and
+// still match the filters, in rank order.
+func (h *Handler) hydrateSearchResults(c *gin.Context, userID string, hits []helpers.FusedHit, f schema.SearchFilters) ([]schema.SearchResult, error) {
+	ids := make([]int64, len(hits))
+	for i, hit := range hits {
+		ids[i] = hit.ID
+	}
+	photos, err := h.photos.GetByIDs(c.Request.Context(), userID, ids, f)
+	if err != nil {
+		return nil, err
+	}
+
+	baseURL := requestBaseURL(c)
+	results := []schema.SearchResult{}
+	for _, hit := range hits {
+		p, ok := photos[hit.ID]
+		if !ok {
+			continue
+		}
+		absolutePhotoURLs(baseURL, &p)
+
+		r := schema.SearchResult{PhotoResponse: p, Score: hit.Score}
+		if hit.KeywordRank > 0 {
+			r.KeywordRank = &hit.KeywordRank
+		}
+		if hit.SemanticRank > 0 {
+			r.SemanticRank = &hit.SemanticRank
+		}
+		results = append(results, r)
+	}
+	return results, nil
+}
+
+// SimilarPhotos finds the user's photos closest to one of their own.
+func (h *Handler) SimilarPhotos(c *gin.Context) {
+	userID := c.GetString("userID")
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid photo id"})
+		return
+	}
+	k, ok := parseSimilarK(c, c.Query("k"))
+	if !ok {
+		return
+	}
+
+	owned, err := h.photos.GetByIDs(c.Request.Context(), userID, []int64{id}, schema.SearchFilters{})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not query photo"})
+		return
+	}
+	if _, ok := owned[id]; !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "photo not found"})
+		return
+	}
+
+	hits, err := helpers.SimilarPhotos(c.Request.Context(), userID, id, k)
+	if errors.Is(err, helpers.ErrVectorNotFound) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, helpers.ErrInvalidUserID) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "similar photos: %v\n", err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "search failed"})
+		return
+	}
+
+	results, err := h.hydrateSearchResults(c, userID, vectorHits(hits), schema.SearchFilters{})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not load search results"})
+		return
+	}
+	c.JSON(http.StatusOK, schema.SimilarPhotosResponse{PhotoID: &id, Results: results})
+}
+
+// SearchByImage is a reverse image search over the user's library.
+func (h *Handler) SearchByImage(c *gin.Context) {
+	userID := c.GetString("userID")
+
+	form, err := h.readUploadForm(c, 1, "image")
+	if err != nil {
+		c.JSON(uploadErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	defer form.Cleanup()
+
+	if len(form.Files) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "image is required"})
+		return
+	}
+	k, ok := parseSimilarK(c, form.Value("k"))
+	if !ok {
+		return
+	}
+
+	info, err := helpers.InspectMedia(form.Files[0].TempPath)
+	if err == nil && info.MediaType() != "image" {
+		err = fmt.Errorf("%w: %s", helpers.ErrUnsupportedType, info.MimeType)
+	}
+	if err != nil {
+		c.JSON(uploadErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	hits, err := helpers.SearchByImage(c.Request.Context(), userID, form.Files[0].TempPath, k)
+	if errors.Is(err, helpers.ErrInvalidUserID) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "image search: %v\n", err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "search failed"})
+		return
+	}
+
+	results, err := h.hydrateSearchResults(c, userID, vectorHits(hits), schema.SearchFilters{})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not load search results"})
+		return
+	}
+	c.JSON(http.StatusOK, schema.SimilarPhotosResponse{Results: results})
+}
+
+func parseSimilarK(c *gin.Context, v string) (int, bool) {
+	if v == "" {
+		return 20, true
+	}
+	k, err := strconv.Atoi(v)
+	if err != nil || k < 1 || k > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "k must be between 1 and 100"})
+		return 0, false
+	}
+	return k, true
+}
+
+// vectorHits ranks the hits of a single vector query.
+func vectorHits(hits []helpers.SearchHit) []helpers.FusedHit {
+	fused := make([]helpers.FusedHit, len(hits))
+	for i, hit := range hits {
+		fused[i] = helpers.FusedHit{ID: hit.ID, Score: hit.Score, SemanticRank: i + 1}
+	}
+	return fused
+}
@@ -0,0 +1,198 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Pranjal095/Memora/backend/config"
+	"github.com/Pranjal095/Memora/backend/internal/helpers"
+	"github.com/Pranjal095/Memora/backend/internal/repository"
+	"github.com/Pranjal095/Memora/backend/internal/schema"
+)
+
+var openServices sync.Once
+
+// testConfig returns the defaults with an in-memory vector index and an
+// embedding service nobody listens on, so embeds fail straight away. The
+// helpers are opened with it once per test binary.
+func testConfig() *config.Config {
+	cfg := config.Defaults()
+	cfg.EmbeddingServiceURL = "http://127.0.0.1:0"
+	cfg.EmbedBatchSize = 1
+	cfg.VectorBackend = "memory"
+	openServices.Do(func() {
+		helpers.OpenEmbedService(cfg)
+		helpers.OpenVectors(cfg)
+	})
+	return cfg
+}
+
+// searchFixture serves /search for ada over in-memory repositories holding
+// three of her photos and one of grace's.
+func searchFixture(t *testing.T) (*gin.Engine, map[string]int64) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	c := context.Background()
+	users, photos := repository.NewMemoryUsers(), repository.NewMemoryPhotos()
+
+	create := func(username string) string {
+		id, err := users.Create(c, username, username+"@example.com", "hash")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return strconv.FormatInt(id, 10)
+	}
+	ada, grace := create("ada"), create("grace")
+
+	ids := make(map[string]int64)
+	for _, p := range []struct {
+		name string
+		repository.NewPhoto
+	}{
+		{"hills", repository.NewPhoto{UserID: ada, Note: "tram in the hills", City: "Lisbon", Country: "Portugal"}},
+		{"yellow", repository.NewPhoto{UserID: ada}},
+		{"river", repository.NewPhoto{UserID: ada, Note: "river", City: "Porto", Country: "Portugal"}},
+		{"grace", repository.NewPhoto{UserID: grace, Note: "tram", City: "Lisbon", Country: "Portugal"}},
+	} {
+		p.URL, p.MediaType = "uploads/"+p.name+".jpg", "image"
+		id, err := photos.Create(c, p.NewPhoto)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids[p.name] = id
+	}
+	if err := photos.StoreCaptions(c, map[int64]string{ids["yellow"]: "a yellow tram"}); err != nil {
+		t.Fatal(err)
+	}
+
+	h := New(testConfig(), users, photos)
+	r := gin.New()
+	r.GET("/search", func(c *gin.Context) { c.Set("userID", ada) }, h.SearchPhotos)
+	return r, ids
+}
+
+func search(t *testing.T, r *gin.Engine, params url.Values) (int, []byte) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/search?"+params.Encode(), nil))
+	return w.Code, w.Body.Bytes()
+}
+
+func TestSearchPhotosValidation(t *testing.T) {
+	r, _ := searchFixture(t)
+
+	tests := []struct {
+		name   string
+		params url.Values
+		fields []string
+	}{
+		{"no query", url.Values{}, nil},
+		{"blank query", url.Values{"q": {"  "}}, nil},
+		{"unknown mode", url.Values{"q": {"tram"}, "mode": {"fuzzy"}}, nil},
+		{"bad fields", url.Values{"q": {"tram"}, "limit": {"0"}, "media_type": {"gif"}, "album_id": {"1"}},
+			[]string{"album_id", "limit", "media_type"}},
+		{"to before from", url.Values{"q": {"tram"}, "from": {"2024-05-02"}, "to": {"2024-05-01"}}, []string{"to"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, body := search(t, r, tt.params)
+			if code != http.StatusBadRequest {
+				t.Fatalf("got %d %s, want 400", code, body)
+			}
+			var resp struct{ Fields map[string]string }
+			if err := json.Unmarshal(body, &resp); err != nil {
+				t.Fatal(err)
+			}
+			var fields []string
+			for f := range resp.Fields {
+				fields = append(fields, f)
+			}
+			slices.Sort(fields)
+			if !slices.Equal(fields, tt.fields) {
+				t.Errorf("invalid fields: got %v, want %v", fields, tt.fields)
+			}
+		})
+	}
+}
+
+func TestSearchPhotos(t *testing.T) {
+	r, ids := searchFixture(t)
+	keyword := func(q string, extra ...string) url.Values {
+		v := url.Values{"q": {q}, "mode": {"keyword"}}
+		for i := 0; i < len(extra); i += 2 {
+			v.Set(extra[i], extra[i+1])
+		}
+		return v
+	}
+
+	tests := []struct {
+		name   string
+		params url.Values
+		text   string
+		chips  []string
+		want   []string
+		next   int // 0 on the last page
+	}{
+		{"note ranks above caption", keyword("tram"), "tram", nil, []string{"hills", "yellow"}, 0},
+		{"first page", keyword("tram", "limit", "1"), "tram", nil, []string{"hills"}, 1},
+		{"last page", keyword("tram", "limit", "1", "offset", "1"), "tram", nil, []string{"yellow"}, 0},
+		{"k is an alias of limit", keyword("tram", "k", "1"), "tram", nil, []string{"hills"}, 1},
+		{"explicit filter", keyword("tram", "city", "Porto"), "tram", nil, nil, 0},
+		{"place chip", keyword("tram in Lisbon"), "tram", []string{"city:in Lisbon"}, []string{"hills"}, 0},
+		{"place chip ignored", keyword("river in Lisbon", "ignore", "city"), "river in Lisbon", nil, nil, 0},
+		{"interpretation off", keyword("Porto", "interpret", "false"), "Porto", nil, []string{"river"}, 0},
+		{"filters only", keyword("Portugal"), "", []string{"country:Portugal"}, []string{"river", "hills"}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, body := search(t, r, tt.params)
+			if code != http.StatusOK {
+				t.Fatalf("got %d %s", code, body)
+			}
+			var resp schema.SearchResponse
+			if err := json.Unmarshal(body, &resp); err != nil {
+				t.Fatal(err)
+			}
+			if resp.Text != tt.text {
+				t.Errorf("text: got %q, want %q", resp.Text, tt.text)
+			}
+
+			var chips []string
+			for _, f := range resp.Interpreted {
+				chips = append(chips, f.Field+":"+f.Text)
+			}
+			if !slices.Equal(chips, tt.chips) {
+				t.Errorf("chips: got %v, want %v", chips, tt.chips)
+			}
+
+			var got []int64
+			for _, res := range resp.Results {
+				got = append(got, res.ID)
+			}
+			var want []int64
+			for _, name := range tt.want {
+				want = append(want, ids[name])
+			}
+			if !slices.Equal(got, want) {
+				t.Errorf("results: got %v, want %v", got, want)
+			}
+
+			var next int
+			if resp.NextOffset != nil {
+				next = *resp.NextOffset
+			}
+			if next != tt.next {
+				t.Errorf("next offset: got %d, want %d", next, tt.next)
+			}
+		})
+	}
+}
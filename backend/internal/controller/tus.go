@@ -0,0 +1,221 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Pranjal095/Memora/backend/internal/helpers"
+)
+
+const statusChecksumMismatch = 460
+
+func (h *Handler) TusOptions(c *gin.Context) {
+	c.Header("Tus-Version", helpers.TusVersion)
+	c.Header("Tus-Extension", "creation,termination,checksum,expiration")
+	c.Header("Tus-Max-Size", strconv.FormatInt(h.cfg.MaxUploadBytes, 10))
+	c.Header("Tus-Checksum-Algorithm", strings.Join(helpers.TusChecksumAlgorithms, ","))
+	c.Status(http.StatusNoContent)
+}
+
+func (h *Handler) TusCreate(c *gin.Context) {
+	userID := c.GetString("userID")
+
+	length, err := strconv.ParseInt(c.GetHeader("Upload-Length"), 10, 64)
+	if err != nil || length < 0 {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "valid Upload-Length header is required"})
+		return
+	}
+	if length > h.cfg.MaxUploadBytes {
+		c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{"error": "upload exceeds maximum size"})
+		return
+	}
+
+	meta, err := helpers.ParseTusMetadata(c.GetHeader("Upload-Metadata"))
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	filename := meta["filename"]
+	if filename == "" {
+		filename = "upload"
+	}
+
+	upload, err := helpers.CreateTusUpload(c.Request.Context(), h.cfg.TusUploadDir, userID, length, filename, meta["note"])
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "could not create upload"})
+		return
+	}
+
+	c.Header("Location", requestBaseURL(c)+"/uploads/tus/"+upload.ID)
+	c.Header("Upload-Offset", "0")
+	h.setUploadExpires(c)
+
+	if length == 0 {
+		h.tusComplete(c, upload)
+		return
+	}
+	c.Status(http.StatusCreated)
+}
+
+func (h *Handler) TusHead(c *gin.Context) {
+	upload, ok := loadTusUpload(c)
+	if !ok {
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.Header("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	c.Header("Upload-Length", strconv.FormatInt(upload.Length, 10))
+	if upload.PhotoID != nil {
+		c.Header("Memora-Photo-Id", strconv.FormatInt(*upload.PhotoID, 10))
+	}
+	c.Status(http.StatusOK)
+}
+
+func (h *Handler) TusPatch(c *gin.Context) {
+	if c.ContentType() != "application/offset+octet-stream" {
+		c.AbortWithStatusJSON(http.StatusUnsupportedMediaType, gin.H{"error": "Content-Type must be application/offset+octet-stream"})
+		return
+	}
+
+	unlock, err := helpers.LockTusUpload(c.Param("id"))
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusLocked, gin.H{"error": err.Error()})
+		return
+	}
+	defer unlock()
+
+	upload, ok := loadTusUpload(c)
+	if !ok {
+		return
+	}
+
+	offset, err := strconv.ParseInt(c.GetHeader("Upload-Offset"), 10, 64)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "valid Upload-Offset header is required"})
+		return
+	}
+	if offset != upload.Offset || upload.PhotoID != nil {
+		c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": "offset does not match upload"})
+		return
+	}
+
+	sum, err := helpers.ParseTusChecksum(c.GetHeader("Upload-Checksum"))
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := helpers.WriteTusChunk(h.cfg.TusUploadDir, upload, c.Request.Body, sum); err != nil {
+		if errors.Is(err, helpers.ErrTusChecksumMismatch) {
+			c.AbortWithStatusJSON(statusChecksumMismatch, gin.H{"error": err.Error()})
+			return
+		}
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "could not write chunk"})
+		return
+	}
+
+	c.Header("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	if upload.Offset == upload.Length {
+		h.tusComplete(c, upload)
+		return
+	}
+	h.setUploadExpires(c)
+	c.Status(http.StatusNoContent)
+}
+
+// setUploadExpires tells the client until when an unfinished upload can be
+// resumed.
+func (h *Handler) setUploadExpires(c *gin.Context) {
+	c.Header("Upload-Expires", time.Now().Add(h.cfg.TusUploadExpiry).UTC().Format(http.TimeFormat))
+}
+
+func (h *Handler) TusDelete(c *gin.Context) {
+	unlock, err := helpers.LockTusUpload(c.Param("id"))
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusLocked, gin.H{"error": err.Error()})
+		return
+	}
+	defer unlock()
+
+	upload, ok := loadTusUpload(c)
+	if !ok {
+		return
+	}
+	if err := helpers.DeleteTusUpload(c.Request.Context(), h.cfg.TusUploadDir, upload.ID); err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "could not delete upload"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func loadTusUpload(c *gin.Context) (*helpers.TusUpload, bool) {
+	upload, err := helpers.GetTusUpload(c.Request.Context(), c.Param("id"), c.GetString("userID"))
+	if errors.Is(err, helpers.ErrTusNotFound) {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "could not load upload"})
+		return nil, false
+	}
+	return upload, true
+}
+
+// tusComplete hands a finished upload to the regular photo pipeline and
+// remembers the resulting photo so retried requests don't store it twice.
+// When storing fails the file goes back into the upload, so completing it
+// can be retried; an upload whose file is gone is deleted.
+func (h *Handler) tusComplete(c *gin.Context, upload *helpers.TusUpload) {
+	dst, info, err := helpers.FinishTusUpload(h.cfg.TusUploadDir, upload)
+	if errors.Is(err, helpers.ErrTusGone) {
+		_ = helpers.DeleteTusUpload(c.Request.Context(), h.cfg.TusUploadDir, upload.ID)
+		c.AbortWithStatusJSON(http.StatusGone, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, helpers.ErrUnsupportedType) {
+		_ = helpers.DeleteTusUpload(c.Request.Context(), h.cfg.TusUploadDir, upload.ID)
+		c.AbortWithStatusJSON(http.StatusUnsupportedMediaType, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "could not save file"})
+		return
+	}
+
+	photo, err := h.storePhoto(c.Request.Context(), requestBaseURL(c), upload.UserID, dst, upload.Note, info, func(photoID int64) error {
+		return helpers.SetTusUploadPhoto(c.Request.Context(), upload.ID, photoID)
+	})
+	switch {
+	case errors.Is(err, errPhotoKept):
+		fmt.Fprintf(os.Stderr, "tus upload %s: %v\n", upload.ID, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "could not save metadata"})
+		return
+	case errors.Is(err, helpers.ErrUnsupportedType):
+		os.Remove(dst)
+		_ = helpers.DeleteTusUpload(c.Request.Context(), h.cfg.TusUploadDir, upload.ID)
+		c.AbortWithStatusJSON(http.StatusUnsupportedMediaType, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		if rerr := helpers.RestoreTusUpload(h.cfg.TusUploadDir, upload, dst); rerr != nil {
+			fmt.Fprintf(os.Stderr, "tus upload %s: %v\n", upload.ID, rerr)
+			os.Remove(dst)
+		}
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "could not save metadata"})
+		return
+	}
+
+	c.Header("Memora-Photo-Id", strconv.FormatInt(photo.ID, 10))
+	if c.Request.Method == http.MethodPost {
+		c.Status(http.StatusCreated)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,109 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Pranjal095/Memora/backend/internal/helpers"
+)
+
+const maxFormFieldBytes = 64 << 10
+
+type uploadedFile struct {
+	Field    string
+	Filename string
+	TempPath string
+}
+
+type uploadForm struct {
+	Files  []uploadedFile
+	Fields map[string][]string
+}
+
+func (f *uploadForm) Value(key string) string {
+	if v := f.Fields[key]; len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+func (f *uploadForm) Cleanup() {
+	for _, file := range f.Files {
+		os.Remove(file.TempPath)
+	}
+}
+
+// readUploadForm walks a multipart body part by part, streaming file parts
+// for the given field names to temp files instead of buffering them. Each
+// file may be up to MAX_UPLOAD_BYTES and the whole body up to maxFiles of
+// them.
+func (h *Handler) readUploadForm(c *gin.Context, maxFiles int, fileFields ...string) (*uploadForm, error) {
+	maxBytes := h.cfg.MaxUploadBytes
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, helpers.MaxRequestBytes(maxBytes, maxFiles))
+
+	reader, err := c.Request.MultipartReader()
+	if err != nil {
+		return nil, fmt.Errorf("expected multipart form: %w", err)
+	}
+
+	form := &uploadForm{Fields: make(map[string][]string)}
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return form, nil
+		}
+		if err != nil {
+			form.Cleanup()
+			return nil, uploadReadError(err)
+		}
+
+		name := part.FormName()
+		if part.FileName() != "" && slices.Contains(fileFields, name) {
+			if len(form.Files) >= maxFiles {
+				part.Close()
+				form.Cleanup()
+				return nil, fmt.Errorf("too many files, at most %d allowed", maxFiles)
+			}
+			tmp, err := helpers.StreamToTemp(part, maxBytes)
+			part.Close()
+			if err != nil {
+				form.Cleanup()
+				return nil, uploadReadError(err)
+			}
+			form.Files = append(form.Files, uploadedFile{Field: name, Filename: part.FileName(), TempPath: tmp})
+			continue
+		}
+
+		value, err := io.ReadAll(io.LimitReader(part, maxFormFieldBytes))
+		part.Close()
+		if err != nil {
+			form.Cleanup()
+			return nil, uploadReadError(err)
+		}
+		form.Fields[name] = append(form.Fields[name], string(value))
+	}
+}
+
+func uploadReadError(err error) error {
+	var maxErr *http.MaxBytesError
+	if errors.As(err, &maxErr) {
+		return helpers.ErrUploadTooLarge
+	}
+	return err
+}
+
+func uploadErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, helpers.ErrUploadTooLarge):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, helpers.ErrUnsupportedType):
+		return http.StatusUnsupportedMediaType
+	}
+	return http.StatusBadRequest
+}
@@ -0,0 +1,80 @@
+package helpers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/Pranjal095/Memora/backend/config"
+)
+
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("open file: %w", err)
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("hash file: %w", err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// lookupAnalysisResult finds a stored result for the audio hash under the
+// current model. params distinguishes segmented runs from whole-clip ones.
+func lookupAnalysisResult(c context.Context, hash, params string) (*Result, error) {
+	var res Result
+	err := config.DB.QueryRow(c,
+		`SELECT result FROM analysis_results WHERE content_hash=$1 AND model_version=$2 AND params=$3`,
+		hash, Classifier().ModelVersion(), params).Scan(&res)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("lookup analysis result: %w", err)
+	}
+	res.Cached = true
+	return &res, nil
+}
+
+func storeAnalysisResult(c context.Context, hash, params string, res Result) error {
+	_, err := config.DB.Exec(c,
+		`INSERT INTO analysis_results(content_hash,model_version,params,result) VALUES($1,$2,$3,$4)
+		 ON CONFLICT (content_hash,model_version,params) DO UPDATE SET result=$4, created_at=NOW()`,
+		hash, Classifier().ModelVersion(), params, res)
+	if err != nil {
+		return fmt.Errorf("store analysis result: %w", err)
+	}
+	return nil
+}
+
+// InvalidateAnalysisResults drops cached results for one model version, for
+// every version except the current one when stale is set, or for all of
+// them otherwise.
+func InvalidateAnalysisResults(c context.Context, modelVersion string, stale bool) (int64, error) {
+	var (
+		tag pgconn.CommandTag
+		err error
+	)
+	switch {
+	case modelVersion != "":
+		tag, err = config.DB.Exec(c, `DELETE FROM analysis_results WHERE model_version=$1`, modelVersion)
+	case stale:
+		tag, err = config.DB.Exec(c, `DELETE FROM analysis_results WHERE model_version<>$1`, Classifier().ModelVersion())
+	default:
+		tag, err = config.DB.Exec(c, `DELETE FROM analysis_results`)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("invalidate analysis results: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
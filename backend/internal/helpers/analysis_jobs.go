@@ -0,0 +1,351 @@
+package helpers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/Pranjal095/Memora/backend/config"
+	"github.com/Pranjal095/Memora/backend/internal/schema"
+)
+
+const (
+	JobQueued    = "queued"
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+	JobCanceled  = "canceled"
+)
+
+var (
+	ErrJobNotFound       = errors.New("analysis job not found")
+	ErrJobFinished       = errors.New("analysis job already finished")
+	ErrAnalysisQueueFull = errors.New("analysis queue is full, try again later")
+)
+
+type AnalysisTask struct {
+	JobID    int64
+	URL      string
+	FilePath string
+	Options  schema.SegmentOptions
+}
+
+// analysisTimeline is the segment part of a result, kept in one JSONB column.
+type analysisTimeline struct {
+	Segments      []schema.AnalysisSegment `json:"segments,omitempty"`
+	Stats         *schema.SegmentStats     `json:"stats,omitempty"`
+	FlaggedRanges []schema.TimeRange       `json:"flagged_ranges,omitempty"`
+}
+
+var (
+	analysisQueue   chan AnalysisTask
+	analysisCancels = make(map[int64]context.CancelFunc)
+	// analysisHeld lists the jobs queued or running here, the ones this
+	// process heartbeats.
+	analysisHeld = make(map[int64]bool)
+	analysisMu   sync.Mutex
+)
+
+const (
+	// analysisHeartbeat is how often a process vouches for the jobs it holds.
+	analysisHeartbeat = 30 * time.Second
+	// analysisOrphanAfter is how long a queued or running job can go without
+	// a heartbeat before the process holding it is taken to be gone.
+	analysisOrphanAfter = 4 * analysisHeartbeat
+)
+
+// analysisOwner names this process in analysis_jobs.owner. Queued jobs wait
+// in its memory, so only it can run them.
+var analysisOwner = newAnalysisOwner()
+
+func newAnalysisOwner() string {
+	host, _ := os.Hostname()
+	raw := make([]byte, 8)
+	rand.Read(raw)
+	return fmt.Sprintf("%s-%d-%s", host, os.Getpid(), hex.EncodeToString(raw))
+}
+
+// StartAnalysisWorkers starts the bounded pool that runs analysis jobs, and
+// a loop that keeps this process's jobs alive and fails the ones whose
+// process stopped heartbeating, since those can't be resumed. Jobs of other
+// replicas that are still running are left alone.
+func StartAnalysisWorkers(ctx context.Context, cfg *config.Config) {
+	go func() {
+		ticker := time.NewTicker(analysisHeartbeat)
+		defer ticker.Stop()
+		for {
+			if err := heartbeatAnalysisJobs(ctx); err != nil {
+				fmt.Fprintf(os.Stderr, "analysis jobs: %v\n", err)
+			}
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	analysisQueue = make(chan AnalysisTask, cfg.AnalysisQueueSize)
+	for i := 0; i < cfg.AnalysisWorkers; i++ {
+		go func() {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case task := <-analysisQueue:
+					runAnalysisTask(ctx, cfg, task)
+				}
+			}
+		}()
+	}
+}
+
+// heartbeatAnalysisJobs marks the jobs this process holds as alive and fails
+// queued or running jobs nobody has vouched for in analysisOrphanAfter,
+// including ones from before owners were recorded. A job whose worker
+// couldn't record its status is no longer held, so it ends up failed here.
+func heartbeatAnalysisJobs(c context.Context) error {
+	if held := heldAnalysisJobs(); len(held) > 0 {
+		_, err := config.DB.Exec(c,
+			`UPDATE analysis_jobs SET heartbeat_at=NOW() WHERE owner=$1 AND id=ANY($2) AND status IN ($3,$4)`,
+			analysisOwner, held, JobQueued, JobRunning)
+		if err != nil {
+			return fmt.Errorf("failed to heartbeat analysis jobs: %w", err)
+		}
+	}
+	_, err := config.DB.Exec(c,
+		`UPDATE analysis_jobs SET status=$1, error='interrupted before its result was recorded', finished_at=NOW()
+		 WHERE status IN ($2,$3) AND (heartbeat_at IS NULL OR heartbeat_at < NOW() - make_interval(secs => $4))`,
+		JobFailed, JobQueued, JobRunning, analysisOrphanAfter.Seconds())
+	if err != nil {
+		return fmt.Errorf("failed to reset orphaned analysis jobs: %w", err)
+	}
+	return nil
+}
+
+func heldAnalysisJobs() []int64 {
+	analysisMu.Lock()
+	defer analysisMu.Unlock()
+	ids := make([]int64, 0, len(analysisHeld))
+	for id := range analysisHeld {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+func holdAnalysisJob(id int64, held bool) {
+	analysisMu.Lock()
+	defer analysisMu.Unlock()
+	if held {
+		analysisHeld[id] = true
+	} else {
+		delete(analysisHeld, id)
+	}
+}
+
+func EnqueueAnalysis(task AnalysisTask) error {
+	holdAnalysisJob(task.JobID, true)
+	select {
+	case analysisQueue <- task:
+		return nil
+	default:
+		holdAnalysisJob(task.JobID, false)
+		return ErrAnalysisQueueFull
+	}
+}
+
+// DiscardAnalysisTask releases resources held by a task that never ran.
+func DiscardAnalysisTask(task AnalysisTask) {
+	if task.FilePath != "" {
+		os.Remove(task.FilePath)
+	}
+}
+
+// runAnalysisTask runs a queued job and records how it ended. Whether or not
+// that could be recorded, the job is no longer held afterwards.
+func runAnalysisTask(parent context.Context, cfg *config.Config, task AnalysisTask) {
+	defer DiscardAnalysisTask(task)
+	defer holdAnalysisJob(task.JobID, false)
+
+	timeout := cfg.AnalysisJobTimeout
+	ctx, cancel := context.WithTimeout(parent, timeout)
+	analysisMu.Lock()
+	analysisCancels[task.JobID] = cancel
+	analysisMu.Unlock()
+	defer func() {
+		analysisMu.Lock()
+		delete(analysisCancels, task.JobID)
+		analysisMu.Unlock()
+		cancel()
+	}()
+
+	tag, err := config.DB.Exec(ctx,
+		`UPDATE analysis_jobs SET status=$1, started_at=NOW() WHERE id=$2 AND status=$3`,
+		JobRunning, task.JobID, JobQueued)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to start analysis job %d: %v\n", task.JobID, err)
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		return
+	}
+
+	var res Result
+	if task.FilePath != "" {
+		res, err = AnalyzeFile(ctx, cfg, task.FilePath, task.Options)
+	} else {
+		res, err = AnalyzeURL(ctx, cfg, task.URL, task.Options)
+	}
+
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		finishAnalysisJob(task.JobID, JobFailed, nil, fmt.Sprintf("timed out after %s", timeout))
+	case errors.Is(ctx.Err(), context.Canceled):
+		finishAnalysisJob(task.JobID, JobCanceled, nil, "")
+	case err != nil:
+		finishAnalysisJob(task.JobID, JobFailed, nil, err.Error())
+	default:
+		finishAnalysisJob(task.JobID, JobSucceeded, &res, "")
+	}
+}
+
+func finishAnalysisJob(id int64, status string, res *Result, errMsg string) {
+	var (
+		probability *float64
+		label       *string
+		cached      bool
+		timeline    *analysisTimeline
+	)
+	if res != nil {
+		probability, label, cached = &res.Probability, &res.Label, res.Cached
+		if res.Stats != nil {
+			timeline = &analysisTimeline{res.Segments, res.Stats, res.FlaggedRanges}
+		}
+	}
+	_, err := config.DB.Exec(context.Background(),
+		`UPDATE analysis_jobs SET status=$2, probability=$3, label=$4, cached=$5, timeline=$6,
+		        error=NULLIF($7,''), finished_at=NOW()
+		 WHERE id=$1`,
+		id, status, probability, label, cached, timeline, errMsg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to record analysis job %d: %v\n", id, err)
+	}
+}
+
+func CreateAnalysisJob(c context.Context, userID, sourceURL, filename string, opts schema.SegmentOptions) (int64, error) {
+	var options *schema.SegmentOptions
+	if opts.WindowSeconds > 0 {
+		options = &opts
+	}
+
+	var id int64
+	err := config.DB.QueryRow(c,
+		`INSERT INTO analysis_jobs(user_id,source_url,filename,options,owner,heartbeat_at)
+		 VALUES($1,NULLIF($2,''),NULLIF($3,''),$4,$5,NOW()) RETURNING id`,
+		userID, sourceURL, filename, options, analysisOwner).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create analysis job: %w", err)
+	}
+	return id, nil
+}
+
+// CancelAnalysisJob stops a queued or running job. Queued jobs are marked
+// canceled here; running ones are interrupted and recorded by their worker.
+func CancelAnalysisJob(c context.Context, id int64, userID string) error {
+	var status string
+	err := config.DB.QueryRow(c,
+		`UPDATE analysis_jobs SET status=CASE WHEN status=$3 THEN $4 ELSE status END,
+		        finished_at=CASE WHEN status=$3 THEN NOW() ELSE finished_at END
+		 WHERE id=$1 AND user_id=$2 RETURNING status`,
+		id, userID, JobQueued, JobCanceled).Scan(&status)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrJobNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to cancel analysis job: %w", err)
+	}
+
+	switch status {
+	case JobCanceled:
+		return nil
+	case JobRunning:
+		analysisMu.Lock()
+		if cancel, ok := analysisCancels[id]; ok {
+			cancel()
+		}
+		analysisMu.Unlock()
+		return nil
+	}
+	return ErrJobFinished
+}
+
+const analysisJobColumns = `id,status,source_url,filename,options,probability,label,cached,timeline,error,created_at,started_at,finished_at`
+
+func scanAnalysisJob(row pgx.Row) (*schema.AnalysisJobResponse, error) {
+	var (
+		j                     schema.AnalysisJobResponse
+		timeline              *analysisTimeline
+		createdAt             time.Time
+		startedAt, finishedAt *time.Time
+	)
+	err := row.Scan(&j.ID, &j.Status, &j.SourceURL, &j.Filename, &j.Options, &j.Probability, &j.Label, &j.Cached,
+		&timeline, &j.Error, &createdAt, &startedAt, &finishedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if timeline != nil {
+		j.Segments, j.Stats, j.FlaggedRanges = timeline.Segments, timeline.Stats, timeline.FlaggedRanges
+	}
+
+	j.CreatedAt = createdAt.Format(time.RFC3339)
+	if startedAt != nil {
+		s := startedAt.Format(time.RFC3339)
+		j.StartedAt = &s
+	}
+	if finishedAt != nil {
+		s := finishedAt.Format(time.RFC3339)
+		j.FinishedAt = &s
+	}
+	return &j, nil
+}
+
+func GetAnalysisJob(c context.Context, id int64, userID string) (*schema.AnalysisJobResponse, error) {
+	row := config.DB.QueryRow(c,
+		`SELECT `+analysisJobColumns+` FROM analysis_jobs WHERE id=$1 AND user_id=$2`, id, userID)
+	j, err := scanAnalysisJob(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrJobNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query analysis job: %w", err)
+	}
+	return j, nil
+}
+
+func ListAnalysisJobs(c context.Context, userID string, limit int) ([]schema.AnalysisJobResponse, error) {
+	rows, err := config.DB.Query(c,
+		`SELECT `+analysisJobColumns+` FROM analysis_jobs WHERE user_id=$1 ORDER BY created_at DESC LIMIT $2`,
+		userID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query analysis jobs: %w", err)
+	}
+	defer rows.Close()
+
+	jobs := []schema.AnalysisJobResponse{}
+	for rows.Next() {
+		j, err := scanAnalysisJob(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read analysis job: %w", err)
+		}
+		jobs = append(jobs, *j)
+	}
+	return jobs, rows.Err()
+}
@@ -0,0 +1,33 @@
+package helpers
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestEnqueueAnalysisHoldsJob(t *testing.T) {
+	prev := analysisQueue
+	analysisQueue = make(chan AnalysisTask, 1)
+	t.Cleanup(func() {
+		analysisQueue = prev
+		holdAnalysisJob(1, false)
+	})
+
+	if err := EnqueueAnalysis(AnalysisTask{JobID: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := EnqueueAnalysis(AnalysisTask{JobID: 2}); !errors.Is(err, ErrAnalysisQueueFull) {
+		t.Fatalf("got %v, want ErrAnalysisQueueFull", err)
+	}
+	// Only the queued job is heartbeated; the rejected one is the caller's
+	// to fail.
+	if held := heldAnalysisJobs(); !slices.Equal(held, []int64{1}) {
+		t.Errorf("held %v, want [1]", held)
+	}
+
+	holdAnalysisJob(1, false)
+	if held := heldAnalysisJobs(); len(held) != 0 {
+		t.Errorf("held %v after release", held)
+	}
+}
@@ -2,67 +2,111 @@ package helpers
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
-	"net/http"
 	"net/url"
 	"os"
 	"os/exec"
 	"path"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
+
+	"github.com/Pranjal095/Memora/backend/config"
+	"github.com/Pranjal095/Memora/backend/internal/schema"
 )
 
 type Result struct {
-	Probability float64 `json:"probability"`
-	Label       string  `json:"label"`
+	Probability   float64                  `json:"probability"`
+	Label         string                   `json:"label"`
+	Cached        bool                     `json:"cached"`
+	Segments      []schema.AnalysisSegment `json:"segments,omitempty"`
+	Stats         *schema.SegmentStats     `json:"stats,omitempty"`
+	FlaggedRanges []schema.TimeRange       `json:"flagged_ranges,omitempty"`
 }
 
 var audioExts = map[string]struct{}{
 	".mp3": {}, ".wav": {}, ".m4a": {}, ".flac": {}, ".ogg": {}, ".aac": {},
 }
 
-func AnalyzeURL(c context.Context, urlStr string) (Result, error) {
+// AnalyzeURL scores the audio behind urlStr. With a window in opts the clip
+// is scored window by window and the result carries a timeline.
+func AnalyzeURL(c context.Context, cfg *config.Config, urlStr string, opts schema.SegmentOptions) (Result, error) {
+	tmp, err := os.MkdirTemp("", "memora")
+	if err != nil {
+		return Result{}, fmt.Errorf("create temp dir: %w", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	input, err := fetchAudio(c, cfg, urlStr, tmp)
+	if err != nil {
+		return Result{}, err
+	}
+	if _, err := ProbeAudio(c, input, cfg.AnalyzeMaxDuration); err != nil {
+		return Result{}, err
+	}
+	return analyzeInput(c, cfg, input, tmp, opts)
+}
+
+// AnalyzeFile runs a local audio or video file through the same
+// normalization and inference steps as AnalyzeURL. Uploads should be checked
+// with CheckAudioFile first.
+func AnalyzeFile(c context.Context, cfg *config.Config, input string, opts schema.SegmentOptions) (Result, error) {
 	tmp, err := os.MkdirTemp("", "memora")
 	if err != nil {
 		return Result{}, fmt.Errorf("create temp dir: %w", err)
 	}
 	defer os.RemoveAll(tmp)
 
+	return analyzeInput(c, cfg, input, tmp, opts)
+}
+
+func fetchAudio(c context.Context, cfg *config.Config, urlStr, tmp string) (string, error) {
 	input := filepath.Join(tmp, "input")
 	u, err := url.Parse(urlStr)
 	if err != nil {
-		return Result{}, fmt.Errorf("invalid URL: %w", err)
+		return "", fmt.Errorf("invalid URL: %w", err)
 	}
 	ext := strings.ToLower(path.Ext(u.Path))
 
 	if _, ok := audioExts[ext]; ok {
-		resp, err := http.Get(urlStr)
-		if err != nil {
-			return Result{}, fmt.Errorf("fetch URL: %w", err)
-		}
-		defer resp.Body.Close()
-		f, err := os.Create(input + ext)
-		if err != nil {
-			return Result{}, fmt.Errorf("create file: %w", err)
+		limits := FetchLimits{
+			MaxBytes:       cfg.AnalyzeMaxBytes,
+			ConnectTimeout: cfg.AnalyzeConnectTimeout,
+			ReadTimeout:    cfg.AnalyzeReadTimeout,
 		}
-		io.Copy(f, resp.Body)
-		f.Close()
-		input += ext
-	} else {
-		cmd := exec.CommandContext(c,
-			"yt-dlp",
-			"-x", "--audio-format", "wav",
-			urlStr,
-			"-o", input+".%(ext)s",
-		)
-		if out, err := cmd.CombinedOutput(); err != nil {
-			return Result{}, fmt.Errorf("download failed: %s", out)
+		if err := SafeFetch(c, urlStr, input+ext, limits); err != nil {
+			return "", err
 		}
-		input += ".wav"
+		return input + ext, nil
+	}
+
+	if err := CheckPublicHost(c, urlStr); err != nil {
+		return "", err
+	}
+	cmd := exec.CommandContext(c,
+		"yt-dlp",
+		"--no-playlist",
+		"--use-extractors", cfg.AnalyzeExtractors,
+		"--max-filesize", strconv.FormatInt(cfg.AnalyzeMaxBytes, 10),
+		"--match-filters", fmt.Sprintf("duration <= %d", int(cfg.AnalyzeMaxDuration.Seconds())),
+		"--socket-timeout", strconv.Itoa(int(cfg.AnalyzeReadTimeout.Seconds())),
+		"-x", "--audio-format", "wav",
+		urlStr,
+		"-o", input+".%(ext)s",
+	)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return "", fmt.Errorf("download failed: %s", out)
 	}
+	// yt-dlp exits cleanly when a filter rejects the media, leaving no file.
+	if _, err := os.Stat(input + ".wav"); err != nil {
+		return "", fmt.Errorf("download rejected: media exceeds duration or size limits or is not from an allowed site")
+	}
+	return input + ".wav", nil
+}
 
+func analyzeInput(c context.Context, cfg *config.Config, input, tmp string, opts schema.SegmentOptions) (Result, error) {
 	wav := filepath.Join(tmp, "audio.wav")
 	cmd := exec.CommandContext(c,
 		"ffmpeg", "-y",
@@ -74,16 +118,153 @@ func AnalyzeURL(c context.Context, urlStr string) (Result, error) {
 		return Result{}, fmt.Errorf("conversion failed: %s", out)
 	}
 
-	py := filepath.Join("microservice", "main.py")
-	outBytes, err := exec.CommandContext(c, "python3", py, wav).CombinedOutput()
+	// The cache is keyed by the normalized audio so the same clip hits it
+	// whatever container or URL it arrived in.
+	hash, err := hashFile(wav)
+	if err != nil {
+		return Result{}, err
+	}
+
+	var params string
+	if opts.WindowSeconds > 0 {
+		params = fmt.Sprintf("window=%g,stride=%g", opts.WindowSeconds, opts.StrideSeconds)
+	}
+
+	res, err := lookupAnalysisResult(c, hash, params)
 	if err != nil {
-		fmt.Printf("%s\n", outBytes)
-		return Result{}, fmt.Errorf("inference failed: %s", outBytes)
+		return Result{}, err
 	}
+	if res == nil {
+		var fresh Result
+		if opts.WindowSeconds > 0 {
+			fresh, err = classifySegments(c, wav, tmp, opts, cfg.AnalysisMaxSegments)
+		} else {
+			fresh, err = Classifier().Classify(c, wav)
+		}
+		if err != nil {
+			return Result{}, err
+		}
+		if err := storeAnalysisResult(c, hash, params, fresh); err != nil {
+			return Result{}, err
+		}
+		res = &fresh
+	}
+
+	if opts.WindowSeconds > 0 {
+		summarizeSegments(res, opts)
+	}
+	return *res, nil
+}
+
+// NormalizeSegmentOptions validates opts and fills in the defaults: a stride
+// of half the window and a threshold of 0.5. A zero window means the clip is
+// scored as a whole.
+func NormalizeSegmentOptions(opts schema.SegmentOptions) (schema.SegmentOptions, error) {
+	if opts.WindowSeconds == 0 {
+		if opts.StrideSeconds != 0 || opts.Threshold != 0 || opts.FlaggedOnly {
+			return opts, errors.New("segment options require window_seconds")
+		}
+		return opts, nil
+	}
+	if opts.WindowSeconds < 1 || opts.WindowSeconds > 600 {
+		return opts, errors.New("window_seconds must be between 1 and 600")
+	}
+	if opts.StrideSeconds == 0 {
+		opts.StrideSeconds = opts.WindowSeconds / 2
+	}
+	if opts.StrideSeconds < 0.5 || opts.StrideSeconds > opts.WindowSeconds {
+		return opts, errors.New("stride_seconds must be between 0.5 and window_seconds")
+	}
+	if opts.Threshold == 0 {
+		opts.Threshold = 0.5
+	}
+	if opts.Threshold < 0 || opts.Threshold > 1 {
+		return opts, errors.New("threshold must be between 0 and 1")
+	}
+	return opts, nil
+}
+
+// classifySegments scores overlapping windows of the normalized WAV, failing
+// when that would take more than maxSegments. The last window is cut short at
+// the end of the clip.
+func classifySegments(c context.Context, wav, tmp string, opts schema.SegmentOptions, maxSegments int) (Result, error) {
+	audio, err := openWAV(wav)
+	if err != nil {
+		return Result{}, err
+	}
+
+	total := audio.Duration()
+	window := time.Duration(opts.WindowSeconds * float64(time.Second))
+	stride := time.Duration(opts.StrideSeconds * float64(time.Second))
+	if total <= 0 {
+		return Result{}, fmt.Errorf("audio is empty")
+	}
+	if n := int((total-window)/stride) + 2; n > maxSegments {
+		return Result{}, fmt.Errorf("audio would need %d segments, at most %d allowed", n, maxSegments)
+	}
+
 	var res Result
-	if err := json.Unmarshal(outBytes, &res); err != nil {
-		return Result{}, fmt.Errorf("invalid inference output: %w", err)
+	for i, start := 0, time.Duration(0); start < total; i, start = i+1, start+stride {
+		end := min(start+window, total)
+		seg := filepath.Join(tmp, fmt.Sprintf("segment_%d.wav", i))
+		if err := audio.WriteWindow(seg, start, end-start); err != nil {
+			return Result{}, err
+		}
+
+		r, err := Classifier().Classify(c, seg)
+		os.Remove(seg)
+		if err != nil {
+			return Result{}, fmt.Errorf("segment at %s: %w", start, err)
+		}
+
+		res.Segments = append(res.Segments, schema.AnalysisSegment{
+			StartMs:     start.Milliseconds(),
+			EndMs:       end.Milliseconds(),
+			Probability: r.Probability,
+			Label:       r.Label,
+		})
+		if end == total {
+			break
+		}
 	}
-	fmt.Println(res)
 	return res, nil
 }
+
+// summarizeSegments derives the flags, statistics and overall score of a
+// timeline for the requested threshold.
+func summarizeSegments(res *Result, opts schema.SegmentOptions) {
+	stats := schema.SegmentStats{Count: len(res.Segments), Threshold: opts.Threshold}
+	var sum float64
+	var flagged int
+	res.FlaggedRanges = nil
+
+	for i := range res.Segments {
+		seg := &res.Segments[i]
+		seg.Flagged = seg.Probability >= opts.Threshold
+		sum += seg.Probability
+		if i == 0 || seg.Probability > stats.Max {
+			stats.Max = seg.Probability
+			res.Probability, res.Label = seg.Probability, seg.Label
+		}
+		if !seg.Flagged {
+			continue
+		}
+		flagged++
+
+		last := len(res.FlaggedRanges) - 1
+		if last >= 0 && seg.StartMs <= res.FlaggedRanges[last].EndMs {
+			res.FlaggedRanges[last].EndMs = max(res.FlaggedRanges[last].EndMs, seg.EndMs)
+		} else {
+			res.FlaggedRanges = append(res.FlaggedRanges, schema.TimeRange{StartMs: seg.StartMs, EndMs: seg.EndMs})
+		}
+	}
+
+	if stats.Count > 0 {
+		stats.Mean = sum / float64(stats.Count)
+		stats.FractionAbove = float64(flagged) / float64(stats.Count)
+	}
+	res.Stats = &stats
+	if opts.FlaggedOnly {
+		res.Segments = nil
+	}
+}
@@ -0,0 +1,154 @@
+package helpers
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/Pranjal095/Memora/backend/internal/schema"
+)
+
+func TestNormalizeSegmentOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      schema.SegmentOptions
+		want    schema.SegmentOptions
+		wantErr bool
+	}{
+		{"whole clip", schema.SegmentOptions{}, schema.SegmentOptions{}, false},
+		{"defaults", schema.SegmentOptions{WindowSeconds: 10},
+			schema.SegmentOptions{WindowSeconds: 10, StrideSeconds: 5, Threshold: 0.5}, false},
+		{"explicit", schema.SegmentOptions{WindowSeconds: 4, StrideSeconds: 4, Threshold: 0.9, FlaggedOnly: true},
+			schema.SegmentOptions{WindowSeconds: 4, StrideSeconds: 4, Threshold: 0.9, FlaggedOnly: true}, false},
+		{"smallest window", schema.SegmentOptions{WindowSeconds: 1},
+			schema.SegmentOptions{WindowSeconds: 1, StrideSeconds: 0.5, Threshold: 0.5}, false},
+		{"largest window", schema.SegmentOptions{WindowSeconds: 600, Threshold: 1},
+			schema.SegmentOptions{WindowSeconds: 600, StrideSeconds: 300, Threshold: 1}, false},
+		{"stride without window", schema.SegmentOptions{StrideSeconds: 2}, schema.SegmentOptions{}, true},
+		{"threshold without window", schema.SegmentOptions{Threshold: 0.4}, schema.SegmentOptions{}, true},
+		{"flagged only without window", schema.SegmentOptions{FlaggedOnly: true}, schema.SegmentOptions{}, true},
+		{"window too short", schema.SegmentOptions{WindowSeconds: 0.5}, schema.SegmentOptions{}, true},
+		{"window too long", schema.SegmentOptions{WindowSeconds: 601}, schema.SegmentOptions{}, true},
+		{"negative window", schema.SegmentOptions{WindowSeconds: -5}, schema.SegmentOptions{}, true},
+		{"stride too short", schema.SegmentOptions{WindowSeconds: 10, StrideSeconds: 0.25}, schema.SegmentOptions{}, true},
+		{"stride past window", schema.SegmentOptions{WindowSeconds: 10, StrideSeconds: 11}, schema.SegmentOptions{}, true},
+		{"threshold above one", schema.SegmentOptions{WindowSeconds: 10, Threshold: 1.5}, schema.SegmentOptions{}, true},
+		{"negative threshold", schema.SegmentOptions{WindowSeconds: 10, Threshold: -0.1}, schema.SegmentOptions{}, true},
+	}
+	for _, tt := range tests {
+		got, err := NormalizeSegmentOptions(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: error %v, want error %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if err == nil && got != tt.want {
+			t.Errorf("%s: got %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSummarizeSegments(t *testing.T) {
+	seg := func(start, end int64, p float64, label string) schema.AnalysisSegment {
+		return schema.AnalysisSegment{StartMs: start, EndMs: end, Probability: p, Label: label}
+	}
+	timeline := []schema.AnalysisSegment{
+		seg(0, 2000, 0.2, "real"),
+		seg(1000, 3000, 0.7, "fake"),
+		seg(2000, 4000, 0.9, "fake"),
+		seg(3000, 5000, 0.1, "real"),
+		seg(6000, 8000, 0.6, "fake"),
+	}
+
+	tests := []struct {
+		name        string
+		segments    []schema.AnalysisSegment
+		opts        schema.SegmentOptions
+		flagged     []bool
+		ranges      []schema.TimeRange
+		stats       schema.SegmentStats
+		probability float64
+		label       string
+	}{
+		{
+			name:        "overlapping flags merge",
+			segments:    timeline,
+			opts:        schema.SegmentOptions{Threshold: 0.5},
+			flagged:     []bool{false, true, true, false, true},
+			ranges:      []schema.TimeRange{{StartMs: 1000, EndMs: 4000}, {StartMs: 6000, EndMs: 8000}},
+			stats:       schema.SegmentStats{Count: 5, Threshold: 0.5, Max: 0.9, Mean: 0.5, FractionAbove: 0.6},
+			probability: 0.9, label: "fake",
+		},
+		{
+			name:        "threshold is inclusive",
+			segments:    timeline,
+			opts:        schema.SegmentOptions{Threshold: 0.7},
+			flagged:     []bool{false, true, true, false, false},
+			ranges:      []schema.TimeRange{{StartMs: 1000, EndMs: 4000}},
+			stats:       schema.SegmentStats{Count: 5, Threshold: 0.7, Max: 0.9, Mean: 0.5, FractionAbove: 0.4},
+			probability: 0.9, label: "fake",
+		},
+		{
+			name:        "touching windows merge",
+			segments:    []schema.AnalysisSegment{seg(0, 1000, 0.8, "fake"), seg(1000, 2000, 0.8, "fake"), seg(2500, 3000, 0.3, "real")},
+			opts:        schema.SegmentOptions{Threshold: 0.5},
+			flagged:     []bool{true, true, false},
+			ranges:      []schema.TimeRange{{StartMs: 0, EndMs: 2000}},
+			stats:       schema.SegmentStats{Count: 3, Threshold: 0.5, Max: 0.8, Mean: 1.9 / 3, FractionAbove: 2.0 / 3},
+			probability: 0.8, label: "fake",
+		},
+		{
+			name:        "nothing flagged",
+			segments:    []schema.AnalysisSegment{seg(0, 1000, 0.1, "real"), seg(500, 1500, 0.3, "real")},
+			opts:        schema.SegmentOptions{Threshold: 0.5},
+			flagged:     []bool{false, false},
+			stats:       schema.SegmentStats{Count: 2, Threshold: 0.5, Max: 0.3, Mean: 0.2},
+			probability: 0.3, label: "real",
+		},
+		{
+			name:  "empty",
+			opts:  schema.SegmentOptions{Threshold: 0.5},
+			stats: schema.SegmentStats{Threshold: 0.5},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, flaggedOnly := range []bool{false, true} {
+				res := Result{
+					Segments: append([]schema.AnalysisSegment(nil), tt.segments...),
+					// Left over from an earlier summary with another threshold.
+					FlaggedRanges: []schema.TimeRange{{StartMs: 0, EndMs: 99999}},
+				}
+				opts := tt.opts
+				opts.FlaggedOnly = flaggedOnly
+				summarizeSegments(&res, opts)
+
+				if !reflect.DeepEqual(res.FlaggedRanges, tt.ranges) {
+					t.Errorf("ranges: got %v, want %v", res.FlaggedRanges, tt.ranges)
+				}
+				if s := res.Stats; s == nil || s.Count != tt.stats.Count || s.Threshold != tt.stats.Threshold ||
+					!near(s.Max, tt.stats.Max) || !near(s.Mean, tt.stats.Mean) || !near(s.FractionAbove, tt.stats.FractionAbove) {
+					t.Errorf("stats: got %+v, want %+v", res.Stats, tt.stats)
+				}
+				if res.Probability != tt.probability || res.Label != tt.label {
+					t.Errorf("overall: got %v %q, want %v %q", res.Probability, res.Label, tt.probability, tt.label)
+				}
+
+				if flaggedOnly {
+					if res.Segments != nil {
+						t.Errorf("flagged_only kept %d segments", len(res.Segments))
+					}
+					continue
+				}
+				for i, s := range res.Segments {
+					if s.Flagged != tt.flagged[i] {
+						t.Errorf("segment %d flagged %v, want %v", i, s.Flagged, tt.flagged[i])
+					}
+				}
+			}
+		})
+	}
+}
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
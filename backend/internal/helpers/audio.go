@@ -0,0 +1,111 @@
+package helpers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"time"
+
+	"github.com/gabriel-vasile/mimetype"
+)
+
+// audioMimeTypes lists the containers accepted for analysis uploads. Video
+// containers are allowed for their audio track.
+var audioMimeTypes = []string{
+	"audio/mpeg",
+	"audio/wav",
+	"audio/x-m4a",
+	"audio/mp4",
+	"audio/flac",
+	"audio/ogg",
+	"audio/aac",
+	"audio/webm",
+	"video/mp4",
+	"video/quicktime",
+	"video/webm",
+	"video/x-matroska",
+	"video/x-m4v",
+	"video/3gpp",
+	"video/3gpp2",
+}
+
+type AudioInfo struct {
+	MimeType   string
+	Codec      string
+	DurationMs int64
+}
+
+// CheckAudioFile identifies an uploaded file by its magic bytes and probes it
+// with ffprobe, rejecting anything outside the allowlist, without a readable
+// audio stream or longer than maxDuration.
+func CheckAudioFile(c context.Context, path string, maxDuration time.Duration) (AudioInfo, error) {
+	mtype, err := mimetype.DetectFile(path)
+	if err != nil {
+		return AudioInfo{}, fmt.Errorf("detect type: %w", err)
+	}
+
+	var mimeType string
+	for _, allowed := range audioMimeTypes {
+		if mtype.Is(allowed) {
+			mimeType = allowed
+			break
+		}
+	}
+	if mimeType == "" {
+		return AudioInfo{}, fmt.Errorf("%w: %s", ErrUnsupportedType, mtype.String())
+	}
+
+	info, err := ProbeAudio(c, path, maxDuration)
+	if err != nil {
+		return AudioInfo{}, err
+	}
+	info.MimeType = mimeType
+	return info, nil
+}
+
+// ProbeAudio checks that path holds a decodable audio stream no longer than
+// maxDuration.
+func ProbeAudio(c context.Context, path string, maxDuration time.Duration) (AudioInfo, error) {
+	out, err := exec.CommandContext(c,
+		"ffprobe", "-v", "error",
+		"-print_format", "json",
+		"-show_format", "-show_streams",
+		"-select_streams", "a",
+		path,
+	).Output()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return AudioInfo{}, fmt.Errorf("%w: unreadable audio", ErrUnsupportedType)
+	}
+	if err != nil {
+		return AudioInfo{}, fmt.Errorf("ffprobe failed: %w", err)
+	}
+
+	var probe ffprobeOutput
+	if err := json.Unmarshal(out, &probe); err != nil {
+		return AudioInfo{}, fmt.Errorf("invalid ffprobe output: %w", err)
+	}
+
+	for _, s := range probe.Streams {
+		if s.CodecType != "audio" {
+			continue
+		}
+		info := AudioInfo{Codec: s.CodecName}
+
+		duration := s.Duration
+		if duration == "" {
+			duration = probe.Format.Duration
+		}
+		if secs, err := strconv.ParseFloat(duration, 64); err == nil {
+			info.DurationMs = int64(secs * 1000)
+		}
+		if limit := maxDuration; time.Duration(info.DurationMs)*time.Millisecond > limit {
+			return AudioInfo{}, fmt.Errorf("%w: audio is longer than %s", ErrUploadTooLarge, limit)
+		}
+		return info, nil
+	}
+	return AudioInfo{}, fmt.Errorf("%w: no audio stream", ErrUnsupportedType)
+}
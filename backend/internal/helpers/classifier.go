@@ -0,0 +1,288 @@
+package helpers
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Pranjal095/Memora/backend/config"
+)
+
+var ErrInferenceUnavailable = errors.New("inference service is unavailable")
+
+// AudioClassifier scores a 16 kHz mono WAV file.
+type AudioClassifier interface {
+	Name() string
+	ModelVersion() string
+	Classify(c context.Context, wavPath string) (Result, error)
+}
+
+var classifier AudioClassifier
+
+// OpenClassifier sets up the driver selected by INFERENCE_DRIVER, wrapped so
+// that its latency is recorded. It must be called before Classifier.
+func OpenClassifier(cfg *config.Config) {
+	var driver AudioClassifier
+	switch cfg.InferenceDriver {
+	case "http":
+		driver = newHTTPClassifier(cfg.InferenceURL, cfg.AnalysisModelVersion)
+	case "fake":
+		driver = fakeClassifier{}
+	default:
+		driver = subprocessClassifier{script: cfg.InferenceScript, version: cfg.AnalysisModelVersion}
+	}
+	classifier = &timedClassifier{AudioClassifier: driver}
+}
+
+// Classifier returns the driver set up by OpenClassifier.
+func Classifier() AudioClassifier {
+	return classifier
+}
+
+// subprocessClassifier starts the python model for every call, so it is
+// only suited to development.
+type subprocessClassifier struct {
+	script  string
+	version string
+}
+
+func (subprocessClassifier) Name() string           { return "subprocess" }
+func (s subprocessClassifier) ModelVersion() string { return s.version }
+
+func (s subprocessClassifier) Classify(c context.Context, wavPath string) (Result, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(c, "python3", s.script, wavPath)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		return Result{}, fmt.Errorf("inference failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	var res Result
+	if err := json.Unmarshal(stdout.Bytes(), &res); err != nil {
+		return Result{}, fmt.Errorf("invalid inference output: %w", err)
+	}
+	return res, nil
+}
+
+// httpClassifier talks to a long-lived inference service that keeps the
+// model loaded. It POSTs the WAV body to /classify and fails fast while the
+// last /health probe found the service down.
+type httpClassifier struct {
+	baseURL string
+	version string
+	client  *http.Client
+
+	mu   sync.Mutex
+	down error
+}
+
+func newHTTPClassifier(baseURL, version string) *httpClassifier {
+	return &httpClassifier{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		version: version,
+		client: &http.Client{
+			Transport: &http.Transport{
+				MaxIdleConns:        32,
+				MaxIdleConnsPerHost: 32,
+				IdleConnTimeout:     90 * time.Second,
+			},
+		},
+	}
+}
+
+func (*httpClassifier) Name() string           { return "http" }
+func (h *httpClassifier) ModelVersion() string { return h.version }
+
+// StartInferenceProbe checks the health of the inference service every 15
+// seconds until ctx is done. Only the http driver has anything to probe.
+func StartInferenceProbe(ctx context.Context) {
+	driver := Classifier()
+	if t, ok := driver.(*timedClassifier); ok {
+		driver = t.AudioClassifier
+	}
+	if h, ok := driver.(*httpClassifier); ok {
+		go h.probe(ctx, 15*time.Second)
+	}
+}
+
+func (h *httpClassifier) probe(ctx context.Context, every time.Duration) {
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+	for {
+		h.check(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// check asks /health whether the service is up and remembers the answer.
+// Until a later check succeeds, Classify returns an error wrapping
+// ErrInferenceUnavailable.
+func (h *httpClassifier) check(ctx context.Context) {
+	c, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(c, http.MethodGet, h.baseURL+"/health", nil)
+	resp, err := h.client.Do(req)
+	if err == nil {
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			err = fmt.Errorf("health check returned %s", resp.Status)
+		}
+	}
+	if ctx.Err() != nil {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	switch {
+	case err != nil && h.down == nil:
+		fmt.Fprintf(os.Stderr, "inference service %s unhealthy: %v\n", h.baseURL, err)
+	case err == nil && h.down != nil:
+		fmt.Fprintf(os.Stderr, "inference service %s recovered\n", h.baseURL)
+	}
+	h.down = nil
+	if err != nil {
+		h.down = fmt.Errorf("%w: %v", ErrInferenceUnavailable, err)
+	}
+}
+
+func (h *httpClassifier) Classify(c context.Context, wavPath string) (Result, error) {
+	h.mu.Lock()
+	down := h.down
+	h.mu.Unlock()
+	if down != nil {
+		return Result{}, down
+	}
+
+	f, err := os.Open(wavPath)
+	if err != nil {
+		return Result{}, fmt.Errorf("open audio: %w", err)
+	}
+	defer f.Close()
+
+	req, err := http.NewRequestWithContext(c, http.MethodPost, h.baseURL+"/classify", f)
+	if err != nil {
+		return Result{}, fmt.Errorf("build inference request: %w", err)
+	}
+	req.Header.Set("Content-Type", "audio/wav")
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		if c.Err() != nil {
+			return Result{}, c.Err()
+		}
+		return Result{}, fmt.Errorf("%w: %v", ErrInferenceUnavailable, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return Result{}, fmt.Errorf("inference failed: %s: %s", resp.Status, bytes.TrimSpace(body))
+	}
+
+	var res Result
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return Result{}, fmt.Errorf("invalid inference output: %w", err)
+	}
+	return res, nil
+}
+
+// fakeClassifier derives a stable score from the file contents, for tests
+// and local runs without the model.
+type fakeClassifier struct{}
+
+func (fakeClassifier) Name() string         { return "fake" }
+func (fakeClassifier) ModelVersion() string { return "fake" }
+
+func (fakeClassifier) Classify(c context.Context, wavPath string) (Result, error) {
+	f, err := os.Open(wavPath)
+	if err != nil {
+		return Result{}, fmt.Errorf("open audio: %w", err)
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return Result{}, fmt.Errorf("read audio: %w", err)
+	}
+
+	p := float64(binary.BigEndian.Uint64(h.Sum(nil))>>11) / (1 << 53)
+	label := "real"
+	if p >= 0.5 {
+		label = "fake"
+	}
+	return Result{Probability: p, Label: label}, nil
+}
+
+type ClassifierStats struct {
+	Driver string  `json:"driver"`
+	Calls  int64   `json:"calls"`
+	Errors int64   `json:"errors"`
+	AvgMs  float64 `json:"avg_ms"`
+	MaxMs  float64 `json:"max_ms"`
+	LastMs float64 `json:"last_ms"`
+	total  time.Duration
+}
+
+var (
+	classifierStats   = make(map[string]*ClassifierStats)
+	classifierStatsMu sync.Mutex
+)
+
+type timedClassifier struct {
+	AudioClassifier
+}
+
+func (t *timedClassifier) Classify(c context.Context, wavPath string) (Result, error) {
+	start := time.Now()
+	res, err := t.AudioClassifier.Classify(c, wavPath)
+	elapsed := time.Since(start)
+
+	classifierStatsMu.Lock()
+	s, ok := classifierStats[t.Name()]
+	if !ok {
+		s = &ClassifierStats{Driver: t.Name()}
+		classifierStats[t.Name()] = s
+	}
+	s.Calls++
+	if err != nil {
+		s.Errors++
+	}
+	s.total += elapsed
+	ms := float64(elapsed.Microseconds()) / 1000
+	s.LastMs = ms
+	s.MaxMs = max(s.MaxMs, ms)
+	s.AvgMs = float64(s.total.Microseconds()) / 1000 / float64(s.Calls)
+	classifierStatsMu.Unlock()
+
+	return res, err
+}
+
+func InferenceStats() []ClassifierStats {
+	classifierStatsMu.Lock()
+	defer classifierStatsMu.Unlock()
+
+	stats := make([]ClassifierStats, 0, len(classifierStats))
+	for _, s := range classifierStats {
+		stats = append(stats, *s)
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Driver < stats[j].Driver })
+	return stats
+}
@@ -0,0 +1,262 @@
+package helpers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Pranjal095/Memora/backend/config"
+	"github.com/Pranjal095/Memora/backend/internal/schema"
+)
+
+// useClassifier makes Classifier return cl for the rest of the test.
+func useClassifier(t *testing.T, cl AudioClassifier) {
+	t.Helper()
+	prev := classifier
+	classifier = cl
+	t.Cleanup(func() { classifier = prev })
+}
+
+func TestFakeClassifier(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	a, same, other := write("a.wav", "one clip"), write("same.wav", "one clip"), write("other.wav", "another clip")
+
+	c := context.Background()
+	var fake fakeClassifier
+	ra, err := fake.Classify(c, a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rs, _ := fake.Classify(c, same); rs.Probability != ra.Probability || rs.Label != ra.Label {
+		t.Errorf("same contents scored %+v and %+v", ra, rs)
+	}
+	if ro, _ := fake.Classify(c, other); ro.Probability == ra.Probability {
+		t.Errorf("different contents both scored %+v", ra)
+	}
+	for _, path := range []string{a, other} {
+		r, _ := fake.Classify(c, path)
+		if r.Probability < 0 || r.Probability >= 1 || (r.Label == "fake") != (r.Probability >= 0.5) {
+			t.Errorf("%s: got %+v", filepath.Base(path), r)
+		}
+	}
+	if _, err := fake.Classify(c, filepath.Join(dir, "missing.wav")); err == nil {
+		t.Error("classifying a missing file succeeded")
+	}
+}
+
+func TestTimedClassifier(t *testing.T) {
+	classifierStatsMu.Lock()
+	prev := classifierStats
+	classifierStats = make(map[string]*ClassifierStats)
+	classifierStatsMu.Unlock()
+	t.Cleanup(func() { classifierStats = prev })
+
+	path := filepath.Join(t.TempDir(), "clip.wav")
+	if err := os.WriteFile(path, []byte("clip"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	timed := &timedClassifier{AudioClassifier: fakeClassifier{}}
+	c := context.Background()
+	timed.Classify(c, path)
+	timed.Classify(c, path)
+	timed.Classify(c, path+".missing")
+
+	stats := InferenceStats()
+	if len(stats) != 1 {
+		t.Fatalf("got %+v, want one driver", stats)
+	}
+	s := stats[0]
+	if s.Driver != "fake" || s.Calls != 3 || s.Errors != 1 || s.MaxMs < s.LastMs || s.MaxMs < s.AvgMs {
+		t.Errorf("got %+v", s)
+	}
+}
+
+func TestOpenClassifier(t *testing.T) {
+	prev := classifier
+	t.Cleanup(func() { classifier = prev })
+
+	tests := []struct {
+		driver, name, version string
+	}{
+		{"subprocess", "subprocess", "v7"},
+		{"http", "http", "v7"},
+		{"fake", "fake", "fake"},
+	}
+	for _, tt := range tests {
+		cfg := config.Defaults()
+		cfg.InferenceDriver, cfg.InferenceURL, cfg.AnalysisModelVersion = tt.driver, "http://127.0.0.1:0", "v7"
+		OpenClassifier(cfg)
+		if got := Classifier(); got.Name() != tt.name || got.ModelVersion() != tt.version {
+			t.Errorf("%s: got %s at %s, want %s at %s", tt.driver, got.Name(), got.ModelVersion(), tt.name, tt.version)
+		}
+	}
+}
+
+func TestClassifySegments(t *testing.T) {
+	useClassifier(t, fakeClassifier{})
+	wav := writeTestWAV(t, pcmFormat(1, 1, 1000, 16), wavChunk{id: "data", data: samples(2500)})
+	opts := schema.SegmentOptions{WindowSeconds: 1, StrideSeconds: 0.75}
+
+	c := context.Background()
+	res, err := classifySegments(c, wav, t.TempDir(), opts, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][2]int64{{0, 1000}, {750, 1750}, {1500, 2500}}
+	if len(res.Segments) != len(want) {
+		t.Fatalf("got %+v, want windows %v", res.Segments, want)
+	}
+	for i, seg := range res.Segments {
+		if seg.StartMs != want[i][0] || seg.EndMs != want[i][1] {
+			t.Errorf("segment %d: got %d-%d ms, want %d-%d", i, seg.StartMs, seg.EndMs, want[i][0], want[i][1])
+		}
+	}
+
+	// The fake driver scores by content, so the same audio gives the same
+	// timeline and different windows differ.
+	again, err := classifySegments(c, wav, t.TempDir(), opts, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range again.Segments {
+		if again.Segments[i] != res.Segments[i] {
+			t.Errorf("segment %d: got %+v, then %+v", i, res.Segments[i], again.Segments[i])
+		}
+	}
+	if res.Segments[0].Probability == res.Segments[1].Probability {
+		t.Errorf("different windows scored the same: %+v", res.Segments)
+	}
+
+	if _, err := classifySegments(c, wav, t.TempDir(), opts, 3); err == nil {
+		t.Error("more segments than allowed were classified")
+	}
+}
+
+func TestHTTPClassifier(t *testing.T) {
+	var healthy atomic.Bool
+	healthy.Store(true)
+	var classified, conns atomic.Int32
+	srv := httptest.NewUnstartedServer(http.NewServeMux())
+	mux := srv.Config.Handler.(*http.ServeMux)
+	mux.HandleFunc("GET /health", func(w http.ResponseWriter, r *http.Request) {
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	})
+	mux.HandleFunc("POST /classify", func(w http.ResponseWriter, r *http.Request) {
+		classified.Add(1)
+		body, _ := io.ReadAll(r.Body)
+		if r.Header.Get("Content-Type") != "audio/wav" || string(body) != "clip" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`{"label": "fake", "probability": 0.75}`))
+	})
+	srv.Config.ConnState = func(_ net.Conn, s http.ConnState) {
+		if s == http.StateNew {
+			conns.Add(1)
+		}
+	}
+	srv.Start()
+	defer srv.Close()
+
+	path := filepath.Join(t.TempDir(), "clip.wav")
+	if err := os.WriteFile(path, []byte("clip"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	h := newHTTPClassifier(srv.URL+"/", "v1")
+	c := context.Background()
+
+	for range 3 {
+		res, err := h.Classify(c, path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Label != "fake" || res.Probability != 0.75 {
+			t.Errorf("got %+v", res)
+		}
+	}
+	if n := conns.Load(); n != 1 {
+		t.Errorf("three calls opened %d connections, want one", n)
+	}
+
+	// Once the probe finds the service down, calls fail without reaching it.
+	healthy.Store(false)
+	h.check(c)
+	before := classified.Load()
+	if _, err := h.Classify(c, path); !errors.Is(err, ErrInferenceUnavailable) {
+		t.Errorf("got %v, want ErrInferenceUnavailable", err)
+	}
+	if classified.Load() != before {
+		t.Error("an unhealthy service was called")
+	}
+
+	healthy.Store(true)
+	h.check(c)
+	if _, err := h.Classify(c, path); err != nil {
+		t.Errorf("after recovery: %v", err)
+	}
+
+	// A bad answer is an inference error, not unavailability.
+	if _, err := h.Classify(c, path+".missing"); err == nil || errors.Is(err, ErrInferenceUnavailable) {
+		t.Errorf("missing file: got %v", err)
+	}
+	if err := os.WriteFile(path, []byte("noise"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := h.Classify(c, path); err == nil || errors.Is(err, ErrInferenceUnavailable) {
+		t.Errorf("rejected request: got %v", err)
+	}
+
+	srv.Close()
+	if _, err := h.Classify(c, path); !errors.Is(err, ErrInferenceUnavailable) {
+		t.Errorf("closed server: got %v, want ErrInferenceUnavailable", err)
+	}
+}
+
+func TestHTTPClassifierProbe(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+	h := newHTTPClassifier(srv.URL, "v1")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		h.probe(ctx, time.Hour)
+		close(done)
+	}()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, err := h.Classify(ctx, "unused.wav"); errors.Is(err, ErrInferenceUnavailable) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("the probe never marked the service down")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the probe kept running after its context was canceled")
+	}
+}
@@ -3,14 +3,12 @@ package helpers
 import (
 	"fmt"
 	"net/smtp"
-	"os"
+
+	"github.com/Pranjal095/Memora/backend/config"
 )
 
-func SendOTPEmail(to, code string) error {
-	host := os.Getenv("SMTP_HOST")
-	port := os.Getenv("SMTP_PORT")
-	user := os.Getenv("SMTP_USER")
-	pass := os.Getenv("SMTP_PASS")
+func SendOTPEmail(cfg *config.Config, to, code string) error {
+	host, port, user, pass := cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUser, cfg.SMTPPass
 
 	auth := smtp.PlainAuth("", user, pass, host)
 	addr := fmt.Sprintf("%s:%s", host, port)
@@ -0,0 +1,128 @@
+package helpers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+type embedJob struct {
+	ctx  context.Context
+	req  EmbedRequest
+	done chan embedResult
+}
+
+type embedResult struct {
+	out embedResponse
+	err error
+}
+
+type embedBatchResult struct {
+	Vector  []float32 `json:"vector"`
+	Caption string    `json:"caption"`
+	Dims    int       `json:"dims"`
+	Error   string    `json:"error"`
+}
+
+// jsonPoster is the part of embedClient that the batcher needs.
+type jsonPoster interface {
+	postJSON(c context.Context, path string, in, out any) error
+}
+
+// embedBatcher collects photos from concurrent callers into /embed_batch
+// calls of up to size photos, sending a batch once it is full or wait has
+// passed since its first photo arrived.
+type embedBatcher struct {
+	size   int
+	wait   time.Duration
+	jobs   chan embedJob
+	client jsonPoster
+}
+
+func newEmbedBatcher(client jsonPoster, size int, wait time.Duration) *embedBatcher {
+	b := &embedBatcher{size: size, wait: wait, jobs: make(chan embedJob), client: client}
+	go b.run()
+	return b
+}
+
+var batcher *embedBatcher
+
+// embedBatches returns the dispatcher, or nil when batching is off.
+func embedBatches() *embedBatcher {
+	return batcher
+}
+
+// embed queues req and waits for its result. A caller that gives up is
+// dropped from its batch if it hasn't been sent yet.
+func (b *embedBatcher) embed(c context.Context, req EmbedRequest) (embedResponse, error) {
+	job := embedJob{ctx: c, req: req, done: make(chan embedResult, 1)}
+	select {
+	case b.jobs <- job:
+	case <-c.Done():
+		return embedResponse{}, c.Err()
+	}
+
+	select {
+	case res := <-job.done:
+		return res.out, res.err
+	case <-c.Done():
+		return embedResponse{}, c.Err()
+	}
+}
+
+func (b *embedBatcher) run() {
+	for {
+		batch := []embedJob{<-b.jobs}
+		timer := time.NewTimer(b.wait)
+	collect:
+		for len(batch) < b.size {
+			select {
+			case job := <-b.jobs:
+				batch = append(batch, job)
+			case <-timer.C:
+				break collect
+			}
+		}
+		timer.Stop()
+		go b.send(batch)
+	}
+}
+
+// send embeds a batch and hands each caller its own result. The call serves
+// several callers, so it is bounded by the client timeout rather than by
+// any one caller's context.
+func (b *embedBatcher) send(batch []embedJob) {
+	var (
+		jobs  []embedJob
+		items []EmbedRequest
+	)
+	for _, job := range batch {
+		if job.ctx.Err() == nil {
+			jobs = append(jobs, job)
+			items = append(items, job.req)
+		}
+	}
+	if len(jobs) == 0 {
+		return
+	}
+
+	var out struct {
+		Results []embedBatchResult `json:"results"`
+	}
+	err := b.client.postJSON(context.Background(), "/embed_batch", map[string]any{"items": items}, &out)
+	if err == nil && len(out.Results) != len(jobs) {
+		err = fmt.Errorf("embed service returned %d results for %d photos", len(out.Results), len(jobs))
+	}
+
+	for i, job := range jobs {
+		switch {
+		case err != nil:
+			job.done <- embedResult{err: err}
+		case out.Results[i].Error != "":
+			job.done <- embedResult{err: errors.New(out.Results[i].Error)}
+		default:
+			job.done <- embedResult{out: embedResponse{Vector: out.Results[i].Vector, Caption: out.Results[i].Caption, Dims: out.Results[i].Dims}}
+		}
+	}
+}
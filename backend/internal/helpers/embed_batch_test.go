@@ -0,0 +1,185 @@
+package helpers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeBatchClient answers /embed_batch calls with respond and records the
+// notes of the photos in each call.
+type fakeBatchClient struct {
+	mu      sync.Mutex
+	calls   [][]string
+	respond func(items []EmbedRequest) ([]embedBatchResult, error)
+}
+
+func (f *fakeBatchClient) postJSON(c context.Context, path string, in, out any) error {
+	items := in.(map[string]any)["items"].([]EmbedRequest)
+	var notes []string
+	for _, item := range items {
+		notes = append(notes, item.Note)
+	}
+	f.mu.Lock()
+	f.calls = append(f.calls, notes)
+	f.mu.Unlock()
+
+	results, err := f.respond(items)
+	if err != nil {
+		return err
+	}
+	b, _ := json.Marshal(map[string]any{"results": results})
+	return json.Unmarshal(b, out)
+}
+
+func (f *fakeBatchClient) sent() [][]string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return slices.Clone(f.calls)
+}
+
+// echo captions every photo with its note.
+func echo(items []EmbedRequest) ([]embedBatchResult, error) {
+	results := make([]embedBatchResult, len(items))
+	for i, item := range items {
+		results[i] = embedBatchResult{Vector: []float32{1}, Caption: item.Note, Dims: 1}
+	}
+	return results, nil
+}
+
+type embedOutcome struct {
+	note string
+	out  embedResponse
+	err  error
+}
+
+// embedAll embeds one photo per note from concurrent callers.
+func embedAll(b *embedBatcher, c context.Context, notes ...string) []embedOutcome {
+	outcomes := make([]embedOutcome, len(notes))
+	var wg sync.WaitGroup
+	for i, note := range notes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			out, err := b.embed(c, EmbedRequest{Note: note})
+			outcomes[i] = embedOutcome{note, out, err}
+		}()
+	}
+	wg.Wait()
+	return outcomes
+}
+
+func TestEmbedBatcherFlushesFullBatch(t *testing.T) {
+	fake := &fakeBatchClient{respond: echo}
+	b := newEmbedBatcher(fake, 3, time.Hour)
+
+	for _, o := range embedAll(b, context.Background(), "a", "b", "c") {
+		if o.err != nil || o.out.Caption != o.note {
+			t.Errorf("%s: got %+v, %v", o.note, o.out, o.err)
+		}
+	}
+	calls := fake.sent()
+	if len(calls) != 1 || len(calls[0]) != 3 {
+		t.Errorf("got calls %v, want one of three photos", calls)
+	}
+}
+
+func TestEmbedBatcherFlushesAfterWait(t *testing.T) {
+	fake := &fakeBatchClient{respond: echo}
+	b := newEmbedBatcher(fake, 10, 20*time.Millisecond)
+
+	start := time.Now()
+	for _, o := range embedAll(b, context.Background(), "a", "b") {
+		if o.err != nil || o.out.Caption != o.note {
+			t.Errorf("%s: got %+v, %v", o.note, o.out, o.err)
+		}
+	}
+	if waited := time.Since(start); waited < 20*time.Millisecond {
+		t.Errorf("a part batch was sent after %s", waited)
+	}
+	var sent []string
+	for _, call := range fake.sent() {
+		sent = append(sent, call...)
+	}
+	slices.Sort(sent)
+	if !slices.Equal(sent, []string{"a", "b"}) {
+		t.Errorf("sent %v, want a and b", sent)
+	}
+}
+
+func TestEmbedBatcherCanceledCaller(t *testing.T) {
+	fake := &fakeBatchClient{respond: echo}
+	b := newEmbedBatcher(fake, 2, 50*time.Millisecond)
+
+	c, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		_, err := b.embed(c, EmbedRequest{Note: "gone"})
+		done <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("got %v, want context.Canceled", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("a canceled caller kept waiting for its batch")
+	}
+
+	// The canceled photo is dropped from the batch it was waiting in.
+	for _, o := range embedAll(b, context.Background(), "kept") {
+		if o.err != nil || o.out.Caption != "kept" {
+			t.Errorf("got %+v, %v", o.out, o.err)
+		}
+	}
+	for _, call := range fake.sent() {
+		if slices.Contains(call, "gone") {
+			t.Errorf("sent %v, which has the canceled photo", call)
+		}
+	}
+}
+
+func TestEmbedBatcherErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		respond func([]EmbedRequest) ([]embedBatchResult, error)
+		want    map[string]string // note to error, "" for success
+	}{
+		{"call fails", func([]EmbedRequest) ([]embedBatchResult, error) {
+			return nil, ErrEmbedUnavailable
+		}, map[string]string{"a": ErrEmbedUnavailable.Error(), "b": ErrEmbedUnavailable.Error()}},
+		{"too few results", func(items []EmbedRequest) ([]embedBatchResult, error) {
+			results, _ := echo(items)
+			return results[:1], nil
+		}, map[string]string{"a": "embed service returned 1 results for 2 photos", "b": "embed service returned 1 results for 2 photos"}},
+		{"one photo fails", func(items []EmbedRequest) ([]embedBatchResult, error) {
+			results, _ := echo(items)
+			for i, item := range items {
+				if item.Note == "b" {
+					results[i] = embedBatchResult{Error: "unreadable image"}
+				}
+			}
+			return results, nil
+		}, map[string]string{"a": "", "b": "unreadable image"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newEmbedBatcher(&fakeBatchClient{respond: tt.respond}, 2, time.Hour)
+			for _, o := range embedAll(b, context.Background(), "a", "b") {
+				var got string
+				if o.err != nil {
+					got = o.err.Error()
+				}
+				if got != tt.want[o.note] {
+					t.Errorf("%s: got error %q, want %q", o.note, got, tt.want[o.note])
+				}
+			}
+		})
+	}
+}
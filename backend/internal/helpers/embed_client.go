@@ -0,0 +1,262 @@
+package helpers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/Pranjal095/Memora/backend/config"
+)
+
+var ErrEmbedUnavailable = errors.New("embedding service is unavailable")
+
+// Circuit breaker states reported by EmbedBreakerState.
+const (
+	BreakerClosed   = "closed"
+	BreakerOpen     = "open"
+	BreakerHalfOpen = "half-open"
+)
+
+// breaker opens after a run of consecutive failures and fails calls fast
+// until the cooldown has passed. It then lets a single trial call through:
+// success closes it again, failure reopens it.
+type breaker struct {
+	mu        sync.Mutex
+	state     string
+	failures  int
+	threshold int
+	cooldown  time.Duration
+	openedAt  time.Time
+	trial     bool
+	lastError string
+	now       func() time.Time
+}
+
+func newBreaker(threshold int, cooldown time.Duration) *breaker {
+	return &breaker{state: BreakerClosed, threshold: threshold, cooldown: cooldown, now: time.Now}
+}
+
+// allow reports whether a call may go ahead.
+func (b *breaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case BreakerOpen:
+		if b.now().Sub(b.openedAt) < b.cooldown {
+			return false
+		}
+		b.state = BreakerHalfOpen
+		b.trial = true
+		return true
+	case BreakerHalfOpen:
+		if b.trial {
+			return false
+		}
+		b.trial = true
+		return true
+	}
+	return true
+}
+
+func (b *breaker) success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state != BreakerClosed {
+		fmt.Fprintln(os.Stderr, "embedding service recovered, closing breaker")
+	}
+	b.state, b.failures, b.trial, b.lastError = BreakerClosed, 0, false, ""
+}
+
+func (b *breaker) failure(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	b.trial = false
+	b.lastError = err.Error()
+	if b.state == BreakerHalfOpen || b.failures >= b.threshold {
+		if b.state != BreakerOpen {
+			fmt.Fprintf(os.Stderr, "embedding service failing (%v), opening breaker\n", err)
+		}
+		b.state, b.openedAt = BreakerOpen, b.now()
+	}
+}
+
+// trip opens the breaker straight away, when the health probe fails.
+func (b *breaker) trip(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.lastError = err.Error()
+	if b.state != BreakerOpen {
+		fmt.Fprintf(os.Stderr, "embedding service unhealthy (%v), opening breaker\n", err)
+		b.state, b.openedAt, b.trial = BreakerOpen, b.now(), false
+	}
+}
+
+// release gives back a trial call that ended without an answer either way.
+func (b *breaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.trial = false
+}
+
+type EmbedBreakerStatus struct {
+	State     string `json:"state"`
+	Failures  int    `json:"consecutive_failures"`
+	OpenedAt  string `json:"opened_at,omitempty"`
+	LastError string `json:"last_error,omitempty"`
+}
+
+func (b *breaker) status() EmbedBreakerStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	s := EmbedBreakerStatus{State: b.state, Failures: b.failures, LastError: b.lastError}
+	if b.state != BreakerClosed {
+		s.OpenedAt = b.openedAt.Format(time.RFC3339)
+	}
+	return s
+}
+
+// embedClient is the one client for the embedding service. It keeps
+// connections alive between calls and fails fast while the breaker is open,
+// which StartEmbedProbe also opens when /health fails.
+type embedClient struct {
+	baseURL string
+	client  *http.Client
+	timeout time.Duration
+	breaker *breaker
+}
+
+var embedder *embedClient
+
+// OpenEmbedService sets up the client for the embedding service, and the
+// batcher in front of it unless EMBED_BATCH_SIZE is 1. It must be called
+// before anything is embedded.
+func OpenEmbedService(cfg *config.Config) {
+	embedder = &embedClient{
+		baseURL: cfg.EmbeddingServiceURL,
+		client: &http.Client{
+			Transport: &http.Transport{
+				MaxIdleConns:        32,
+				MaxIdleConnsPerHost: 32,
+				IdleConnTimeout:     90 * time.Second,
+			},
+		},
+		timeout: cfg.EmbedTimeout,
+		breaker: newBreaker(cfg.EmbedBreakerFailures, cfg.EmbedBreakerCooldown),
+	}
+	batcher = nil
+	if size := cfg.EmbedBatchSize; size > 1 {
+		batcher = newEmbedBatcher(embedder, size, time.Duration(cfg.EmbedBatchWaitMS)*time.Millisecond)
+	}
+}
+
+func embedService() *embedClient {
+	return embedder
+}
+
+// EmbedBreakerState reports the breaker of the embedding service client.
+func EmbedBreakerState() EmbedBreakerStatus {
+	return embedService().breaker.status()
+}
+
+// StartEmbedProbe checks the health of the embedding service every 15
+// seconds until ctx is done.
+func StartEmbedProbe(ctx context.Context) {
+	go embedService().probe(ctx, 15*time.Second)
+}
+
+func (e *embedClient) probe(ctx context.Context, every time.Duration) {
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+	for {
+		e.check(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// check trips the breaker when /health fails. A healthy answer doesn't close
+// it by itself; it only lets the next call through as the trial.
+func (e *embedClient) check(ctx context.Context) {
+	c, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(c, http.MethodGet, e.baseURL+"/health", nil)
+	resp, err := e.client.Do(req)
+	if err == nil {
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			err = fmt.Errorf("health check returned %s", resp.Status)
+		}
+	}
+	if err != nil && ctx.Err() == nil {
+		e.breaker.trip(err)
+	}
+}
+
+// post sends body to path and decodes the JSON answer into out. Network
+// errors and 5xx answers count against the breaker; a canceled caller
+// doesn't.
+func (e *embedClient) post(c context.Context, path, contentType string, body io.Reader, out any) error {
+	if !e.breaker.allow() {
+		if rc, ok := body.(io.Closer); ok {
+			rc.Close()
+		}
+		return ErrEmbedUnavailable
+	}
+
+	ctx, cancel := context.WithTimeout(c, e.timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.baseURL+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		if c.Err() != nil {
+			e.breaker.release()
+			return c.Err()
+		}
+		e.breaker.failure(err)
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 500 {
+		err := fmt.Errorf("embed service returned %s", resp.Status)
+		e.breaker.failure(err)
+		return err
+	}
+	e.breaker.success()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("embed service returned %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("invalid response: %w", err)
+	}
+	return nil
+}
+
+func (e *embedClient) postJSON(c context.Context, path string, in, out any) error {
+	b, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	return e.post(c, path, "application/json", bytes.NewReader(b), out)
+}
@@ -0,0 +1,181 @@
+package helpers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestBreaker(t *testing.T) {
+	const cooldown = 30 * time.Second
+	type step struct {
+		do    string // allow, deny, success, failure, trip, release or wait
+		state string
+	}
+
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{"stays closed below the threshold", []step{
+			{"failure", BreakerClosed}, {"failure", BreakerClosed}, {"allow", BreakerClosed},
+		}},
+		{"success resets the count", []step{
+			{"failure", BreakerClosed}, {"failure", BreakerClosed}, {"success", BreakerClosed},
+			{"failure", BreakerClosed}, {"failure", BreakerClosed}, {"allow", BreakerClosed},
+		}},
+		{"opens at the threshold", []step{
+			{"failure", BreakerClosed}, {"failure", BreakerClosed}, {"failure", BreakerOpen},
+			{"deny", BreakerOpen},
+		}},
+		{"one trial after the cooldown", []step{
+			{"trip", BreakerOpen}, {"deny", BreakerOpen},
+			{"wait", BreakerOpen}, {"allow", BreakerHalfOpen}, {"deny", BreakerHalfOpen},
+		}},
+		{"a successful trial closes it", []step{
+			{"trip", BreakerOpen}, {"wait", BreakerOpen}, {"allow", BreakerHalfOpen},
+			{"success", BreakerClosed}, {"allow", BreakerClosed}, {"allow", BreakerClosed},
+		}},
+		{"a failed trial reopens it at once", []step{
+			{"trip", BreakerOpen}, {"wait", BreakerOpen}, {"allow", BreakerHalfOpen},
+			{"failure", BreakerOpen}, {"deny", BreakerOpen}, {"wait", BreakerOpen}, {"allow", BreakerHalfOpen},
+		}},
+		{"a released trial can be retried", []step{
+			{"trip", BreakerOpen}, {"wait", BreakerOpen}, {"allow", BreakerHalfOpen},
+			{"release", BreakerHalfOpen}, {"allow", BreakerHalfOpen}, {"deny", BreakerHalfOpen},
+		}},
+		{"tripping an open breaker keeps its cooldown", []step{
+			{"trip", BreakerOpen}, {"half", BreakerOpen}, {"trip", BreakerOpen}, {"half", BreakerOpen},
+			{"allow", BreakerHalfOpen},
+		}},
+		{"the probe can cut a trial short", []step{
+			{"trip", BreakerOpen}, {"wait", BreakerOpen}, {"allow", BreakerHalfOpen},
+			{"trip", BreakerOpen}, {"deny", BreakerOpen},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := time.Date(2024, time.May, 15, 10, 0, 0, 0, time.UTC)
+			b := newBreaker(3, cooldown)
+			b.now = func() time.Time { return now }
+			boom := errors.New("boom")
+
+			for i, s := range tt.steps {
+				switch s.do {
+				case "allow", "deny":
+					if got := b.allow(); got != (s.do == "allow") {
+						t.Fatalf("step %d: allow() = %v", i, got)
+					}
+				case "success":
+					b.success()
+				case "failure":
+					b.failure(boom)
+				case "trip":
+					b.trip(boom)
+				case "release":
+					b.release()
+				case "wait":
+					now = now.Add(cooldown)
+				case "half":
+					now = now.Add(cooldown / 2)
+				}
+				if got := b.status().State; got != s.state {
+					t.Fatalf("step %d (%s): state %s, want %s", i, s.do, got, s.state)
+				}
+			}
+		})
+	}
+}
+
+func TestBreakerStatus(t *testing.T) {
+	opened := time.Date(2024, time.May, 15, 10, 0, 0, 0, time.UTC)
+	b := newBreaker(2, time.Minute)
+	b.now = func() time.Time { return opened }
+
+	b.failure(errors.New("connection refused"))
+	if s := b.status(); s.State != BreakerClosed || s.Failures != 1 || s.OpenedAt != "" || s.LastError != "connection refused" {
+		t.Errorf("after one failure: %+v", s)
+	}
+	b.failure(errors.New("timeout"))
+	if s := b.status(); s.State != BreakerOpen || s.Failures != 2 || s.OpenedAt != "2024-05-15T10:00:00Z" || s.LastError != "timeout" {
+		t.Errorf("after opening: %+v", s)
+	}
+	b.success()
+	if s := b.status(); s != (EmbedBreakerStatus{State: BreakerClosed}) {
+		t.Errorf("after recovering: %+v", s)
+	}
+}
+
+func TestEmbedClientCountsServerErrors(t *testing.T) {
+	status := http.StatusServiceUnavailable
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if status != http.StatusOK {
+			http.Error(w, "no", status)
+			return
+		}
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer srv.Close()
+
+	e := &embedClient{baseURL: srv.URL, client: srv.Client(), timeout: time.Second, breaker: newBreaker(2, time.Hour)}
+	var out struct{ OK bool }
+	c := context.Background()
+
+	// A client error is the caller's fault and doesn't count.
+	status = http.StatusBadRequest
+	if err := e.postJSON(c, "/embed", nil, &out); err == nil {
+		t.Fatal("a 400 answer succeeded")
+	}
+	status = http.StatusServiceUnavailable
+	for i := 0; i < 2; i++ {
+		if err := e.postJSON(c, "/embed", nil, &out); err == nil || errors.Is(err, ErrEmbedUnavailable) {
+			t.Fatalf("call %d: %v", i, err)
+		}
+	}
+	status = http.StatusOK
+	if err := e.postJSON(c, "/embed", nil, &out); !errors.Is(err, ErrEmbedUnavailable) {
+		t.Fatalf("open breaker let a call through: %v", err)
+	}
+
+	canceled, cancel := context.WithCancel(c)
+	cancel()
+	e.breaker = newBreaker(1, time.Hour)
+	if err := e.postJSON(canceled, "/embed", nil, &out); !errors.Is(err, context.Canceled) {
+		t.Fatalf("canceled call: %v", err)
+	}
+	if err := e.postJSON(c, "/embed", nil, &out); err != nil || !out.OK {
+		t.Fatalf("a canceled call counted against the breaker: %v", err)
+	}
+}
+
+func TestEmbedClientProbe(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+	e := &embedClient{baseURL: srv.URL, client: srv.Client(), timeout: time.Second, breaker: newBreaker(3, time.Hour)}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		e.probe(ctx, time.Hour)
+		close(done)
+	}()
+	deadline := time.Now().Add(5 * time.Second)
+	for e.breaker.status().State != BreakerOpen {
+		if time.Now().After(deadline) {
+			t.Fatal("a failed health check didn't open the breaker")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the probe kept running after its context was canceled")
+	}
+}
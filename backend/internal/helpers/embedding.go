@@ -1,37 +1,137 @@
 package helpers
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
 	"fmt"
-	"net/http"
-	"time"
+	"io"
+	"mime/multipart"
+	"os"
+	"path/filepath"
+
+	"github.com/Pranjal095/Memora/backend/internal/repository"
+	"github.com/Pranjal095/Memora/backend/internal/vectorindex"
 )
 
-type embedRequest struct {
-	ImagePath string `json:"image_path"`
-	Note      string `json:"note"`
-	City      string `json:"city"`
-	ID        int64  `json:"id"`
+// EmbedRequest describes one photo to embed. For videos ImagePaths holds the
+// keyframes, whose image embeddings are averaged, and ImagePath the poster
+// used for the caption. A Caption set by the user is embedded instead of a
+// generated one. The fields not sent to the embed service go into the
+// vector payload so searches can filter on them.
+type EmbedRequest struct {
+	ImagePath  string   `json:"image_path"`
+	ImagePaths []string `json:"image_paths,omitempty"`
+	Note       string   `json:"note"`
+	City       string   `json:"city"`
+	Caption    string   `json:"caption,omitempty"`
+	Country    string   `json:"-"`
+	MediaType  string   `json:"-"`
+	UserID     string   `json:"-"`
+	CreatedAt  int64    `json:"-"`
+	ID         int64    `json:"-"`
+}
+
+type embedResponse struct {
+	Vector  []float32 `json:"vector"`
+	Caption string    `json:"caption"`
+	Dims    int       `json:"dims"`
+}
+
+// EmbedResult is what the embed service made of a photo.
+type EmbedResult struct {
+	Caption string `json:"caption"`
+	Dims    int    `json:"dims"`
+}
+
+// SendToEmbedService has the photo embedded, writes the vector to the index
+// and stores the caption on the photo row.
+func SendToEmbedService(c context.Context, photos repository.PhotoRepository, req EmbedRequest) (EmbedResult, error) {
+	point, err := EmbedPhoto(c, req)
+	if err != nil {
+		return EmbedResult{}, err
+	}
+	if err := Vectors().Upsert(c, point); err != nil {
+		return EmbedResult{}, err
+	}
+	if err := StorePhotoCaptions(c, photos, point); err != nil {
+		return EmbedResult{}, err
+	}
+	return EmbedResult{Caption: point.Payload.Caption, Dims: len(point.Vector)}, nil
+}
+
+// EmbedPhoto has the photo embedded and returns it as a point for the index,
+// without writing it. Concurrent calls are batched unless EMBED_BATCH_SIZE
+// is 1.
+func EmbedPhoto(c context.Context, req EmbedRequest) (vectorindex.Point, error) {
+	var (
+		out embedResponse
+		err error
+	)
+	if b := embedBatches(); b != nil {
+		out, err = b.embed(c, req)
+	} else {
+		err = embedService().postJSON(c, "/embed", req, &out)
+	}
+	if err != nil {
+		return vectorindex.Point{}, fmt.Errorf("embed photo: %w", err)
+	}
+	if out.Dims != len(out.Vector) {
+		return vectorindex.Point{}, fmt.Errorf("embed photo: got %d values for %d dimensions", len(out.Vector), out.Dims)
+	}
+
+	userID, err := parseUserID(req.UserID)
+	if err != nil {
+		return vectorindex.Point{}, fmt.Errorf("embed photo %d: %w", req.ID, err)
+	}
+	return vectorindex.Point{
+		ID:     req.ID,
+		Vector: out.Vector,
+		Payload: vectorindex.Payload{
+			UserID:    userID,
+			CreatedAt: req.CreatedAt,
+			Note:      req.Note,
+			Caption:   out.Caption,
+			City:      req.City,
+			Country:   req.Country,
+			MediaType: req.MediaType,
+		},
+	}, nil
 }
 
-func SendToEmbedService(c context.Context, imgPath, note, city string, id int64) error {
-	req := embedRequest{
-		ImagePath: imgPath,
-		Note:      note,
-		City:      city,
-		ID:        id,
+// EmbedText embeds a search query with the CLIP text encoder.
+func EmbedText(c context.Context, text string) ([]float32, error) {
+	var out embedResponse
+	if err := embedService().postJSON(c, "/embed_text", map[string]string{"text": text}, &out); err != nil {
+		return nil, fmt.Errorf("embed text: %w", err)
 	}
-	b, _ := json.Marshal(req)
-	client := &http.Client{Timeout: 2 * time.Minute}
-	resp, err := client.Post("http://localhost:5000/embed", "application/json", bytes.NewReader(b))
+	return out.Vector, nil
+}
+
+// EmbedImageFile embeds the image at path on its own, without a caption.
+func EmbedImageFile(c context.Context, path string) ([]float32, error) {
+	f, err := os.Open(path)
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("embed image: %w", err)
 	}
-	defer resp.Body.Close()
-	if resp.StatusCode != 200 {
-		return fmt.Errorf("embed service error: %s", resp.Status)
+	defer f.Close()
+
+	// The image is streamed into the multipart body rather than buffered.
+	pr, pw := io.Pipe()
+	mw := multipart.NewWriter(pw)
+	go func() {
+		part, err := mw.CreateFormFile("image", filepath.Base(path))
+		if err == nil {
+			_, err = io.Copy(part, f)
+		}
+		if err == nil {
+			err = mw.Close()
+		}
+		pw.CloseWithError(err)
+	}()
+	defer pr.Close()
+
+	var out embedResponse
+	if err := embedService().post(c, "/embed_image", mw.FormDataContentType(), pr, &out); err != nil {
+		return nil, fmt.Errorf("embed image: %w", err)
 	}
-	return nil
+	return out.Vector, nil
 }
@@ -0,0 +1,205 @@
+package helpers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"os"
+	"strings"
+	"syscall"
+	"time"
+)
+
+var (
+	ErrForbiddenDestination = errors.New("destination address is not allowed")
+	ErrFetchTooLarge        = errors.New("remote file exceeds maximum size")
+	ErrFetchContentType     = errors.New("remote file has an unsupported content type")
+)
+
+// blockedPrefixes covers special-purpose ranges that netip's predicates
+// don't: shared address space, benchmarking, reserved and NAT64.
+var blockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+	netip.MustParsePrefix("64:ff9b:1::/48"),
+}
+
+var fetchContentTypes = []string{"audio/", "video/", "application/ogg", "application/octet-stream"}
+
+func IsPublicAddr(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	if !ip.IsValid() || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() ||
+		ip.IsMulticast() {
+		return false
+	}
+	for _, p := range blockedPrefixes {
+		if p.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// dialControl runs after DNS resolution for every connection the client
+// makes, redirects included, so it sees the address actually dialled.
+func dialControl(network, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrForbiddenDestination, address)
+	}
+	if !IsPublicAddr(addrPort.Addr()) {
+		return fmt.Errorf("%w: %s", ErrForbiddenDestination, addrPort.Addr())
+	}
+	return nil
+}
+
+// FetchLimits bound a SafeFetch: the size of the body, how long connecting
+// may take and how long the server may stay silent.
+type FetchLimits struct {
+	MaxBytes       int64
+	ConnectTimeout time.Duration
+	ReadTimeout    time.Duration
+}
+
+func newSafeClient(l FetchLimits) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: l.ConnectTimeout,
+		Control: dialControl,
+	}
+	transport := &http.Transport{
+		Proxy:                 nil,
+		DialContext:           dialer.DialContext,
+		TLSHandshakeTimeout:   l.ConnectTimeout,
+		ResponseHeaderTimeout: l.ReadTimeout,
+		MaxIdleConns:          10,
+		IdleConnTimeout:       90 * time.Second,
+	}
+	return &http.Client{
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 5 {
+				return errors.New("too many redirects")
+			}
+			if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+				return fmt.Errorf("%w: redirect to %s", ErrForbiddenDestination, req.URL.Scheme)
+			}
+			return nil
+		},
+	}
+}
+
+// CheckPublicHost resolves the host of rawURL and fails unless every address
+// it points at is public. It is a pre-flight check for tools such as yt-dlp
+// that make their own connections.
+func CheckPublicHost(c context.Context, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("invalid URL: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("%w: scheme %q", ErrForbiddenDestination, u.Scheme)
+	}
+
+	addrs, err := net.DefaultResolver.LookupNetIP(c, "ip", u.Hostname())
+	if err != nil {
+		return fmt.Errorf("resolve host: %w", err)
+	}
+	for _, addr := range addrs {
+		if !IsPublicAddr(addr) {
+			return fmt.Errorf("%w: %s", ErrForbiddenDestination, addr)
+		}
+	}
+	return nil
+}
+
+type idleReader struct {
+	r     io.Reader
+	timer *time.Timer
+	d     time.Duration
+}
+
+func (r *idleReader) Read(p []byte) (int, error) {
+	r.timer.Reset(r.d)
+	return r.r.Read(p)
+}
+
+// SafeFetch downloads rawURL into dst, refusing non-public destinations,
+// unexpected content types and bodies over l.MaxBytes. A read that stalls
+// for longer than l.ReadTimeout aborts the transfer.
+func SafeFetch(c context.Context, rawURL, dst string, l FetchLimits) error {
+	if err := CheckPublicHost(c, rawURL); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(c)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return fmt.Errorf("invalid URL: %w", err)
+	}
+	client := newSafeClient(l)
+	defer client.CloseIdleConnections()
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("fetch URL: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("fetch URL: %s", resp.Status)
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	allowed := false
+	for _, prefix := range fetchContentTypes {
+		if strings.HasPrefix(mediaType, prefix) {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		return fmt.Errorf("%w: %q", ErrFetchContentType, mediaType)
+	}
+
+	max := l.MaxBytes
+	if resp.ContentLength > max {
+		return ErrFetchTooLarge
+	}
+
+	f, err := os.Create(dst)
+	if err != nil {
+		return fmt.Errorf("create file: %w", err)
+	}
+
+	timer := time.AfterFunc(l.ReadTimeout, cancel)
+	defer timer.Stop()
+	body := &idleReader{r: resp.Body, timer: timer, d: l.ReadTimeout}
+
+	n, err := io.Copy(f, io.LimitReader(body, max+1))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil && n > max {
+		err = ErrFetchTooLarge
+	}
+	if err != nil {
+		os.Remove(dst)
+		if ctx.Err() != nil && c.Err() == nil {
+			return fmt.Errorf("fetch URL: read timed out after %s", l.ReadTimeout)
+		}
+		return fmt.Errorf("download: %w", err)
+	}
+	return nil
+}
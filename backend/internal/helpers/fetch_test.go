@@ -0,0 +1,91 @@
+package helpers
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+)
+
+func TestIsPublicAddr(t *testing.T) {
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{"93.184.216.34", true},
+		{"8.8.8.8", true},
+		{"2606:4700:4700::1111", true},
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false},
+		{"0.0.0.0", false},
+		{"::", false},
+		{"0.1.2.3", false},
+		{"100.64.0.1", false},
+		{"192.0.0.8", false},
+		{"198.18.0.1", false},
+		{"240.0.0.1", false},
+		{"255.255.255.255", false},
+		{"224.0.0.1", false},
+		{"fe80::1", false},
+		{"fc00::1", false},
+		{"ff02::1", false},
+		{"64:ff9b::7f00:1", false},
+		{"64:ff9b:1::1", false},
+		// IPv4-mapped addresses are judged by the IPv4 address inside.
+		{"::ffff:127.0.0.1", false},
+		{"::ffff:10.0.0.1", false},
+		{"::ffff:8.8.8.8", true},
+	}
+	for _, tt := range tests {
+		if got := IsPublicAddr(netip.MustParseAddr(tt.addr)); got != tt.want {
+			t.Errorf("IsPublicAddr(%s) = %v, want %v", tt.addr, got, tt.want)
+		}
+	}
+	if IsPublicAddr(netip.Addr{}) {
+		t.Error("the zero Addr counts as public")
+	}
+}
+
+func TestDialControl(t *testing.T) {
+	tests := []struct {
+		address string
+		allowed bool
+	}{
+		{"93.184.216.34:443", true},
+		{"[2606:4700:4700::1111]:80", true},
+		{"127.0.0.1:8080", false},
+		{"[::1]:80", false},
+		{"[::ffff:169.254.169.254]:80", false},
+		{"10.0.0.1:22", false},
+		// Only resolved addresses should get here; anything else is refused.
+		{"example.com:80", false},
+		{"93.184.216.34", false},
+	}
+	for _, tt := range tests {
+		err := dialControl("tcp", tt.address, nil)
+		if tt.allowed && err != nil {
+			t.Errorf("dialControl(%s): %v", tt.address, err)
+		}
+		if !tt.allowed && !errors.Is(err, ErrForbiddenDestination) {
+			t.Errorf("dialControl(%s): got %v, want ErrForbiddenDestination", tt.address, err)
+		}
+	}
+}
+
+func TestSafeClientRefusesLoopback(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("the request reached a loopback server")
+	}))
+	defer srv.Close()
+
+	_, err := newSafeClient(FetchLimits{ConnectTimeout: time.Second, ReadTimeout: time.Second}).Get(srv.URL)
+	if !errors.Is(err, ErrForbiddenDestination) {
+		t.Errorf("got %v, want ErrForbiddenDestination", err)
+	}
+}
@@ -0,0 +1,339 @@
+package helpers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/Pranjal095/Memora/backend/config"
+	"github.com/Pranjal095/Memora/backend/internal/repository"
+	"github.com/Pranjal095/Memora/backend/internal/schema"
+	"github.com/Pranjal095/Memora/backend/internal/vectorindex"
+)
+
+// Repairs CheckConsistency can apply. Delete and quarantine both deal with
+// orphan files, so only one of them can be chosen.
+const (
+	FsckReembed    = "reembed"
+	FsckDelete     = "delete"
+	FsckQuarantine = "quarantine"
+)
+
+var (
+	ErrFsckRepair   = errors.New("repair must be reembed, delete or quarantine, and not both delete and quarantine")
+	ErrFsckRunning  = errors.New("a consistency check is already running")
+	ErrFsckNotFound = errors.New("consistency check not found")
+)
+
+var fsckActive atomic.Bool
+
+func ValidateFsckRepair(repair []string) error {
+	for _, mode := range repair {
+		if mode != FsckReembed && mode != FsckDelete && mode != FsckQuarantine {
+			return ErrFsckRepair
+		}
+	}
+	if slices.Contains(repair, FsckDelete) && slices.Contains(repair, FsckQuarantine) {
+		return ErrFsckRepair
+	}
+	return nil
+}
+
+// CheckConsistency compares the photo rows with the files under uploads/ and
+// the vector index, then applies the requested repairs. Vectors are listed
+// before rows and rows before files: each is written after the one before it
+// on upload, so a concurrent upload can't be mistaken for an orphan.
+func CheckConsistency(c context.Context, cfg *config.Config, photos repository.PhotoRepository, repair []string) (*schema.FsckReport, error) {
+	if err := ValidateFsckRepair(repair); err != nil {
+		return nil, err
+	}
+	report := &schema.FsckReport{
+		MissingFiles:   []schema.FsckMissingFile{},
+		OrphanFiles:    []string{},
+		MissingVectors: []int64{},
+		OrphanVectors:  []int64{},
+	}
+
+	vectorIDs := make(map[int64]bool)
+	for next := int64(0); ; {
+		points, after, err := Vectors().Scroll(c, vectorindex.Filter{}, next, 256)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list vectors: %w", err)
+		}
+		for _, p := range points {
+			vectorIDs[p.ID] = true
+		}
+		if after == 0 {
+			break
+		}
+		next = after
+	}
+	report.Vectors = int64(len(vectorIDs))
+
+	photoIDs, referenced, unreadable, err := checkPhotoFiles(c, photos, report)
+	if err != nil {
+		return nil, err
+	}
+
+	err = filepath.WalkDir("uploads", func(path string, d fs.DirEntry, err error) error {
+		if errors.Is(err, fs.ErrNotExist) && path == "uploads" {
+			return fs.SkipAll
+		}
+		if err != nil || d.IsDir() {
+			return err
+		}
+		report.Files++
+		if referenced[path] {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if time.Since(info.ModTime()) >= cfg.FsckGrace {
+			report.OrphanFiles = append(report.OrphanFiles, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list uploads: %w", err)
+	}
+
+	for id := range vectorIDs {
+		if !photoIDs[id] {
+			report.OrphanVectors = append(report.OrphanVectors, id)
+		}
+	}
+	for id := range photoIDs {
+		if !vectorIDs[id] {
+			report.MissingVectors = append(report.MissingVectors, id)
+		}
+	}
+	slices.Sort(report.OrphanVectors)
+	slices.Sort(report.MissingVectors)
+
+	if slices.Contains(repair, FsckReembed) {
+		var ids []int64
+		for _, id := range report.MissingVectors {
+			if !unreadable[id] {
+				ids = append(ids, id)
+			}
+		}
+		if err := reembedPhotos(c, cfg, photos, ids, report); err != nil {
+			return report, err
+		}
+	}
+	if slices.Contains(repair, FsckDelete) {
+		if err := deleteOrphans(c, report); err != nil {
+			return report, err
+		}
+	}
+	if slices.Contains(repair, FsckQuarantine) {
+		quarantineOrphans(report, cfg.FsckQuarantineDir)
+	}
+	return report, nil
+}
+
+// checkPhotoFiles stats every file the photo rows point at. It returns the
+// photo ids, the local files referenced and the photos missing one.
+func checkPhotoFiles(c context.Context, photos repository.PhotoRepository, report *schema.FsckReport) (map[int64]bool, map[string]bool, map[int64]bool, error) {
+	refs, err := photos.FileRefs(c)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	var (
+		photoIDs   = make(map[int64]bool)
+		referenced = make(map[string]bool)
+		unreadable = make(map[int64]bool)
+	)
+	for _, ref := range refs {
+		photoIDs[ref.ID] = true
+
+		paths := append([]string{ref.URL}, ref.Keyframes...)
+		if ref.PosterURL != "" {
+			paths = append(paths, ref.PosterURL)
+		}
+		for _, path := range paths {
+			if strings.HasPrefix(path, "http") {
+				continue
+			}
+			path = filepath.Clean(path)
+			referenced[path] = true
+			if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+				report.MissingFiles = append(report.MissingFiles, schema.FsckMissingFile{PhotoID: ref.ID, UserID: ref.UserID, Path: path})
+				unreadable[ref.ID] = true
+			}
+		}
+	}
+	report.Photos = int64(len(photoIDs))
+	return photoIDs, referenced, unreadable, nil
+}
+
+func reembedPhotos(c context.Context, cfg *config.Config, photos repository.PhotoRepository, ids []int64, report *schema.FsckReport) error {
+	for len(ids) > 0 {
+		n := min(len(ids), reindexPageSize)
+		sources, err := photos.EmbedSourcesByIDs(c, ids[:n])
+		if err != nil {
+			return err
+		}
+		ids = ids[n:]
+
+		points, failed := embedPhotos(c, "fsck", embedRequests(sources, cfg.PublicURL), cfg.ReindexConcurrency)
+		if c.Err() != nil {
+			return c.Err()
+		}
+		if len(points) > 0 {
+			if err := Vectors().Upsert(c, points...); err != nil {
+				return err
+			}
+			if err := StorePhotoCaptions(c, photos, points...); err != nil {
+				return err
+			}
+		}
+		report.Reembedded += len(points)
+		report.ReembedFailed += len(failed)
+	}
+	return nil
+}
+
+func deleteOrphans(c context.Context, report *schema.FsckReport) error {
+	for _, path := range report.OrphanFiles {
+		if err := os.Remove(path); err != nil {
+			fmt.Fprintf(os.Stderr, "fsck: %v\n", err)
+			continue
+		}
+		report.DeletedFiles++
+	}
+
+	for ids := report.OrphanVectors; len(ids) > 0; {
+		n := min(len(ids), 256)
+		if err := Vectors().Delete(c, ids[:n]...); err != nil {
+			return err
+		}
+		report.DeletedVectors += n
+		ids = ids[n:]
+	}
+	return nil
+}
+
+// quarantineOrphans moves orphan files under dir, keeping their path so
+// they can be put back.
+func quarantineOrphans(report *schema.FsckReport, dir string) {
+	for _, path := range report.OrphanFiles {
+		dst := filepath.Join(dir, path)
+		err := os.MkdirAll(filepath.Dir(dst), 0755)
+		if err == nil {
+			err = os.Rename(path, dst)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "fsck: quarantine %s: %v\n", path, err)
+			continue
+		}
+		report.Quarantined++
+	}
+}
+
+// StartFsck records a check and runs it in the background. Only one runs at
+// a time; checks left running by a previous process are marked failed.
+func StartFsck(c context.Context, cfg *config.Config, photos repository.PhotoRepository, repair []string) (*schema.FsckRun, error) {
+	if err := ValidateFsckRepair(repair); err != nil {
+		return nil, err
+	}
+	if !fsckActive.CompareAndSwap(false, true) {
+		return nil, ErrFsckRunning
+	}
+
+	_, err := config.DB.Exec(c,
+		`UPDATE fsck_runs SET status=$1, error='interrupted by server restart', finished_at=NOW() WHERE status=$2`,
+		JobFailed, JobRunning)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to reset consistency checks: %v\n", err)
+	}
+
+	if repair == nil {
+		repair = []string{}
+	}
+	var id int64
+	err = config.DB.QueryRow(c,
+		`INSERT INTO fsck_runs(status,repair) VALUES($1,$2) RETURNING id`, JobRunning, repair).Scan(&id)
+	if err != nil {
+		fsckActive.Store(false)
+		return nil, fmt.Errorf("failed to create consistency check: %w", err)
+	}
+
+	go func() {
+		defer fsckActive.Store(false)
+
+		report, err := CheckConsistency(context.Background(), cfg, photos, repair)
+		status, errMsg := JobSucceeded, ""
+		if err != nil {
+			status, errMsg = JobFailed, err.Error()
+		}
+		_, err = config.DB.Exec(context.Background(),
+			`UPDATE fsck_runs SET status=$2, report=$3, error=NULLIF($4,''), finished_at=NOW() WHERE id=$1`,
+			id, status, report, errMsg)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to record consistency check %d: %v\n", id, err)
+		}
+	}()
+
+	return GetFsckRun(c, id)
+}
+
+const fsckRunColumns = `id,status,repair,report,error,created_at,finished_at`
+
+func scanFsckRun(row pgx.Row) (*schema.FsckRun, error) {
+	var (
+		r          schema.FsckRun
+		createdAt  time.Time
+		finishedAt *time.Time
+	)
+	if err := row.Scan(&r.ID, &r.Status, &r.Repair, &r.Report, &r.Error, &createdAt, &finishedAt); err != nil {
+		return nil, err
+	}
+	r.CreatedAt = createdAt.Format(time.RFC3339)
+	r.FinishedAt = formatTime(finishedAt)
+	return &r, nil
+}
+
+func GetFsckRun(c context.Context, id int64) (*schema.FsckRun, error) {
+	row := config.DB.QueryRow(c, `SELECT `+fsckRunColumns+` FROM fsck_runs WHERE id=$1`, id)
+	r, err := scanFsckRun(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrFsckNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get consistency check: %w", err)
+	}
+	return r, nil
+}
+
+// ListFsckRuns lists recent checks without their reports, which can be long.
+func ListFsckRuns(c context.Context) ([]schema.FsckRun, error) {
+	rows, err := config.DB.Query(c,
+		`SELECT id,status,repair,NULL::jsonb,error,created_at,finished_at FROM fsck_runs ORDER BY id DESC LIMIT 50`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list consistency checks: %w", err)
+	}
+	defer rows.Close()
+
+	runs := []schema.FsckRun{}
+	for rows.Next() {
+		r, err := scanFsckRun(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error reading consistency checks: %w", err)
+		}
+		runs = append(runs, *r)
+	}
+	return runs, rows.Err()
+}
@@ -0,0 +1,209 @@
+package helpers
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+var errNoHEIFSize = errors.New("heif: primary image has no size")
+
+// heifBox is an ISO BMFF box with its payload read into memory. Only the
+// boxes on the path to the image properties are read, which are small.
+type heifBox struct {
+	kind string
+	data []byte
+}
+
+// heifBoxes splits b into the boxes it contains.
+func heifBoxes(b []byte) ([]heifBox, error) {
+	var boxes []heifBox
+	for len(b) > 0 {
+		if len(b) < 8 {
+			return nil, errors.New("heif: truncated box header")
+		}
+		size, header := uint64(binary.BigEndian.Uint32(b)), uint64(8)
+		kind := string(b[4:8])
+		switch size {
+		case 0:
+			size = uint64(len(b))
+		case 1:
+			if len(b) < 16 {
+				return nil, errors.New("heif: truncated box header")
+			}
+			size, header = binary.BigEndian.Uint64(b[8:]), 16
+		}
+		if size < header || size > uint64(len(b)) {
+			return nil, fmt.Errorf("heif: %s box overruns its parent", kind)
+		}
+		boxes = append(boxes, heifBox{kind: kind, data: b[header:size]})
+		b = b[size:]
+	}
+	return boxes, nil
+}
+
+func findHEIFBox(boxes []heifBox, kind string) (heifBox, bool) {
+	for _, b := range boxes {
+		if b.kind == kind {
+			return b, true
+		}
+	}
+	return heifBox{}, false
+}
+
+// heifSize reads the displayed size of the primary image of a HEIF or HEIC
+// file from its ispe property, turned by its irot property if it has one.
+// The image itself isn't decoded.
+func heifSize(r io.Reader) (int, int, error) {
+	// The meta box comes right after ftyp and holds no pixel data, so the
+	// top-level walk stops as soon as it is found.
+	var meta []byte
+	for meta == nil {
+		var header [8]byte
+		if _, err := io.ReadFull(r, header[:]); err != nil {
+			return 0, 0, fmt.Errorf("heif: no meta box: %w", err)
+		}
+		size := binary.BigEndian.Uint32(header[:])
+		if size < 8 || size > 1<<20 {
+			return 0, 0, fmt.Errorf("heif: unexpected %q box of %d bytes", header[4:], size)
+		}
+		body := make([]byte, size-8)
+		if _, err := io.ReadFull(r, body); err != nil {
+			return 0, 0, fmt.Errorf("heif: truncated %q box: %w", header[4:], err)
+		}
+		if string(header[4:]) == "meta" {
+			meta = body
+		}
+	}
+
+	// meta is a full box: its children follow the version and flags.
+	if len(meta) < 4 {
+		return 0, 0, errors.New("heif: truncated meta box")
+	}
+	children, err := heifBoxes(meta[4:])
+	if err != nil {
+		return 0, 0, err
+	}
+
+	primary, err := heifPrimaryItem(children)
+	if err != nil {
+		return 0, 0, err
+	}
+	iprp, ok := findHEIFBox(children, "iprp")
+	if !ok {
+		return 0, 0, errNoHEIFSize
+	}
+	props, err := heifBoxes(iprp.data)
+	if err != nil {
+		return 0, 0, err
+	}
+	ipco, ok := findHEIFBox(props, "ipco")
+	if !ok {
+		return 0, 0, errNoHEIFSize
+	}
+	properties, err := heifBoxes(ipco.data)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var width, height, turns int
+	found := false
+	for _, ipma := range props {
+		if ipma.kind != "ipma" {
+			continue
+		}
+		indexes, err := heifAssociations(ipma.data, primary)
+		if err != nil {
+			return 0, 0, err
+		}
+		for _, i := range indexes {
+			// Property indexes count from 1; 0 means none.
+			if i == 0 || i > len(properties) {
+				continue
+			}
+			p := properties[i-1]
+			switch {
+			case p.kind == "ispe" && len(p.data) >= 12:
+				width = int(binary.BigEndian.Uint32(p.data[4:]))
+				height = int(binary.BigEndian.Uint32(p.data[8:]))
+				found = true
+			case p.kind == "irot" && len(p.data) >= 1:
+				turns = int(p.data[0] & 3)
+			}
+		}
+	}
+	if !found {
+		return 0, 0, errNoHEIFSize
+	}
+	if turns%2 == 1 {
+		width, height = height, width
+	}
+	return width, height, nil
+}
+
+// heifPrimaryItem reads the item id in the pitm box.
+func heifPrimaryItem(meta []heifBox) (uint32, error) {
+	pitm, ok := findHEIFBox(meta, "pitm")
+	if !ok || len(pitm.data) < 6 {
+		return 0, errors.New("heif: no primary item")
+	}
+	if pitm.data[0] == 0 {
+		return uint32(binary.BigEndian.Uint16(pitm.data[4:])), nil
+	}
+	if len(pitm.data) < 8 {
+		return 0, errors.New("heif: truncated pitm box")
+	}
+	return binary.BigEndian.Uint32(pitm.data[4:]), nil
+}
+
+// heifAssociations returns the property indexes an ipma box associates
+// with item.
+func heifAssociations(b []byte, item uint32) ([]int, error) {
+	truncated := errors.New("heif: truncated ipma box")
+	if len(b) < 8 {
+		return nil, truncated
+	}
+	version, flags := b[0], b[3]
+	entries := binary.BigEndian.Uint32(b[4:])
+	b = b[8:]
+
+	for ; entries > 0; entries-- {
+		var id uint32
+		if version < 1 {
+			if len(b) < 3 {
+				return nil, truncated
+			}
+			id, b = uint32(binary.BigEndian.Uint16(b)), b[2:]
+		} else {
+			if len(b) < 5 {
+				return nil, truncated
+			}
+			id, b = binary.BigEndian.Uint32(b), b[4:]
+		}
+		count := int(b[0])
+		b = b[1:]
+
+		// Each association is a 7 or 15 bit index after an essential flag.
+		width := 1
+		if flags&1 != 0 {
+			width = 2
+		}
+		if len(b) < count*width {
+			return nil, truncated
+		}
+		if id == item {
+			indexes := make([]int, count)
+			for i := range indexes {
+				if width == 1 {
+					indexes[i] = int(b[i] & 0x7f)
+				} else {
+					indexes[i] = int(binary.BigEndian.Uint16(b[2*i:]) & 0x7fff)
+				}
+			}
+			return indexes, nil
+		}
+		b = b[count*width:]
+	}
+	return nil, nil
+}
@@ -0,0 +1,172 @@
+package helpers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/Pranjal095/Memora/backend/config"
+)
+
+const IdempotencyTTL = 24 * time.Hour
+
+var (
+	ErrIdempotencyInProgress = errors.New("a request with this Idempotency-Key is still in progress")
+	ErrIdempotencyKeyReused  = errors.New("Idempotency-Key was already used with a different request")
+)
+
+type IdempotentResponse struct {
+	Status      int
+	ContentType string
+	// Header holds the other response headers, without Content-Length and
+	// Date.
+	Header http.Header
+	Body   []byte
+}
+
+// HashRequest fingerprints a request so a retried key can be matched against
+// the original payload. Multipart bodies are hashed part by part because
+// clients pick a fresh boundary on every attempt.
+func HashRequest(method, uri, contentType string, body io.Reader) (string, error) {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s %s\n", method, uri)
+
+	mediaType, params, _ := mime.ParseMediaType(contentType)
+	if !strings.HasPrefix(mediaType, "multipart/") || params["boundary"] == "" {
+		if _, err := io.Copy(h, body); err != nil {
+			return "", fmt.Errorf("hash request body: %w", err)
+		}
+		return hex.EncodeToString(h.Sum(nil)), nil
+	}
+
+	reader := multipart.NewReader(body, params["boundary"])
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("hash request body: %w", err)
+		}
+		fmt.Fprintf(h, "%q %q\n", part.FormName(), part.FileName())
+		_, err = io.Copy(h, part)
+		part.Close()
+		if err != nil {
+			return "", fmt.Errorf("hash request body: %w", err)
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// BeginIdempotentRequest claims key for the user. It returns nil when the
+// caller should go ahead and process the request, or the recorded response
+// of an earlier request with the same key and payload.
+func BeginIdempotentRequest(c context.Context, userID, key, requestHash string) (*IdempotentResponse, error) {
+	_, err := config.DB.Exec(c,
+		`DELETE FROM idempotency_keys WHERE user_id=$1 AND key=$2 AND created_at < NOW() - make_interval(secs => $3)`,
+		userID, key, IdempotencyTTL.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("expire idempotency key: %w", err)
+	}
+
+	tag, err := config.DB.Exec(c,
+		`INSERT INTO idempotency_keys(user_id,key,request_hash) VALUES($1,$2,$3) ON CONFLICT DO NOTHING`,
+		userID, key, requestHash)
+	if err != nil {
+		return nil, fmt.Errorf("claim idempotency key: %w", err)
+	}
+	if tag.RowsAffected() == 1 {
+		return nil, nil
+	}
+
+	var (
+		storedHash  string
+		status      *int
+		contentType *string
+		header      http.Header
+		body        []byte
+	)
+	err = config.DB.QueryRow(c,
+		`SELECT request_hash,status,content_type,headers,body FROM idempotency_keys WHERE user_id=$1 AND key=$2`,
+		userID, key).Scan(&storedHash, &status, &contentType, &header, &body)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrIdempotencyInProgress
+	}
+	if err != nil {
+		return nil, fmt.Errorf("lookup idempotency key: %w", err)
+	}
+	if storedHash != requestHash {
+		return nil, ErrIdempotencyKeyReused
+	}
+	if status == nil {
+		return nil, ErrIdempotencyInProgress
+	}
+
+	resp := &IdempotentResponse{Status: *status, Header: header, Body: body}
+	if contentType != nil {
+		resp.ContentType = *contentType
+	}
+	return resp, nil
+}
+
+func CompleteIdempotentRequest(c context.Context, userID, key string, resp IdempotentResponse) error {
+	_, err := config.DB.Exec(c,
+		`UPDATE idempotency_keys SET status=$3, content_type=$4, headers=$5, body=$6 WHERE user_id=$1 AND key=$2`,
+		userID, key, resp.Status, resp.ContentType, resp.Header, resp.Body)
+	if err != nil {
+		return fmt.Errorf("record idempotent response: %w", err)
+	}
+	return nil
+}
+
+// ReleaseIdempotencyKey forgets a claimed key so that a failed request can be
+// retried with it.
+func ReleaseIdempotencyKey(c context.Context, userID, key string) error {
+	_, err := config.DB.Exec(c,
+		`DELETE FROM idempotency_keys WHERE user_id=$1 AND key=$2 AND status IS NULL`,
+		userID, key)
+	if err != nil {
+		return fmt.Errorf("release idempotency key: %w", err)
+	}
+	return nil
+}
+
+// StartIdempotencyPurge deletes expired idempotency keys every hour until c
+// is done.
+func StartIdempotencyPurge(c context.Context) {
+	go func() {
+		ticker := time.NewTicker(time.Hour)
+		defer ticker.Stop()
+		for {
+			if err := PurgeExpiredIdempotencyKeys(c); err != nil {
+				fmt.Fprintf(os.Stderr, "idempotency: %v\n", err)
+			}
+			select {
+			case <-ticker.C:
+			case <-c.Done():
+				return
+			}
+		}
+	}()
+}
+
+func PurgeExpiredIdempotencyKeys(c context.Context) error {
+	_, err := config.DB.Exec(c,
+		`DELETE FROM idempotency_keys WHERE created_at < NOW() - make_interval(secs => $1)`,
+		IdempotencyTTL.Seconds())
+	if err != nil {
+		return fmt.Errorf("purge idempotency keys: %w", err)
+	}
+	return nil
+}
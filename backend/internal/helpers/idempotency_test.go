@@ -0,0 +1,86 @@
+package helpers
+
+import (
+	"bytes"
+	"io"
+	"mime/multipart"
+	"strings"
+	"testing"
+)
+
+// multipartBody encodes fields as form fields, except "file", which is sent
+// as a file named photo.jpg.
+func multipartBody(t *testing.T, boundary string, fields ...[2]string) (string, string) {
+	t.Helper()
+	var b bytes.Buffer
+	w := multipart.NewWriter(&b)
+	if err := w.SetBoundary(boundary); err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range fields {
+		var err error
+		if f[0] == "file" {
+			var part io.Writer
+			part, err = w.CreateFormFile("file", "photo.jpg")
+			if err == nil {
+				_, err = part.Write([]byte(f[1]))
+			}
+		} else {
+			err = w.WriteField(f[0], f[1])
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	w.Close()
+	return w.FormDataContentType(), b.String()
+}
+
+func TestHashRequest(t *testing.T) {
+	file, note := [2]string{"file", "JPEGDATA"}, [2]string{"note", "tram"}
+	ctA, bodyA := multipartBody(t, "boundaryA", file, note)
+	ctB, bodyB := multipartBody(t, "boundaryB", file, note)
+	ctOther, bodyOther := multipartBody(t, "boundaryB", [2]string{"file", "OTHERDATA"}, note)
+	ctSwapped, bodySwapped := multipartBody(t, "boundaryA", note, file)
+
+	hash := func(method, uri, contentType, body string) string {
+		t.Helper()
+		h, err := HashRequest(method, uri, contentType, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return h
+	}
+	base := hash("POST", "/photos", ctA, bodyA)
+
+	tests := []struct {
+		name                  string
+		method, uri, ct, body string
+		same                  bool
+	}{
+		{"same request", "POST", "/photos", ctA, bodyA, true},
+		{"new boundary", "POST", "/photos", ctB, bodyB, true},
+		{"other file", "POST", "/photos", ctOther, bodyOther, false},
+		{"parts reordered", "POST", "/photos", ctSwapped, bodySwapped, false},
+		{"other path", "POST", "/photos/batch", ctA, bodyA, false},
+		{"other method", "PUT", "/photos", ctA, bodyA, false},
+		{"same bytes, not multipart", "POST", "/photos", "application/octet-stream", bodyA, false},
+	}
+	for _, tt := range tests {
+		if got := hash(tt.method, tt.uri, tt.ct, tt.body); (got == base) != tt.same {
+			t.Errorf("%s: hash equal %v, want %v", tt.name, got == base, tt.same)
+		}
+	}
+
+	// Plain bodies are hashed as they are.
+	if hash("POST", "/x", "application/json", `{"a":1}`) == hash("POST", "/x", "application/json", `{"a":2}`) {
+		t.Error("different JSON bodies hashed the same")
+	}
+	if hash("POST", "/x", "", "abc") != hash("POST", "/x", "text/plain", "abc") {
+		t.Error("the content type of a plain body changed its hash")
+	}
+
+	if _, err := HashRequest("POST", "/photos", ctA, strings.NewReader("--boundaryA\r\nbroken")); err == nil {
+		t.Error("a truncated multipart body hashed without error")
+	}
+}
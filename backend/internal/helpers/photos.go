@@ -2,76 +2,102 @@ package helpers
 
 import (
 	"context"
-	"database/sql"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
 	"time"
 
-	"github.com/Pranjal095/Memora/backend/config"
-	"github.com/Pranjal095/Memora/backend/internal/schema"
+	"github.com/Pranjal095/Memora/backend/internal/repository"
+	"github.com/Pranjal095/Memora/backend/internal/vectorindex"
 )
 
-func SavePhotoFile(filename, userID string, file []byte) (string, error) {
+// SavePhotoFile moves an uploaded temp file into uploads/ under a sanitized
+// name.
+func SavePhotoFile(src, filename, userID string, info MediaInfo) (string, error) {
 	if err := os.MkdirAll("uploads", 0755); err != nil {
 		return "", fmt.Errorf("failed to create uploads directory: %w", err)
 	}
 
-	dst := filepath.Join("uploads", fmt.Sprintf("%d_%s_%s", time.Now().UnixNano(), userID, filename))
+	name := SanitizeFilename(filename, info.MimeType)
+	dst := filepath.Join("uploads", fmt.Sprintf("%d_%s_%s", time.Now().UnixNano(), userID, name))
 
-	if err := os.WriteFile(dst, file, 0644); err != nil {
+	if err := moveFile(src, dst); err != nil {
 		return "", fmt.Errorf("failed to save file: %w", err)
 	}
-
 	return dst, nil
 }
 
-func CreatePhotoRecord(c context.Context, userID, url, note string) (int64, error) {
-	var id int64
-	err := config.DB.QueryRow(
-		c,
-		`INSERT INTO photos(user_id,url,note) VALUES($1,$2,$3) RETURNING id`,
-		userID, url, note,
-	).Scan(&id)
-	if err != nil {
-		return 0, fmt.Errorf("failed to create photo record: %w", err)
+// moveFile renames src to dst, copying it over when they are on different
+// file systems. dst must not exist yet.
+func moveFile(src, dst string) error {
+	if err := os.Rename(src, dst); err == nil {
+		return nil
 	}
-	return id, nil
-}
 
-func GetUserPhotos(c context.Context, userID string) ([]schema.PhotoResponse, error) {
-	rows, err := config.DB.Query(c,
-		`SELECT id,url,note,created_at FROM photos WHERE user_id=$1 ORDER BY created_at DESC`, userID)
+	in, err := os.Open(src)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query photos: %w", err)
+		return err
 	}
-	defer rows.Close()
-
-	var photos []schema.PhotoResponse
-	for rows.Next() {
-		var p schema.PhotoResponse
-		var note sql.NullString
-		var createdAt time.Time
+	defer in.Close()
 
-		err := rows.Scan(&p.ID, &p.URL, &note, &createdAt)
-		if err != nil {
-			continue
-		}
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(dst)
+		return err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(dst)
+		return err
+	}
+	os.Remove(src)
+	return nil
+}
 
-		if note.Valid {
-			p.Note = &note.String
-		}
+func CreatePhotoRecord(c context.Context, photos repository.PhotoRepository, userID, url, note string, info MediaInfo) (int64, error) {
+	return photos.Create(c, repository.NewPhoto{
+		UserID:      userID,
+		URL:         url,
+		Note:        note,
+		MimeType:    info.MimeType,
+		SizeBytes:   info.Size,
+		Width:       info.Width,
+		Height:      info.Height,
+		ContentHash: info.Hash,
+		MediaType:   info.MediaType(),
+		DurationMs:  info.DurationMs,
+		VideoCodec:  info.Codec,
+		PosterURL:   info.Poster,
+		Keyframes:   info.Keyframes,
+		City:        info.City,
+		Country:     info.Country,
+	})
+}
 
-		p.CreatedAt = createdAt.Format(time.RFC3339)
-		photos = append(photos, p)
+// StorePhotoCaptions records the captions the embed service returned on
+// the photo rows, except where the user wrote their own.
+func StorePhotoCaptions(c context.Context, photos repository.PhotoRepository, points ...vectorindex.Point) error {
+	captions := make(map[int64]string, len(points))
+	for _, p := range points {
+		captions[p.ID] = p.Payload.Caption
 	}
+	return photos.StoreCaptions(c, captions)
+}
 
-	if err = rows.Err(); err != nil {
-		return nil, fmt.Errorf("error reading photos: %w", err)
+// PhotoEmbedRequest rebuilds the embed request of one of the user's photos,
+// with file URLs under publicURL, where the embedding service can fetch
+// them outside of a request.
+func PhotoEmbedRequest(c context.Context, photos repository.PhotoRepository, publicURL, userID string, id int64) (EmbedRequest, error) {
+	src, err := photos.EmbedSource(c, userID, id)
+	if err != nil {
+		return EmbedRequest{}, err
 	}
-
-	return photos, nil
+	return embedRequest(src, publicURL), nil
 }
 
 func BuildFullURL(baseURL, path string) string {
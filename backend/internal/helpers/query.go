@@ -0,0 +1,245 @@
+package helpers
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Pranjal095/Memora/backend/internal/schema"
+)
+
+// Fields reported in schema.InterpretedFilter.
+const (
+	InterpretDate    = "date"
+	InterpretCity    = "city"
+	InterpretCountry = "country"
+)
+
+var months = map[string]time.Month{
+	"january": time.January, "jan": time.January,
+	"february": time.February, "feb": time.February,
+	"march": time.March, "mar": time.March,
+	"april": time.April, "apr": time.April,
+	"may":  time.May,
+	"june": time.June, "jun": time.June,
+	"july": time.July, "jul": time.July,
+	"august": time.August, "aug": time.August,
+	"september": time.September, "sep": time.September, "sept": time.September,
+	"october": time.October, "oct": time.October,
+	"november": time.November, "nov": time.November,
+	"december": time.December, "dec": time.December,
+}
+
+// seasonStart is the month each northern hemisphere season begins in.
+var seasonStart = map[string]time.Month{
+	"spring": time.March,
+	"summer": time.June,
+	"autumn": time.September,
+	"fall":   time.September,
+	"winter": time.December,
+}
+
+const (
+	datePrep   = `(?:(?:in|from|during|on|of|since)\s+)?`
+	monthNames = `january|february|march|april|may|june|july|august|september|october|november|december|jan|feb|mar|apr|jun|jul|aug|sept|sep|oct|nov|dec`
+	seasons    = `spring|summer|autumn|fall|winter`
+)
+
+// dateRule turns a matched phrase into a half-open [from, to) range.
+type dateRule struct {
+	re      *regexp.Regexp
+	rangeFn func(m []string, now time.Time) (time.Time, time.Time, bool)
+}
+
+// dateRules are tried in order; the first that matches wins, so the more
+// specific phrasings come first.
+var dateRules = []dateRule{
+	{
+		re: regexp.MustCompile(`(?i)\b` + datePrep + `(` + seasons + `)\s+(?:of\s+)?(\d{4})\b`),
+		rangeFn: func(m []string, now time.Time) (time.Time, time.Time, bool) {
+			year, _ := strconv.Atoi(m[2])
+			from := time.Date(year, seasonStart[strings.ToLower(m[1])], 1, 0, 0, 0, 0, time.UTC)
+			return from, from.AddDate(0, 3, 0), validYear(year)
+		},
+	},
+	{
+		re: regexp.MustCompile(`(?i)\b` + datePrep + `(this|last)\s+(` + seasons + `)\b`),
+		rangeFn: func(m []string, now time.Time) (time.Time, time.Time, bool) {
+			start := seasonStart[strings.ToLower(m[2])]
+			from := time.Date(now.Year(), start, 1, 0, 0, 0, 0, time.UTC)
+			if from.After(now) {
+				from = from.AddDate(-1, 0, 0)
+			}
+			// "last" means the most recent season that is already over.
+			if strings.EqualFold(m[1], "last") && from.AddDate(0, 3, 0).After(now) {
+				from = from.AddDate(-1, 0, 0)
+			}
+			return from, from.AddDate(0, 3, 0), true
+		},
+	},
+	{
+		re: regexp.MustCompile(`(?i)\b` + datePrep + `(` + monthNames + `)\s+(?:of\s+)?(\d{4})\b`),
+		rangeFn: func(m []string, now time.Time) (time.Time, time.Time, bool) {
+			year, _ := strconv.Atoi(m[2])
+			from := time.Date(year, months[strings.ToLower(m[1])], 1, 0, 0, 0, 0, time.UTC)
+			return from, from.AddDate(0, 1, 0), validYear(year)
+		},
+	},
+	{
+		// Bare month names need a lead-in: "may" and "march" are also words.
+		re: regexp.MustCompile(`(?i)\b(in|this|last)\s+(` + monthNames + `)\b`),
+		rangeFn: func(m []string, now time.Time) (time.Time, time.Time, bool) {
+			from := time.Date(now.Year(), months[strings.ToLower(m[2])], 1, 0, 0, 0, 0, time.UTC)
+			if from.After(now) {
+				from = from.AddDate(-1, 0, 0)
+			}
+			if strings.EqualFold(m[1], "last") && from.AddDate(0, 1, 0).After(now) {
+				from = from.AddDate(-1, 0, 0)
+			}
+			return from, from.AddDate(0, 1, 0), true
+		},
+	},
+	{
+		re: regexp.MustCompile(`(?i)\b` + datePrep + `(today|yesterday)\b`),
+		rangeFn: func(m []string, now time.Time) (time.Time, time.Time, bool) {
+			from := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+			if strings.EqualFold(m[1], "yesterday") {
+				from = from.AddDate(0, 0, -1)
+			}
+			return from, from.AddDate(0, 0, 1), true
+		},
+	},
+	{
+		re: regexp.MustCompile(`(?i)\b` + datePrep + `(this|last)\s+(week|month|year)\b`),
+		rangeFn: func(m []string, now time.Time) (time.Time, time.Time, bool) {
+			last := strings.EqualFold(m[1], "last")
+			var from, to time.Time
+			switch strings.ToLower(m[2]) {
+			case "week":
+				day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+				from = day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+				if last {
+					from = from.AddDate(0, 0, -7)
+				}
+				to = from.AddDate(0, 0, 7)
+			case "month":
+				from = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+				if last {
+					from = from.AddDate(0, -1, 0)
+				}
+				to = from.AddDate(0, 1, 0)
+			case "year":
+				from = time.Date(now.Year(), time.January, 1, 0, 0, 0, 0, time.UTC)
+				if last {
+					from = from.AddDate(-1, 0, 0)
+				}
+				to = from.AddDate(1, 0, 0)
+			}
+			return from, to, true
+		},
+	},
+	{
+		re: regexp.MustCompile(`(?i)\b` + datePrep + `((?:19|20)\d{2})\b`),
+		rangeFn: func(m []string, now time.Time) (time.Time, time.Time, bool) {
+			year, _ := strconv.Atoi(m[1])
+			from := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+			return from, from.AddDate(1, 0, 0), true
+		},
+	},
+}
+
+func validYear(year int) bool {
+	return year >= 1900 && year < 2100
+}
+
+// InterpretQuery pulls a date phrase and known place names out of q and
+// returns them as filters along with the remaining text. Places are matched
+// against the cities and countries passed in, typically those the user has
+// photos in. Fields listed in skip are left in the text.
+func InterpretQuery(q string, now time.Time, cities, countries []string, skip map[string]bool) (string, schema.SearchFilters, []schema.InterpretedFilter) {
+	var (
+		f      schema.SearchFilters
+		chips  []schema.InterpretedFilter
+		spaced = q
+	)
+
+	if !skip[InterpretDate] {
+		for _, rule := range dateRules {
+			loc := rule.re.FindStringSubmatchIndex(spaced)
+			if loc == nil {
+				continue
+			}
+			m := submatches(spaced, loc)
+			from, to, ok := rule.rangeFn(m, now.UTC())
+			if !ok {
+				continue
+			}
+			f.From, f.To = &from, &to
+			chips = append(chips, schema.InterpretedFilter{
+				Field: InterpretDate,
+				Text:  strings.TrimSpace(m[0]),
+				From:  &from,
+				To:    &to,
+			})
+			spaced = spaced[:loc[0]] + " " + spaced[loc[1]:]
+			break
+		}
+	}
+
+	if !skip[InterpretCity] {
+		if name, rest, text, ok := matchPlace(spaced, cities); ok {
+			f.City, spaced = name, rest
+			chips = append(chips, schema.InterpretedFilter{Field: InterpretCity, Text: text, Value: name})
+		}
+	}
+	if !skip[InterpretCountry] {
+		if name, rest, text, ok := matchPlace(spaced, countries); ok {
+			f.Country, spaced = name, rest
+			chips = append(chips, schema.InterpretedFilter{Field: InterpretCountry, Text: text, Value: name})
+		}
+	}
+
+	// Drop punctuation left stranded by the removed phrases.
+	var words []string
+	for _, w := range strings.Fields(spaced) {
+		if strings.Trim(w, ",.;:!?") != "" {
+			words = append(words, w)
+		}
+	}
+	return strings.Join(words, " "), f, chips
+}
+
+func submatches(s string, loc []int) []string {
+	m := make([]string, len(loc)/2)
+	for i := range m {
+		if loc[2*i] >= 0 {
+			m[i] = s[loc[2*i]:loc[2*i+1]]
+		}
+	}
+	return m
+}
+
+// matchPlace finds the longest of names in q as a whole phrase, together
+// with a leading "in", "at" or "near".
+func matchPlace(q string, names []string) (string, string, string, bool) {
+	sorted := append([]string(nil), names...)
+	sort.Slice(sorted, func(i, j int) bool { return len(sorted[i]) > len(sorted[j]) })
+
+	for _, name := range sorted {
+		if strings.TrimSpace(name) == "" {
+			continue
+		}
+		re, err := regexp.Compile(`(?i)(?:^|\s)((?:(?:in|at|near|from)\s+)?` + regexp.QuoteMeta(name) + `)(?:$|[\s,.!?])`)
+		if err != nil {
+			continue
+		}
+		loc := re.FindStringSubmatchIndex(q)
+		if loc == nil {
+			continue
+		}
+		return name, q[:loc[2]] + " " + q[loc[3]:], strings.TrimSpace(q[loc[2]:loc[3]]), true
+	}
+	return "", q, "", false
+}
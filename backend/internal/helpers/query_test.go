@@ -0,0 +1,166 @@
+package helpers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Pranjal095/Memora/backend/internal/schema"
+)
+
+// queryNow is the fixed clock for the query tests: a Wednesday in spring.
+var queryNow = time.Date(2024, time.May, 15, 10, 30, 0, 0, time.UTC)
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func TestDateRules(t *testing.T) {
+	tests := []struct {
+		phrase   string
+		from, to time.Time
+	}{
+		{"summer 2023", date(2023, time.June, 1), date(2023, time.September, 1)},
+		{"in spring of 2022", date(2022, time.March, 1), date(2022, time.June, 1)},
+		{"winter 2023", date(2023, time.December, 1), date(2024, time.March, 1)},
+		// Summer hasn't started yet, so "this summer" is last year's.
+		{"this summer", date(2023, time.June, 1), date(2023, time.September, 1)},
+		{"this spring", date(2024, time.March, 1), date(2024, time.June, 1)},
+		// Spring isn't over, so "last spring" is the one before.
+		{"last spring", date(2023, time.March, 1), date(2023, time.June, 1)},
+		{"last winter", date(2023, time.December, 1), date(2024, time.March, 1)},
+		{"during the fall", time.Time{}, time.Time{}},
+		{"march 2021", date(2021, time.March, 1), date(2021, time.April, 1)},
+		{"from Sept of 2020", date(2020, time.September, 1), date(2020, time.October, 1)},
+		{"in june", date(2023, time.June, 1), date(2023, time.July, 1)},
+		{"this may", date(2024, time.May, 1), date(2024, time.June, 1)},
+		{"last may", date(2023, time.May, 1), date(2023, time.June, 1)},
+		{"last april", date(2024, time.April, 1), date(2024, time.May, 1)},
+		{"may flowers", time.Time{}, time.Time{}},
+		{"today", date(2024, time.May, 15), date(2024, time.May, 16)},
+		{"on yesterday", date(2024, time.May, 14), date(2024, time.May, 15)},
+		{"this week", date(2024, time.May, 13), date(2024, time.May, 20)},
+		{"last week", date(2024, time.May, 6), date(2024, time.May, 13)},
+		{"this month", date(2024, time.May, 1), date(2024, time.June, 1)},
+		{"last month", date(2024, time.April, 1), date(2024, time.May, 1)},
+		{"last year", date(2023, time.January, 1), date(2024, time.January, 1)},
+		{"since 2019", date(2019, time.January, 1), date(2020, time.January, 1)},
+		// Out of range years fall through to the rules after them.
+		{"summer 1850", time.Time{}, time.Time{}},
+		{"room 3000", time.Time{}, time.Time{}},
+	}
+	for _, tt := range tests {
+		var from, to time.Time
+		for _, rule := range dateRules {
+			loc := rule.re.FindStringSubmatchIndex(tt.phrase)
+			if loc == nil {
+				continue
+			}
+			if f, tm, ok := rule.rangeFn(submatches(tt.phrase, loc), queryNow); ok {
+				from, to = f, tm
+				break
+			}
+		}
+		if !from.Equal(tt.from) || !to.Equal(tt.to) {
+			t.Errorf("%q: got [%s, %s), want [%s, %s)", tt.phrase,
+				from.Format(time.DateOnly), to.Format(time.DateOnly),
+				tt.from.Format(time.DateOnly), tt.to.Format(time.DateOnly))
+		}
+	}
+}
+
+func TestMatchPlace(t *testing.T) {
+	tests := []struct {
+		name  string
+		q     string
+		names []string
+		want  string
+		rest  string
+		text  string
+	}{
+		{"with lead-in", "beach in Lisbon", []string{"Lisbon"}, "Lisbon", "beach  ", "in Lisbon"},
+		{"from", "from Porto", []string{"Porto"}, "Porto", " ", "from Porto"},
+		{"longest wins", "new york pizza", []string{"York", "New York"}, "New York", "  pizza", "new york"},
+		{"before punctuation", "Paris, at night", []string{"Paris"}, "Paris", " , at night", "Paris"},
+		{"quoted name", "walk near St. Ives", []string{"St. Ives"}, "St. Ives", "walk  ", "near St. Ives"},
+		{"whole words only", "lisboners", []string{"Lisbon"}, "", "lisboners", ""},
+		{"blank names", "in  town", []string{"", " "}, "", "in  town", ""},
+		{"no names", "in Lisbon", nil, "", "in Lisbon", ""},
+	}
+	for _, tt := range tests {
+		name, rest, text, ok := matchPlace(tt.q, tt.names)
+		if ok != (tt.want != "") || name != tt.want || rest != tt.rest || text != tt.text {
+			t.Errorf("%s: got %q, %q, %q, %v; want %q, %q, %q", tt.name, name, rest, text, ok, tt.want, tt.rest, tt.text)
+		}
+	}
+}
+
+func TestInterpretQuery(t *testing.T) {
+	cities := []string{"Lisbon", "Paris"}
+	countries := []string{"Portugal", "France"}
+	lastSummer := [2]time.Time{date(2023, time.June, 1), date(2023, time.September, 1)}
+	in2022 := [2]time.Time{date(2022, time.January, 1), date(2023, time.January, 1)}
+
+	tests := []struct {
+		name    string
+		q       string
+		skip    map[string]bool
+		text    string
+		dates   *[2]time.Time
+		city    string
+		country string
+		chips   []string
+	}{
+		{"plain", "red bicycle", nil, "red bicycle", nil, "", "", nil},
+		{"date and city", "sunset in Lisbon last summer", nil, "sunset", &lastSummer, "Lisbon", "",
+			[]string{"date:last summer", "city:in Lisbon"}},
+		{"date and country", "photos from Portugal in 2022", nil, "photos", &in2022, "", "Portugal",
+			[]string{"date:in 2022", "country:from Portugal"}},
+		{"stranded punctuation", "dogs at Paris, yesterday", nil, "dogs", &[2]time.Time{date(2024, time.May, 14), date(2024, time.May, 15)}, "Paris", "",
+			[]string{"date:yesterday", "city:at Paris"}},
+		{"only the first date", "2019 or 2020", nil, "or 2020", &[2]time.Time{date(2019, time.January, 1), date(2020, time.January, 1)}, "", "",
+			[]string{"date:2019"}},
+		{"skip date", "cats in 2022", map[string]bool{InterpretDate: true}, "cats in 2022", nil, "", "", nil},
+		{"skip city", "Paris in France", map[string]bool{InterpretCity: true}, "Paris", nil, "", "France",
+			[]string{"country:in France"}},
+		{"unknown place", "Berlin wall", nil, "Berlin wall", nil, "", "", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			text, f, chips := InterpretQuery(tt.q, queryNow, cities, countries, tt.skip)
+			if text != tt.text {
+				t.Errorf("text: got %q, want %q", text, tt.text)
+			}
+			if f.City != tt.city || f.Country != tt.country {
+				t.Errorf("place: got %q, %q, want %q, %q", f.City, f.Country, tt.city, tt.country)
+			}
+			checkDates(t, f, tt.dates)
+
+			var got []string
+			for _, c := range chips {
+				got = append(got, c.Field+":"+c.Text)
+			}
+			if len(got) != len(tt.chips) {
+				t.Fatalf("chips: got %v, want %v", got, tt.chips)
+			}
+			for i := range got {
+				if got[i] != tt.chips[i] {
+					t.Errorf("chips: got %v, want %v", got, tt.chips)
+					break
+				}
+			}
+		})
+	}
+}
+
+func checkDates(t *testing.T, f schema.SearchFilters, want *[2]time.Time) {
+	t.Helper()
+	if want == nil {
+		if f.From != nil || f.To != nil {
+			t.Errorf("dates: got %v, %v, want none", f.From, f.To)
+		}
+		return
+	}
+	if f.From == nil || f.To == nil || !f.From.Equal(want[0]) || !f.To.Equal(want[1]) {
+		t.Errorf("dates: got %v, %v, want %v", f.From, f.To, *want)
+	}
+}
@@ -0,0 +1,571 @@
+package helpers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/Pranjal095/Memora/backend/config"
+	"github.com/Pranjal095/Memora/backend/internal/repository"
+	"github.com/Pranjal095/Memora/backend/internal/schema"
+	"github.com/Pranjal095/Memora/backend/internal/vectorindex"
+)
+
+const (
+	ReindexRunning   = "running"
+	ReindexPaused    = "paused"
+	ReindexSucceeded = "succeeded"
+	ReindexFailed    = "failed"
+)
+
+var (
+	ErrReindexRunning   = errors.New("a reindex is already running")
+	ErrReindexNotFound  = errors.New("reindex run not found")
+	ErrReindexFinished  = errors.New("reindex run already finished")
+	ErrReindexNotActive = errors.New("reindex run is not running in this server")
+)
+
+// reindexLockKey identifies the advisory lock held while a run is active, so
+// the CLI and the server never rebuild the index at the same time.
+const reindexLockKey = 0x6d656d6f7261
+
+const reindexPageSize = 100
+
+var (
+	reindexCancels = make(map[int64]context.CancelFunc)
+	reindexMu      sync.Mutex
+)
+
+// ReindexLock is the session advisory lock of a run, held on a dedicated
+// connection until Release.
+type ReindexLock struct {
+	conn *pgxpool.Conn
+}
+
+// LockReindex takes the reindex lock or fails with ErrReindexRunning. Runs
+// still marked running at that point belonged to a process that died, so
+// they are marked paused and can be resumed.
+func LockReindex(c context.Context) (*ReindexLock, error) {
+	conn, err := config.DB.Acquire(c)
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire connection: %w", err)
+	}
+
+	var ok bool
+	if err := conn.QueryRow(c, `SELECT pg_try_advisory_lock($1)`, reindexLockKey).Scan(&ok); err != nil {
+		conn.Release()
+		return nil, fmt.Errorf("failed to take reindex lock: %w", err)
+	}
+	if !ok {
+		conn.Release()
+		return nil, ErrReindexRunning
+	}
+
+	_, err = config.DB.Exec(c,
+		`UPDATE reindex_runs SET status=$1, updated_at=NOW() WHERE status=$2`,
+		ReindexPaused, ReindexRunning)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to reset reindex runs: %v\n", err)
+	}
+	return &ReindexLock{conn: conn}, nil
+}
+
+func (l *ReindexLock) Release() {
+	l.conn.Exec(context.Background(), `SELECT pg_advisory_unlock($1)`, reindexLockKey)
+	l.conn.Release()
+}
+
+// CreateReindexRun records a new run. An unfiltered run on a backend with
+// generations gets a target named after the live index and the run id;
+// filtered runs only touch some vectors, so they overwrite them in place.
+func CreateReindexRun(c context.Context, req schema.ReindexRequest) (*schema.ReindexRun, error) {
+	var userID *int64
+	if req.UserID > 0 {
+		userID = &req.UserID
+	}
+
+	var id int64
+	err := config.DB.QueryRow(c,
+		`INSERT INTO reindex_runs(status,user_id,from_ts,to_ts) VALUES($1,$2,$3,$4) RETURNING id`,
+		ReindexPaused, userID, req.From, req.To).Scan(&id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create reindex run: %w", err)
+	}
+
+	if _, ok := Vectors().(vectorindex.Generations); ok && userID == nil && req.From == nil && req.To == nil {
+		target := fmt.Sprintf("%s_v%d", vectorsName, id)
+		if _, err := config.DB.Exec(c, `UPDATE reindex_runs SET target=$2 WHERE id=$1`, id, target); err != nil {
+			return nil, fmt.Errorf("failed to create reindex run: %w", err)
+		}
+	}
+	return GetReindexRun(c, id)
+}
+
+// RunReindex re-embeds the photos in scope of run id, continuing after its
+// checkpoint, and promotes the target generation once every page is done and
+// every photo that failed has been retried successfully.
+// The caller must hold the reindex lock. progress, if set, is called after
+// each page. A canceled context leaves the run paused.
+func RunReindex(c context.Context, cfg *config.Config, photos repository.PhotoRepository, id int64, concurrency int, progress func(schema.ReindexRun)) error {
+	run, err := GetReindexRun(c, id)
+	if err != nil {
+		return err
+	}
+	if run.Status == ReindexSucceeded {
+		return ErrReindexFinished
+	}
+
+	_, err = config.DB.Exec(c,
+		`UPDATE reindex_runs SET status=$2, error=NULL, finished_at=NULL, updated_at=NOW() WHERE id=$1`,
+		id, ReindexRunning)
+	if err != nil {
+		return fmt.Errorf("failed to start reindex run: %w", err)
+	}
+
+	err = reindex(c, photos, cfg.PublicURL, run, concurrency, progress)
+	switch {
+	case c.Err() != nil:
+		finishReindexRun(id, ReindexPaused, "")
+		return c.Err()
+	case err != nil:
+		finishReindexRun(id, ReindexFailed, err.Error())
+		return err
+	}
+	finishReindexRun(id, ReindexSucceeded, "")
+	return nil
+}
+
+func reindex(c context.Context, photos repository.PhotoRepository, base string, run *schema.ReindexRun, concurrency int, progress func(schema.ReindexRun)) error {
+	idx := Vectors()
+	var gens vectorindex.Generations
+	if run.Target != nil {
+		var ok bool
+		if gens, ok = idx.(vectorindex.Generations); !ok {
+			return errors.New("vector backend does not support generations")
+		}
+		var err error
+		if idx, err = gens.Generation(c, *run.Target); err != nil {
+			return err
+		}
+	}
+
+	scope, err := reindexScope(c, run.ID)
+	if err != nil {
+		return err
+	}
+	total, err := photos.Count(c, scope)
+	if err != nil {
+		return err
+	}
+	if _, err := config.DB.Exec(c, `UPDATE reindex_runs SET total=$2 WHERE id=$1`, run.ID, total); err != nil {
+		return fmt.Errorf("failed to record reindex total: %w", err)
+	}
+
+	for checkpoint := run.CheckpointID; ; {
+		sources, err := photos.EmbedSourcesAfter(c, scope, checkpoint, reindexPageSize)
+		if err != nil {
+			return err
+		}
+		if len(sources) == 0 {
+			break
+		}
+
+		page := embedRequests(sources, base)
+		points, failed := embedPhotos(c, fmt.Sprintf("reindex %d", run.ID), page, concurrency)
+		// A page cut short by cancellation is redone on resume.
+		if c.Err() != nil {
+			return c.Err()
+		}
+		if err := writePoints(c, photos, idx, points); err != nil {
+			return err
+		}
+
+		checkpoint = page[len(page)-1].ID
+		row := config.DB.QueryRow(c,
+			`UPDATE reindex_runs SET done=done+$2, failed_ids=failed_ids||$3::bigint[], failed=cardinality(failed_ids)+cardinality($3::bigint[]),
+			        checkpoint_id=$4, updated_at=NOW()
+			 WHERE id=$1 RETURNING `+reindexRunColumns,
+			run.ID, len(points), failed, checkpoint)
+		if err := reportReindex(row, progress); err != nil {
+			return err
+		}
+	}
+
+	if err := retryFailed(c, photos, base, run.ID, idx, concurrency, progress); err != nil {
+		return err
+	}
+	if gens == nil {
+		return nil
+	}
+	if err := catchUp(c, photos, base, run.ID, idx, concurrency); err != nil {
+		return err
+	}
+	// Anything written to the live index between the catch-up and the
+	// switch is still lost; fsck finds and repairs it.
+	return gens.Promote(c, *run.Target)
+}
+
+// writePoints stores freshly embedded points and the captions generated for
+// them.
+func writePoints(c context.Context, photos repository.PhotoRepository, idx vectorindex.VectorIndex, points []vectorindex.Point) error {
+	if len(points) == 0 {
+		return nil
+	}
+	if err := idx.Upsert(c, points...); err != nil {
+		return err
+	}
+	return StorePhotoCaptions(c, photos, points...)
+}
+
+func reportReindex(row pgx.Row, progress func(schema.ReindexRun)) error {
+	updated, err := scanReindexRun(row)
+	if err != nil {
+		return fmt.Errorf("failed to record reindex progress: %w", err)
+	}
+	if progress != nil {
+		progress(*updated)
+	}
+	return nil
+}
+
+// retryFailed embeds the photos of run id that failed once more. Photos
+// deleted in the meantime are dropped. A run with photos still failing
+// returns an error, so it ends failed and can be resumed to retry them
+// rather than being promoted without them.
+func retryFailed(c context.Context, photos repository.PhotoRepository, base string, runID int64, idx vectorindex.VectorIndex, concurrency int, progress func(schema.ReindexRun)) error {
+	var ids []int64
+	if err := config.DB.QueryRow(c, `SELECT failed_ids FROM reindex_runs WHERE id=$1`, runID).Scan(&ids); err != nil {
+		return fmt.Errorf("failed to get reindex run: %w", err)
+	}
+	sources, err := photos.EmbedSourcesByIDs(c, ids)
+	if err != nil {
+		return err
+	}
+
+	points, failed := embedPhotos(c, fmt.Sprintf("reindex %d retry", runID), embedRequests(sources, base), concurrency)
+	if c.Err() != nil {
+		return c.Err()
+	}
+	if err := writePoints(c, photos, idx, points); err != nil {
+		return err
+	}
+
+	row := config.DB.QueryRow(c,
+		`UPDATE reindex_runs SET done=done+$2, failed_ids=$3::bigint[], failed=cardinality($3::bigint[]), updated_at=NOW()
+		 WHERE id=$1 RETURNING `+reindexRunColumns,
+		runID, len(points), failed)
+	if err := reportReindex(row, progress); err != nil {
+		return err
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("%d photos failed to embed; resume the run to retry them", len(failed))
+	}
+	return nil
+}
+
+// catchUp replays into the target generation of run id what happened to the
+// live index while the run went through the pages behind its checkpoint:
+// captions edited since the run was created are embedded again, and vectors
+// of photos deleted since are removed.
+func catchUp(c context.Context, photos repository.PhotoRepository, base string, runID int64, idx vectorindex.VectorIndex, concurrency int) error {
+	var (
+		createdAt  time.Time
+		checkpoint int64
+	)
+	err := config.DB.QueryRow(c, `SELECT created_at, checkpoint_id FROM reindex_runs WHERE id=$1`, runID).
+		Scan(&createdAt, &checkpoint)
+	if err != nil {
+		return fmt.Errorf("failed to get reindex run: %w", err)
+	}
+	sources, err := photos.EditedEmbedSources(c, createdAt, checkpoint)
+	if err != nil {
+		return err
+	}
+	for edited := embedRequests(sources, base); len(edited) > 0; {
+		n := min(len(edited), reindexPageSize)
+		points, failed := embedPhotos(c, fmt.Sprintf("reindex %d catch-up", runID), edited[:n], concurrency)
+		if c.Err() != nil {
+			return c.Err()
+		}
+		if len(failed) > 0 {
+			return fmt.Errorf("%d edited photos failed to embed; resume the run to retry them", len(failed))
+		}
+		if err := writePoints(c, photos, idx, points); err != nil {
+			return err
+		}
+		edited = edited[n:]
+	}
+
+	for next := int64(0); ; {
+		points, after, err := idx.Scroll(c, vectorindex.Filter{}, next, 256)
+		if err != nil {
+			return err
+		}
+		ids := make([]int64, len(points))
+		for i, p := range points {
+			ids[i] = p.ID
+		}
+		existing, err := photos.ExistingIDs(c, ids)
+		if err != nil {
+			return err
+		}
+		var gone []int64
+		for _, id := range ids {
+			if !slices.Contains(existing, id) {
+				gone = append(gone, id)
+			}
+		}
+		if len(gone) > 0 {
+			if err := idx.Delete(c, gone...); err != nil {
+				return err
+			}
+		}
+		if after == 0 {
+			return nil
+		}
+		next = after
+	}
+}
+
+// reindexScope reads which photos run id covers.
+func reindexScope(c context.Context, runID int64) (repository.PhotoScope, error) {
+	var (
+		scope  repository.PhotoScope
+		userID *int64
+	)
+	err := config.DB.QueryRow(c, `SELECT user_id, from_ts, to_ts FROM reindex_runs WHERE id=$1`, runID).
+		Scan(&userID, &scope.From, &scope.To)
+	if err != nil {
+		return scope, fmt.Errorf("failed to get reindex run: %w", err)
+	}
+	if userID != nil {
+		scope.UserID = *userID
+	}
+	return scope, nil
+}
+
+// embedRequests rebuilds the requests the uploads of sources made, with file
+// URLs under base.
+func embedRequests(sources []repository.EmbedSource, base string) []EmbedRequest {
+	page := make([]EmbedRequest, len(sources))
+	for i, src := range sources {
+		page[i] = embedRequest(src, base)
+	}
+	return page
+}
+
+// embedRequest rebuilds the request the upload of src made, with file URLs
+// under base.
+func embedRequest(src repository.EmbedSource, base string) EmbedRequest {
+	req := EmbedRequest{
+		ImagePath: BuildFullURL(base, src.URL),
+		Note:      src.Note,
+		City:      src.City,
+		Country:   src.Country,
+		Caption:   src.Caption,
+		MediaType: src.MediaType,
+		UserID:    strconv.FormatInt(src.UserID, 10),
+		CreatedAt: src.CreatedAt.Unix(),
+		ID:        src.ID,
+	}
+	if src.PosterURL != "" {
+		req.ImagePath = BuildFullURL(base, src.PosterURL)
+		for _, kf := range src.Keyframes {
+			req.ImagePaths = append(req.ImagePaths, BuildFullURL(base, kf))
+		}
+	}
+	return req
+}
+
+// embedPhotos embeds a page of photos, at most concurrency at a time. Photos
+// that fail are logged under label and their ids returned rather than
+// retried.
+func embedPhotos(c context.Context, label string, page []EmbedRequest, concurrency int) ([]vectorindex.Point, []int64) {
+	var (
+		points []vectorindex.Point
+		failed = []int64{}
+		mu     sync.Mutex
+		wg     sync.WaitGroup
+		sem    = make(chan struct{}, max(concurrency, 1))
+	)
+	for _, req := range page {
+		select {
+		case sem <- struct{}{}:
+		case <-c.Done():
+		}
+		if c.Err() != nil {
+			break
+		}
+
+		wg.Add(1)
+		go func(req EmbedRequest) {
+			defer func() { <-sem; wg.Done() }()
+			point, err := EmbedPhoto(c, req)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%s: photo %d: %v\n", label, req.ID, err)
+				failed = append(failed, req.ID)
+				return
+			}
+			points = append(points, point)
+		}(req)
+	}
+	wg.Wait()
+	return points, failed
+}
+
+func finishReindexRun(id int64, status, errMsg string) {
+	_, err := config.DB.Exec(context.Background(),
+		`UPDATE reindex_runs SET status=$2, error=NULLIF($3,''), updated_at=NOW(),
+		        finished_at=CASE WHEN $2 IN ($4,$5) THEN NOW() END
+		 WHERE id=$1`,
+		id, status, errMsg, ReindexSucceeded, ReindexFailed)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to record reindex run %d: %v\n", id, err)
+	}
+}
+
+// StartReindex creates a run and starts it in the background. It fails with
+// ErrReindexRunning while another run holds the lock.
+func StartReindex(c context.Context, cfg *config.Config, photos repository.PhotoRepository, req schema.ReindexRequest) (*schema.ReindexRun, error) {
+	lock, err := LockReindex(c)
+	if err != nil {
+		return nil, err
+	}
+	run, err := CreateReindexRun(c, req)
+	if err != nil {
+		lock.Release()
+		return nil, err
+	}
+	goReindex(lock, cfg, photos, run.ID, req.Concurrency)
+	return run, nil
+}
+
+// ResumeReindex continues a paused or failed run in the background.
+func ResumeReindex(c context.Context, cfg *config.Config, photos repository.PhotoRepository, id int64, concurrency int) (*schema.ReindexRun, error) {
+	lock, err := LockReindex(c)
+	if err != nil {
+		return nil, err
+	}
+	run, err := GetReindexRun(c, id)
+	if err == nil && run.Status == ReindexSucceeded {
+		err = ErrReindexFinished
+	}
+	if err != nil {
+		lock.Release()
+		return nil, err
+	}
+	goReindex(lock, cfg, photos, id, concurrency)
+	return run, nil
+}
+
+// goReindex runs id under lock, releasing it when the run ends. The run can
+// be paused with PauseReindex.
+func goReindex(lock *ReindexLock, cfg *config.Config, photos repository.PhotoRepository, id int64, concurrency int) {
+	if concurrency < 1 {
+		concurrency = cfg.ReindexConcurrency
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	reindexMu.Lock()
+	reindexCancels[id] = cancel
+	reindexMu.Unlock()
+
+	go func() {
+		defer func() {
+			reindexMu.Lock()
+			delete(reindexCancels, id)
+			reindexMu.Unlock()
+			cancel()
+			lock.Release()
+		}()
+		if err := RunReindex(ctx, cfg, photos, id, concurrency, nil); err != nil && ctx.Err() == nil {
+			fmt.Fprintf(os.Stderr, "reindex %d failed: %v\n", id, err)
+		}
+	}()
+}
+
+// PauseReindex stops a run started by this server. Its progress is kept and
+// it can be resumed later.
+func PauseReindex(c context.Context, id int64) error {
+	reindexMu.Lock()
+	cancel, ok := reindexCancels[id]
+	reindexMu.Unlock()
+	if ok {
+		cancel()
+		return nil
+	}
+
+	if _, err := GetReindexRun(c, id); err != nil {
+		return err
+	}
+	return ErrReindexNotActive
+}
+
+const reindexRunColumns = `id,status,user_id,from_ts,to_ts,target,total,done,failed,checkpoint_id,error,created_at,updated_at,finished_at`
+
+func scanReindexRun(row pgx.Row) (*schema.ReindexRun, error) {
+	var (
+		r                    schema.ReindexRun
+		from, to, finishedAt *time.Time
+		createdAt, updatedAt time.Time
+	)
+	err := row.Scan(&r.ID, &r.Status, &r.UserID, &from, &to, &r.Target, &r.Total, &r.Done, &r.Failed,
+		&r.CheckpointID, &r.Error, &createdAt, &updatedAt, &finishedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	r.From, r.To, r.FinishedAt = formatTime(from), formatTime(to), formatTime(finishedAt)
+	r.CreatedAt = createdAt.Format(time.RFC3339)
+	r.UpdatedAt = updatedAt.Format(time.RFC3339)
+	return &r, nil
+}
+
+func formatTime(t *time.Time) *string {
+	if t == nil {
+		return nil
+	}
+	s := t.Format(time.RFC3339)
+	return &s
+}
+
+func GetReindexRun(c context.Context, id int64) (*schema.ReindexRun, error) {
+	row := config.DB.QueryRow(c, `SELECT `+reindexRunColumns+` FROM reindex_runs WHERE id=$1`, id)
+	r, err := scanReindexRun(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrReindexNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get reindex run: %w", err)
+	}
+	return r, nil
+}
+
+func ListReindexRuns(c context.Context) ([]schema.ReindexRun, error) {
+	rows, err := config.DB.Query(c, `SELECT `+reindexRunColumns+` FROM reindex_runs ORDER BY id DESC LIMIT 50`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list reindex runs: %w", err)
+	}
+	defer rows.Close()
+
+	runs := []schema.ReindexRun{}
+	for rows.Next() {
+		r, err := scanReindexRun(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error reading reindex runs: %w", err)
+		}
+		runs = append(runs, *r)
+	}
+	return runs, rows.Err()
+}
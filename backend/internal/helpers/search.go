@@ -0,0 +1,179 @@
+package helpers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Pranjal095/Memora/backend/internal/repository"
+	"github.com/Pranjal095/Memora/backend/internal/schema"
+	"github.com/Pranjal095/Memora/backend/internal/vectorindex"
+)
+
+var ErrVectorNotFound = errors.New("photo has no vector yet")
+
+const (
+	SearchKeyword  = "keyword"
+	SearchSemantic = "semantic"
+	SearchHybrid   = "hybrid"
+)
+
+type SearchHit struct {
+	ID    int64   `json:"id"`
+	Score float64 `json:"score"`
+}
+
+// FusedHit is a photo ranked by reciprocal rank fusion. A rank of 0 means the
+// photo did not come up in that list.
+type FusedHit struct {
+	ID           int64
+	Score        float64
+	KeywordRank  int
+	SemanticRank int
+}
+
+// KeywordSearch runs q as a web search style query (quoted phrases, -term,
+// or) against the user's notes, captions and place names.
+func KeywordSearch(c context.Context, photos repository.PhotoRepository, userID, q string, f schema.SearchFilters, limit int) ([]SearchHit, error) {
+	found, err := photos.KeywordSearch(c, userID, q, f, limit)
+	if err != nil {
+		return nil, err
+	}
+	hits := make([]SearchHit, len(found))
+	for i, h := range found {
+		hits[i] = SearchHit{ID: h.ID, Score: h.Score}
+	}
+	return hits, nil
+}
+
+// FilterPhotos lists the user's photos matching f, newest first, for
+// queries that consist only of filters.
+func FilterPhotos(c context.Context, photos repository.PhotoRepository, userID string, f schema.SearchFilters, limit int) ([]FusedHit, error) {
+	ids, err := photos.Filter(c, userID, f, limit)
+	if err != nil {
+		return nil, err
+	}
+	hits := make([]FusedHit, len(ids))
+	for i, id := range ids {
+		hits[i] = FusedHit{ID: id}
+	}
+	return hits, nil
+}
+
+// SemanticSearch embeds q and returns the user's photos whose vectors are
+// closest to it and match f.
+func SemanticSearch(c context.Context, userID, q string, f schema.SearchFilters, limit int) ([]SearchHit, error) {
+	filter, err := vectorFilter(userID, f)
+	if err != nil {
+		return nil, err
+	}
+	vector, err := EmbedText(c, semanticQueryText(q))
+	if err != nil {
+		return nil, err
+	}
+	return vectorSearch(c, vector, filter, limit)
+}
+
+// SimilarPhotos returns the user's photos nearest to the stored vector of
+// photo id, excluding the photo itself.
+func SimilarPhotos(c context.Context, userID string, id int64, k int) ([]SearchHit, error) {
+	filter, err := vectorFilter(userID, schema.SearchFilters{})
+	if err != nil {
+		return nil, err
+	}
+	owned := filter
+	owned.IDs = []int64{id}
+	points, _, err := Vectors().Scroll(c, owned, 0, 1)
+	if err != nil {
+		return nil, fmt.Errorf("similar photos: %w", err)
+	}
+	if len(points) == 0 {
+		return nil, ErrVectorNotFound
+	}
+	filter.ExcludeIDs = []int64{id}
+	return vectorSearch(c, points[0].Vector, filter, k)
+}
+
+// SearchByImage returns the user's photos nearest to the image at path.
+func SearchByImage(c context.Context, userID, path string, k int) ([]SearchHit, error) {
+	filter, err := vectorFilter(userID, schema.SearchFilters{})
+	if err != nil {
+		return nil, err
+	}
+	vector, err := EmbedImageFile(c, path)
+	if err != nil {
+		return nil, err
+	}
+	return vectorSearch(c, vector, filter, k)
+}
+
+func vectorSearch(c context.Context, vector []float32, f vectorindex.Filter, limit int) ([]SearchHit, error) {
+	found, err := Vectors().Search(c, vector, f, limit)
+	if err != nil {
+		return nil, fmt.Errorf("vector search: %w", err)
+	}
+	hits := make([]SearchHit, len(found))
+	for i, h := range found {
+		hits[i] = SearchHit{ID: h.ID, Score: h.Score}
+	}
+	return hits, nil
+}
+
+// semanticQueryText strips the keyword query syntax, which means nothing to
+// the text encoder: quotes, excluded terms and "or".
+func semanticQueryText(q string) string {
+	var words []string
+	for _, w := range strings.Fields(strings.ReplaceAll(q, `"`, " ")) {
+		if strings.HasPrefix(w, "-") || strings.EqualFold(w, "or") {
+			continue
+		}
+		words = append(words, w)
+	}
+	return strings.Join(words, " ")
+}
+
+// RankWeights tune FuseRanks: the weight of each list and the k that damps
+// the difference between the top ranks.
+type RankWeights struct {
+	Keyword, Semantic, K float64
+}
+
+// FuseRanks merges the keyword and semantic lists with weighted reciprocal
+// rank fusion: each list contributes weight / (k + rank) for every photo it
+// contains.
+func FuseRanks(keyword, semantic []SearchHit, w RankWeights) []FusedHit {
+	k := w.K
+	byID := make(map[int64]*FusedHit)
+	fused := make([]*FusedHit, 0, len(keyword)+len(semantic))
+
+	add := func(hits []SearchHit, weight float64, setRank func(*FusedHit, int)) {
+		for i, h := range hits {
+			f, ok := byID[h.ID]
+			if !ok {
+				f = &FusedHit{ID: h.ID}
+				byID[h.ID] = f
+				fused = append(fused, f)
+			}
+			setRank(f, i+1)
+			f.Score += weight / (k + float64(i+1))
+		}
+	}
+	add(keyword, w.Keyword, func(f *FusedHit, r int) { f.KeywordRank = r })
+	add(semantic, w.Semantic, func(f *FusedHit, r int) { f.SemanticRank = r })
+
+	// Ties are broken by id so that pages don't shift between requests.
+	sort.Slice(fused, func(i, j int) bool {
+		if fused[i].Score != fused[j].Score {
+			return fused[i].Score > fused[j].Score
+		}
+		return fused[i].ID > fused[j].ID
+	})
+
+	out := make([]FusedHit, len(fused))
+	for i, f := range fused {
+		out[i] = *f
+	}
+	return out
+}
@@ -0,0 +1,59 @@
+package helpers
+
+import (
+	"math"
+	"testing"
+)
+
+func TestFuseRanks(t *testing.T) {
+	hits := func(ids ...int64) []SearchHit {
+		var out []SearchHit
+		for _, id := range ids {
+			out = append(out, SearchHit{ID: id})
+		}
+		return out
+	}
+
+	tests := []struct {
+		name              string
+		k, kwW, semW      float64
+		keyword, semantic []SearchHit
+		want              []FusedHit
+	}{
+		{"empty", 60, 1, 1, nil, nil, []FusedHit{}},
+		{"both lists count", 60, 1, 1, hits(1, 2), hits(2, 3), []FusedHit{
+			{ID: 2, Score: 1.0/62 + 1.0/61, KeywordRank: 2, SemanticRank: 1},
+			{ID: 1, Score: 1.0 / 61, KeywordRank: 1},
+			{ID: 3, Score: 1.0 / 62, SemanticRank: 2},
+		}},
+		{"ties go to the higher id", 60, 1, 1, hits(5), hits(7), []FusedHit{
+			{ID: 7, Score: 1.0 / 61, SemanticRank: 1},
+			{ID: 5, Score: 1.0 / 61, KeywordRank: 1},
+		}},
+		{"weights", 60, 0, 2, hits(1, 2), hits(3), []FusedHit{
+			{ID: 3, Score: 2.0 / 61, SemanticRank: 1},
+			{ID: 2, Score: 0, KeywordRank: 2},
+			{ID: 1, Score: 0, KeywordRank: 1},
+		}},
+		{"small k", 1, 1, 1, hits(1, 2, 3), hits(3, 2, 1), []FusedHit{
+			{ID: 3, Score: 1.0/4 + 1.0/2, KeywordRank: 3, SemanticRank: 1},
+			{ID: 1, Score: 1.0/2 + 1.0/4, KeywordRank: 1, SemanticRank: 3},
+			{ID: 2, Score: 1.0/3 + 1.0/3, KeywordRank: 2, SemanticRank: 2},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := FuseRanks(tt.keyword, tt.semantic, RankWeights{Keyword: tt.kwW, Semantic: tt.semW, K: tt.k})
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %+v, want %+v", got, tt.want)
+			}
+			for i, w := range tt.want {
+				g := got[i]
+				if g.ID != w.ID || g.KeywordRank != w.KeywordRank || g.SemanticRank != w.SemanticRank ||
+					math.Abs(g.Score-w.Score) > 1e-12 {
+					t.Errorf("hit %d: got %+v, want %+v", i, g, w)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,359 @@
+package helpers
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/Pranjal095/Memora/backend/config"
+)
+
+const TusVersion = "1.0.0"
+
+var (
+	ErrTusNotFound         = errors.New("upload not found")
+	ErrTusChecksumMismatch = errors.New("checksum mismatch")
+	ErrTusLocked           = errors.New("upload is locked")
+	ErrTusGone             = errors.New("upload data is gone; start a new upload")
+)
+
+var TusChecksumAlgorithms = []string{"sha1", "sha256", "md5"}
+
+type TusUpload struct {
+	ID       string
+	UserID   string
+	Length   int64
+	Offset   int64
+	Filename string
+	Note     string
+	PhotoID  *int64
+}
+
+type TusChecksum struct {
+	Algorithm string
+	Sum       []byte
+}
+
+var (
+	tusLocks   = make(map[string]bool)
+	tusLocksMu sync.Mutex
+)
+
+func tusFilePath(dir, id string) string {
+	return filepath.Join(dir, id+".bin")
+}
+
+// LockTusUpload guards an upload against concurrent PATCH requests; the
+// returned func releases the lock. Only held locks are remembered.
+func LockTusUpload(id string) (func(), error) {
+	tusLocksMu.Lock()
+	defer tusLocksMu.Unlock()
+	if tusLocks[id] {
+		return nil, ErrTusLocked
+	}
+	tusLocks[id] = true
+
+	return func() {
+		tusLocksMu.Lock()
+		delete(tusLocks, id)
+		tusLocksMu.Unlock()
+	}, nil
+}
+
+func ParseTusMetadata(header string) (map[string]string, error) {
+	meta := make(map[string]string)
+	if strings.TrimSpace(header) == "" {
+		return meta, nil
+	}
+	for _, pair := range strings.Split(header, ",") {
+		parts := strings.Fields(pair)
+		switch len(parts) {
+		case 1:
+			meta[parts[0]] = ""
+		case 2:
+			v, err := base64.StdEncoding.DecodeString(parts[1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid metadata value for %q: %w", parts[0], err)
+			}
+			meta[parts[0]] = string(v)
+		default:
+			return nil, fmt.Errorf("invalid metadata pair %q", pair)
+		}
+	}
+	return meta, nil
+}
+
+func ParseTusChecksum(header string) (*TusChecksum, error) {
+	if header == "" {
+		return nil, nil
+	}
+	parts := strings.Fields(header)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("malformed Upload-Checksum header")
+	}
+	if newTusHash(parts[0]) == nil {
+		return nil, fmt.Errorf("unsupported checksum algorithm %q", parts[0])
+	}
+	sum, err := base64.StdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("invalid checksum encoding: %w", err)
+	}
+	return &TusChecksum{Algorithm: parts[0], Sum: sum}, nil
+}
+
+func newTusHash(alg string) hash.Hash {
+	switch alg {
+	case "sha1":
+		return sha1.New()
+	case "sha256":
+		return sha256.New()
+	case "md5":
+		return md5.New()
+	}
+	return nil
+}
+
+// CreateTusUpload records an upload and creates its empty file in dir.
+func CreateTusUpload(c context.Context, dir, userID string, length int64, filename, note string) (*TusUpload, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create tus directory: %w", err)
+	}
+
+	raw := make([]byte, 16)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, fmt.Errorf("failed to generate upload id: %w", err)
+	}
+	id := hex.EncodeToString(raw)
+
+	f, err := os.Create(tusFilePath(dir, id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create upload file: %w", err)
+	}
+	f.Close()
+
+	_, err = config.DB.Exec(c,
+		`INSERT INTO tus_uploads(id,user_id,upload_length,filename,note) VALUES($1,$2,$3,$4,$5)`,
+		id, userID, length, filename, note)
+	if err != nil {
+		os.Remove(tusFilePath(dir, id))
+		return nil, fmt.Errorf("failed to create upload record: %w", err)
+	}
+
+	return &TusUpload{
+		ID:       id,
+		UserID:   userID,
+		Length:   length,
+		Filename: filename,
+		Note:     note,
+	}, nil
+}
+
+func GetTusUpload(c context.Context, id, userID string) (*TusUpload, error) {
+	u := TusUpload{ID: id, UserID: userID}
+	err := config.DB.QueryRow(c,
+		`SELECT upload_length,upload_offset,filename,note,photo_id FROM tus_uploads WHERE id=$1 AND user_id=$2`,
+		id, userID).
+		Scan(&u.Length, &u.Offset, &u.Filename, &u.Note, &u.PhotoID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrTusNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("lookup upload: %w", err)
+	}
+	return &u, nil
+}
+
+// WriteTusChunk streams body into the upload file at the current offset. When
+// a checksum is supplied the chunk is rolled back unless it verifies; without
+// one, whatever arrived before an interrupted transfer is kept, so the offset
+// is persisted even after the client has gone away.
+func WriteTusChunk(dir string, u *TusUpload, body io.Reader, sum *TusChecksum) error {
+	n, err := writeTusFile(tusFilePath(dir, u.ID), u.Offset, u.Length, body, sum)
+	if n > 0 {
+		if _, err := config.DB.Exec(context.Background(),
+			`UPDATE tus_uploads SET upload_offset=$1, updated_at=NOW() WHERE id=$2`, u.Offset+n, u.ID); err != nil {
+			return fmt.Errorf("update upload offset: %w", err)
+		}
+		u.Offset += n
+	}
+	return err
+}
+
+// writeTusFile does the file half of WriteTusChunk and returns how many
+// bytes it kept, which can be more than zero alongside an error.
+func writeTusFile(path string, offset, length int64, body io.Reader, sum *TusChecksum) (int64, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY, 0644)
+	if err != nil {
+		return 0, fmt.Errorf("open upload file: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return 0, fmt.Errorf("seek upload file: %w", err)
+	}
+
+	src := io.LimitReader(body, length-offset)
+	var h hash.Hash
+	if sum != nil {
+		h = newTusHash(sum.Algorithm)
+		src = io.TeeReader(src, h)
+	}
+
+	n, copyErr := io.Copy(f, src)
+	if sum != nil && (copyErr != nil || string(h.Sum(nil)) != string(sum.Sum)) {
+		if err := f.Truncate(offset); err != nil {
+			return 0, fmt.Errorf("discard chunk: %w", err)
+		}
+		if copyErr != nil {
+			return 0, fmt.Errorf("write chunk: %w", copyErr)
+		}
+		return 0, ErrTusChecksumMismatch
+	}
+	if err := f.Sync(); err != nil {
+		return 0, fmt.Errorf("sync upload file: %w", err)
+	}
+	if copyErr != nil {
+		return n, fmt.Errorf("write chunk: %w", copyErr)
+	}
+	return n, nil
+}
+
+// FinishTusUpload validates a fully received upload and moves it into the
+// photo store, returning the stored path. If storing the photo fails after
+// that, RestoreTusUpload puts the file back so the upload can be completed
+// again. It fails with ErrTusGone when the file is missing.
+func FinishTusUpload(dir string, u *TusUpload) (string, MediaInfo, error) {
+	path := tusFilePath(dir, u.ID)
+	if err := os.Truncate(path, u.Length); errors.Is(err, fs.ErrNotExist) {
+		return "", MediaInfo{}, ErrTusGone
+	} else if err != nil {
+		return "", MediaInfo{}, fmt.Errorf("truncate upload file: %w", err)
+	}
+
+	info, err := InspectMedia(path)
+	if err != nil {
+		return "", MediaInfo{}, err
+	}
+
+	dst, err := SavePhotoFile(path, u.Filename, u.UserID, info)
+	if err != nil {
+		return "", MediaInfo{}, err
+	}
+	return dst, info, nil
+}
+
+// RestoreTusUpload moves the file FinishTusUpload stored at dst back into
+// the upload.
+func RestoreTusUpload(dir string, u *TusUpload, dst string) error {
+	if err := moveFile(dst, tusFilePath(dir, u.ID)); err != nil {
+		return fmt.Errorf("restore upload file: %w", err)
+	}
+	return nil
+}
+
+func SetTusUploadPhoto(c context.Context, id string, photoID int64) error {
+	_, err := config.DB.Exec(c, `UPDATE tus_uploads SET photo_id=$1, updated_at=NOW() WHERE id=$2`, photoID, id)
+	if err != nil {
+		return fmt.Errorf("link upload to photo: %w", err)
+	}
+	return nil
+}
+
+func DeleteTusUpload(c context.Context, dir, id string) error {
+	if _, err := config.DB.Exec(c, `DELETE FROM tus_uploads WHERE id=$1`, id); err != nil {
+		return fmt.Errorf("delete upload record: %w", err)
+	}
+	if err := os.Remove(tusFilePath(dir, id)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("delete upload file: %w", err)
+	}
+	return nil
+}
+
+// StartTusPurge removes the uploads in dir that expired every hour until c
+// is done.
+func StartTusPurge(c context.Context, dir string, expiry time.Duration) {
+	go func() {
+		ticker := time.NewTicker(time.Hour)
+		defer ticker.Stop()
+		for {
+			if err := PurgeExpiredTusUploads(c, dir, expiry); err != nil {
+				fmt.Fprintf(os.Stderr, "tus: %v\n", err)
+			}
+			select {
+			case <-ticker.C:
+			case <-c.Done():
+				return
+			}
+		}
+	}()
+}
+
+// PurgeExpiredTusUploads deletes the uploads nobody has touched for expiry,
+// with their files, and files in dir older than that which have no upload at
+// all.
+func PurgeExpiredTusUploads(c context.Context, dir string, expiry time.Duration) error {
+	rows, err := config.DB.Query(c,
+		`DELETE FROM tus_uploads WHERE updated_at < NOW() - make_interval(secs => $1) RETURNING id`,
+		expiry.Seconds())
+	if err != nil {
+		return fmt.Errorf("purge uploads: %w", err)
+	}
+	expired, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return fmt.Errorf("purge uploads: %w", err)
+	}
+	for _, id := range expired {
+		if err := os.Remove(tusFilePath(dir, id)); err != nil && !os.IsNotExist(err) {
+			fmt.Fprintf(os.Stderr, "tus: %v\n", err)
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("purge uploads: %w", err)
+	}
+	var stale []string
+	for _, e := range entries {
+		id, ok := strings.CutSuffix(e.Name(), ".bin")
+		if info, err := e.Info(); ok && err == nil && time.Since(info.ModTime()) > expiry {
+			stale = append(stale, id)
+		}
+	}
+	if len(stale) == 0 {
+		return nil
+	}
+	rows, err = config.DB.Query(c, `SELECT id FROM tus_uploads WHERE id = ANY($1)`, stale)
+	if err != nil {
+		return fmt.Errorf("purge uploads: %w", err)
+	}
+	known, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return fmt.Errorf("purge uploads: %w", err)
+	}
+	for _, id := range stale {
+		if !slices.Contains(known, id) {
+			os.Remove(tusFilePath(dir, id))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package helpers
+
+import (
+	"crypto/sha256"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseTusMetadata(t *testing.T) {
+	tests := []struct {
+		header  string
+		want    map[string]string
+		wantErr bool
+	}{
+		{"", map[string]string{}, false},
+		{"   ", map[string]string{}, false},
+		{"filename d29ybGQuanBn", map[string]string{"filename": "world.jpg"}, false},
+		{"filename d29ybGQuanBn,is_confidential", map[string]string{"filename": "world.jpg", "is_confidential": ""}, false},
+		{" note  aGk= , filetype aW1hZ2UvcG5n", map[string]string{"note": "hi", "filetype": "image/png"}, false},
+		{"filename not*base64", nil, true},
+		{"filename d29ybGQ= extra", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseTusMetadata(tt.header)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%q: error %v, want error %v", tt.header, err, tt.wantErr)
+			continue
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("%q: got %v, want %v", tt.header, got, tt.want)
+			continue
+		}
+		for k, v := range tt.want {
+			if got[k] != v {
+				t.Errorf("%q: got %v, want %v", tt.header, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+// brokenReader returns data and then fails, like a dropped connection.
+type brokenReader struct{ r io.Reader }
+
+func (b *brokenReader) Read(p []byte) (int, error) {
+	n, err := b.r.Read(p)
+	if err == io.EOF {
+		return n, errors.New("connection reset")
+	}
+	return n, err
+}
+
+func TestWriteTusFile(t *testing.T) {
+	sha := func(s string) *TusChecksum {
+		sum := sha256.Sum256([]byte(s))
+		return &TusChecksum{Algorithm: "sha256", Sum: sum[:]}
+	}
+	body := func(s string) io.Reader { return strings.NewReader(s) }
+	broken := func(s string) io.Reader { return &brokenReader{strings.NewReader(s)} }
+
+	tests := []struct {
+		name    string
+		body    io.Reader
+		sum     *TusChecksum
+		n       int64
+		file    string
+		wantErr error
+	}{
+		{"appends at the offset", body("def"), nil, 3, "abcdef", nil},
+		{"stops at the length", body("defghij"), nil, 5, "abcdefgh", nil},
+		{"checksum matches", body("def"), sha("def"), 3, "abcdef", nil},
+		{"checksum mismatch is rolled back", body("deX"), sha("def"), 0, "abc", ErrTusChecksumMismatch},
+		{"interrupted chunk is kept", broken("de"), nil, 2, "abcde", errors.New("connection reset")},
+		{"interrupted checked chunk is rolled back", broken("de"), sha("de"), 0, "abc", errors.New("connection reset")},
+		{"empty body", body(""), nil, 0, "abc", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "upload.bin")
+			if err := os.WriteFile(path, []byte("abc"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			n, err := writeTusFile(path, 3, 8, tt.body, tt.sum)
+			switch {
+			case tt.wantErr == nil && err != nil:
+				t.Fatalf("unexpected error %v", err)
+			case tt.wantErr != nil && (err == nil || !strings.Contains(err.Error(), tt.wantErr.Error())):
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+			if n != tt.n {
+				t.Errorf("kept %d bytes, want %d", n, tt.n)
+			}
+			data, _ := os.ReadFile(path)
+			if string(data) != tt.file {
+				t.Errorf("file holds %q, want %q", data, tt.file)
+			}
+		})
+	}
+}
+
+func TestWriteTusChunkKeepsOffsetOnMismatch(t *testing.T) {
+	dir := t.TempDir()
+	u := &TusUpload{ID: "abc123", Length: 8, Offset: 3}
+	if err := os.WriteFile(tusFilePath(dir, u.ID), []byte("abc"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	err := WriteTusChunk(dir, u, strings.NewReader("xyz"), &TusChecksum{Algorithm: "md5", Sum: []byte("nope")})
+	if !errors.Is(err, ErrTusChecksumMismatch) {
+		t.Fatalf("got %v, want ErrTusChecksumMismatch", err)
+	}
+	if u.Offset != 3 {
+		t.Errorf("offset moved to %d", u.Offset)
+	}
+
+	if err := WriteTusChunk(dir, &TusUpload{ID: "missing", Length: 8}, strings.NewReader("x"), nil); err == nil {
+		t.Error("writing to a missing upload succeeded")
+	}
+}
@@ -0,0 +1,169 @@
+package helpers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/gabriel-vasile/mimetype"
+	_ "golang.org/x/image/webp"
+)
+
+var (
+	ErrUploadTooLarge  = errors.New("upload exceeds maximum size")
+	ErrUnsupportedType = errors.New("unsupported file type")
+)
+
+// allowedMimeTypes maps every accepted upload type to the extension it is
+// stored under, whatever the client called the file.
+var allowedMimeTypes = map[string]string{
+	"image/jpeg":       ".jpg",
+	"image/png":        ".png",
+	"image/heic":       ".heic",
+	"image/heif":       ".heif",
+	"image/webp":       ".webp",
+	"image/gif":        ".gif",
+	"video/mp4":        ".mp4",
+	"video/quicktime":  ".mov",
+	"video/webm":       ".webm",
+	"video/x-matroska": ".mkv",
+	"video/x-m4v":      ".m4v",
+	"video/3gpp":       ".3gp",
+	"video/3gpp2":      ".3g2",
+}
+
+var unsafeFilenameChars = regexp.MustCompile(`[^A-Za-z0-9_-]+`)
+
+type MediaInfo struct {
+	MimeType string
+	Size     int64
+	Width    int
+	Height   int
+	Hash     string
+
+	DurationMs int64
+	Codec      string
+	Poster     string
+	Keyframes  []string
+
+	// City and Country are reverse geocoded from the EXIF position.
+	City    string
+	Country string
+}
+
+func (i MediaInfo) MediaType() string {
+	if strings.HasPrefix(i.MimeType, "video/") {
+		return "video"
+	}
+	return "image"
+}
+
+// MaxRequestBytes bounds the body of a request carrying up to files uploads
+// of maxUpload bytes, plus room for the multipart framing and form fields.
+func MaxRequestBytes(maxUpload int64, files int) int64 {
+	return int64(files)*maxUpload + 1<<20
+}
+
+// StreamToTemp copies r into a temp file without holding it in memory and
+// gives up as soon as more than max bytes have arrived.
+func StreamToTemp(r io.Reader, max int64) (string, error) {
+	f, err := os.CreateTemp("", "memora-upload-*")
+	if err != nil {
+		return "", fmt.Errorf("create temp file: %w", err)
+	}
+
+	n, err := io.Copy(f, io.LimitReader(r, max+1))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", fmt.Errorf("write temp file: %w", err)
+	}
+	if n > max {
+		os.Remove(f.Name())
+		return "", ErrUploadTooLarge
+	}
+	return f.Name(), nil
+}
+
+// InspectMedia identifies a file by its magic bytes and rejects anything
+// outside the upload allowlist.
+func InspectMedia(path string) (MediaInfo, error) {
+	mtype, err := mimetype.DetectFile(path)
+	if err != nil {
+		return MediaInfo{}, fmt.Errorf("detect type: %w", err)
+	}
+
+	var info MediaInfo
+	for allowed := range allowedMimeTypes {
+		if mtype.Is(allowed) {
+			info.MimeType = allowed
+			break
+		}
+	}
+	if info.MimeType == "" {
+		return MediaInfo{}, fmt.Errorf("%w: %s", ErrUnsupportedType, mtype.String())
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return MediaInfo{}, fmt.Errorf("open file: %w", err)
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if info.Size, err = io.Copy(h, f); err != nil {
+		return MediaInfo{}, fmt.Errorf("hash file: %w", err)
+	}
+	info.Hash = hex.EncodeToString(h.Sum(nil))
+
+	// Dimensions are best effort: a file that passed the type check is kept
+	// even when they can't be read.
+	if _, err := f.Seek(0, io.SeekStart); err == nil {
+		switch info.MimeType {
+		case "image/heic", "image/heif":
+			info.Width, info.Height, _ = heifSize(f)
+		default:
+			if cfg, _, err := image.DecodeConfig(f); err == nil {
+				info.Width, info.Height = cfg.Width, cfg.Height
+			}
+		}
+	}
+
+	return info, nil
+}
+
+// SanitizeFilename reduces a client supplied name to a safe base name and
+// swaps its extension for the one matching the detected type.
+func SanitizeFilename(name, mimeType string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	ext := filepath.Ext(name)
+	base := strings.Trim(unsafeFilenameChars.ReplaceAllString(strings.TrimSuffix(name, ext), "_"), "_")
+	if len(base) > 64 {
+		base = base[:64]
+	}
+	if base == "" {
+		base = "photo"
+	}
+
+	if known, ok := allowedMimeTypes[mimeType]; ok {
+		ext = known
+	} else {
+		ext = "." + unsafeFilenameChars.ReplaceAllString(strings.TrimPrefix(ext, "."), "")
+		if ext == "." {
+			ext = ""
+		}
+	}
+	return base + strings.ToLower(ext)
+}
@@ -0,0 +1,178 @@
+package helpers
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSanitizeFilename(t *testing.T) {
+	tests := []struct {
+		name, mimeType, want string
+	}{
+		{"holiday.JPG", "image/jpeg", "holiday.jpg"},
+		{"photo.exe", "image/jpeg", "photo.jpg"},
+		{"clip.mp4", "video/quicktime", "clip.mov"},
+		{"../../etc/passwd", "image/png", "passwd.png"},
+		{`C:\Users\me\cat pic!.png`, "image/png", "cat_pic.png"},
+		{"", "image/gif", "photo.gif"},
+		{"...", "", "photo"},
+		{"__x__.PNG", "", "x.png"},
+		{"notes.t*x t", "text/plain", "notes.txt"},
+		{"noext", "application/octet-stream", "noext"},
+		{strings.Repeat("a", 100) + ".png", "image/png", strings.Repeat("a", 64) + ".png"},
+	}
+	for _, tt := range tests {
+		if got := SanitizeFilename(tt.name, tt.mimeType); got != tt.want {
+			t.Errorf("SanitizeFilename(%q, %q) = %q, want %q", tt.name, tt.mimeType, got, tt.want)
+		}
+	}
+}
+
+func TestInspectMedia(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 3, 2))
+	encoded := func(encode func(io.Writer, image.Image) error) []byte {
+		var b bytes.Buffer
+		if err := encode(&b, img); err != nil {
+			t.Fatal(err)
+		}
+		return b.Bytes()
+	}
+
+	tests := []struct {
+		name          string
+		data          []byte
+		mimeType      string
+		width, height int
+		wantErr       error
+	}{
+		{"png", encoded(png.Encode), "image/png", 3, 2, nil},
+		{"jpeg", encoded(func(w io.Writer, m image.Image) error { return jpeg.Encode(w, m, nil) }), "image/jpeg", 3, 2, nil},
+		{"gif", encoded(func(w io.Writer, m image.Image) error { return gif.Encode(w, m, nil) }), "image/gif", 3, 2, nil},
+		{"webp", webpLossless(3, 2), "image/webp", 3, 2, nil},
+		{"heic", testHEIF("heic", 1, 4032, 3024, -1), "image/heic", 4032, 3024, nil},
+		// Dimensions that can't be read don't reject an allowed type.
+		{"heic without size", testHEIF("heic", 1, 0, 0, -1), "image/heic", 0, 0, nil},
+		// The extension and content type a client claims don't matter.
+		{"script", []byte("#!/bin/sh\nrm -rf /\n"), "", 0, 0, ErrUnsupportedType},
+		{"html", []byte("<html><body>hi</body></html>"), "", 0, 0, ErrUnsupportedType},
+		{"empty", nil, "", 0, 0, ErrUnsupportedType},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "upload.jpg")
+			if err := os.WriteFile(path, tt.data, 0644); err != nil {
+				t.Fatal(err)
+			}
+			info, err := InspectMedia(path)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if info.MimeType != tt.mimeType || info.Width != tt.width || info.Height != tt.height {
+				t.Errorf("got %s %dx%d, want %s %dx%d", info.MimeType, info.Width, info.Height, tt.mimeType, tt.width, tt.height)
+			}
+			if info.Size != int64(len(tt.data)) || len(info.Hash) != 64 {
+				t.Errorf("size %d, hash %q", info.Size, info.Hash)
+			}
+		})
+	}
+}
+
+// webpLossless returns the header of a lossless WebP image, which is all
+// that is read for its size.
+func webpLossless(width, height int) []byte {
+	var vp8l [5]byte
+	vp8l[0] = 0x2f
+	binary.LittleEndian.PutUint32(vp8l[1:], uint32(width-1)|uint32(height-1)<<14)
+
+	var b bytes.Buffer
+	b.WriteString("RIFF")
+	binary.Write(&b, binary.LittleEndian, uint32(4+8+len(vp8l)+1))
+	b.WriteString("WEBPVP8L")
+	binary.Write(&b, binary.LittleEndian, uint32(len(vp8l)))
+	b.Write(vp8l[:])
+	b.WriteByte(0)
+	return b.Bytes()
+}
+
+// isoBox encodes an ISO BMFF box; a full box gets its version and flags
+// passed as the first bytes of body.
+func isoBox(kind string, body ...[]byte) []byte {
+	payload := bytes.Join(body, nil)
+	b := binary.BigEndian.AppendUint32(nil, uint32(8+len(payload)))
+	return append(append(b, kind...), payload...)
+}
+
+// testHEIF returns the boxes of a HEIF file with a 160x120 thumbnail as
+// item 2. Item primary gets a size unless width is 0, and is turned by
+// rotation quarter turns unless that is negative.
+func testHEIF(brand string, primary uint16, width, height uint32, rotation int) []byte {
+	u16 := func(v uint16) []byte { return binary.BigEndian.AppendUint16(nil, v) }
+	u32 := func(v uint32) []byte { return binary.BigEndian.AppendUint32(nil, v) }
+	ispe := func(w, h uint32) []byte { return isoBox("ispe", u32(0), u32(w), u32(h)) }
+
+	// Properties 1 and 2 are the thumbnail's size and the shared hvcC.
+	props := [][]byte{ispe(160, 120), isoBox("hvcC", []byte{1})}
+	assoc := []byte{0x82} // essential hvcC
+	if width != 0 {
+		props = append(props, ispe(width, height))
+		assoc = append(assoc, byte(len(props)))
+	}
+	if rotation >= 0 {
+		props = append(props, isoBox("irot", []byte{byte(rotation)}))
+		assoc = append(assoc, 0x80|byte(len(props)))
+	}
+	ipma := isoBox("ipma", u32(0), u32(2),
+		u16(2), []byte{2, 1, 0x82},
+		u16(primary), []byte{byte(len(assoc))}, assoc)
+
+	return bytes.Join([][]byte{
+		isoBox("ftyp", []byte(brand), u32(0), []byte("mif1"+brand)),
+		isoBox("meta", u32(0),
+			isoBox("hdlr", u32(0), u32(0), []byte("pict"), make([]byte, 13)),
+			isoBox("pitm", u32(0), u16(primary)),
+			isoBox("iprp", isoBox("ipco", props...), ipma),
+		),
+		isoBox("mdat", make([]byte, 32)),
+	}, nil)
+}
+
+func TestHEIFSize(t *testing.T) {
+	tests := []struct {
+		name          string
+		data          []byte
+		width, height int
+		wantErr       bool
+	}{
+		{"primary item", testHEIF("heic", 1, 4032, 3024, -1), 4032, 3024, false},
+		{"not rotated", testHEIF("heic", 1, 4032, 3024, 0), 4032, 3024, false},
+		{"quarter turn", testHEIF("heic", 1, 4032, 3024, 1), 3024, 4032, false},
+		{"half turn", testHEIF("heic", 1, 4032, 3024, 2), 4032, 3024, false},
+		{"thumbnail is primary", testHEIF("heif", 2, 4032, 3024, -1), 160, 120, false},
+		{"no size", testHEIF("heic", 1, 0, 0, 1), 0, 0, true},
+		{"not heif", []byte("\x00\x00\x00\x08free"), 0, 0, true},
+		{"truncated", testHEIF("heic", 1, 4032, 3024, -1)[:60], 0, 0, true},
+	}
+	for _, tt := range tests {
+		w, h, err := heifSize(bytes.NewReader(tt.data))
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: got error %v, want error %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if w != tt.width || h != tt.height {
+			t.Errorf("%s: got %dx%d, want %dx%d", tt.name, w, h, tt.width, tt.height)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package helpers
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/Pranjal095/Memora/backend/config"
+	"github.com/Pranjal095/Memora/backend/internal/schema"
+	"github.com/Pranjal095/Memora/backend/internal/vectorindex"
+)
+
+var ErrInvalidUserID = errors.New("user id must be a positive integer")
+
+var (
+	vectors     vectorindex.VectorIndex
+	vectorsName string
+)
+
+// OpenVectors sets up the index selected by VECTOR_BACKEND. It must be
+// called after config.ConnectPSQL and before Vectors.
+func OpenVectors(cfg *config.Config) {
+	switch cfg.VectorBackend {
+	case "memory":
+		vectors, vectorsName = vectorindex.NewMemory(), "memory"
+	case "pgvector":
+		vectors, vectorsName = vectorindex.NewPgVector(config.DB, cfg.PgVectorTable), cfg.PgVectorTable
+	default:
+		vectors, vectorsName = NewQdrantIndex(cfg, cfg.QdrantCollection), cfg.QdrantCollection
+	}
+}
+
+// Vectors returns the index set up by OpenVectors.
+func Vectors() vectorindex.VectorIndex {
+	return vectors
+}
+
+func NewQdrantIndex(cfg *config.Config, collection string) *vectorindex.Qdrant {
+	return vectorindex.NewQdrant(cfg.QdrantURL, collection, cfg.QdrantAPIKey)
+}
+
+// parseUserID reads a user id for a vector payload or query. A zero user id
+// in a vectorindex.Filter means no user filter at all, so anything that isn't
+// a positive integer fails with ErrInvalidUserID rather than widening a
+// query to every user's photos.
+func parseUserID(userID string) (int64, error) {
+	id, err := strconv.ParseInt(userID, 10, 64)
+	if err != nil || id <= 0 {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidUserID, userID)
+	}
+	return id, nil
+}
+
+// vectorFilter scopes f to the user for a vector query.
+func vectorFilter(userID string, f schema.SearchFilters) (vectorindex.Filter, error) {
+	uid, err := parseUserID(userID)
+	if err != nil {
+		return vectorindex.Filter{}, err
+	}
+	return vectorindex.Filter{
+		UserID:    uid,
+		From:      f.From,
+		To:        f.To,
+		City:      f.City,
+		Country:   f.Country,
+		MediaType: f.MediaType,
+		HasNote:   f.HasNote,
+	}, nil
+}
@@ -0,0 +1,24 @@
+package helpers
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Pranjal095/Memora/backend/internal/schema"
+)
+
+func TestVectorFilterRejectsInvalidUsers(t *testing.T) {
+	for _, userID := range []string{"", "0", "-3", "abc", "12abc"} {
+		if _, err := vectorFilter(userID, schema.SearchFilters{}); !errors.Is(err, ErrInvalidUserID) {
+			t.Errorf("vectorFilter(%q): got %v, want ErrInvalidUserID", userID, err)
+		}
+	}
+
+	f, err := vectorFilter("42", schema.SearchFilters{City: "Lisbon"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.UserID != 42 || f.City != "Lisbon" {
+		t.Errorf("got %+v", f)
+	}
+}
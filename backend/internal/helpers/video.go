@@ -0,0 +1,259 @@
+package helpers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type ffprobeOutput struct {
+	Streams []struct {
+		CodecType    string            `json:"codec_type"`
+		CodecName    string            `json:"codec_name"`
+		Width        int               `json:"width"`
+		Height       int               `json:"height"`
+		Duration     string            `json:"duration"`
+		Tags         map[string]string `json:"tags"`
+		SideDataList []struct {
+			Rotation float64 `json:"rotation"`
+		} `json:"side_data_list"`
+	} `json:"streams"`
+	Format struct {
+		Duration string `json:"duration"`
+	} `json:"format"`
+}
+
+type VideoInfo struct {
+	DurationMs int64
+	Width      int
+	Height     int
+	Codec      string
+}
+
+// PrepareVideo probes a stored video and renders its poster and keyframes,
+// filling in the video fields of info with up to keyframes frames. It gives
+// up after timeout or when c is done.
+func PrepareVideo(c context.Context, path string, keyframes int, timeout time.Duration, info *MediaInfo) error {
+	c, cancel := context.WithTimeout(c, timeout)
+	defer cancel()
+
+	video, err := ProbeVideo(c, path)
+	if err != nil {
+		return err
+	}
+
+	poster, frames, err := ExtractVideoFrames(c, path, video.DurationMs, keyframes)
+	if err != nil {
+		return err
+	}
+
+	info.Width, info.Height = video.Width, video.Height
+	info.DurationMs = video.DurationMs
+	info.Codec = video.Codec
+	info.Poster = poster
+	info.Keyframes = frames
+	return nil
+}
+
+func ProbeVideo(c context.Context, path string) (VideoInfo, error) {
+	out, err := exec.CommandContext(c,
+		"ffprobe", "-v", "error",
+		"-print_format", "json",
+		"-show_format", "-show_streams",
+		path,
+	).Output()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return VideoInfo{}, fmt.Errorf("%w: unreadable video", ErrUnsupportedType)
+	}
+	if err != nil {
+		return VideoInfo{}, fmt.Errorf("ffprobe failed: %w", err)
+	}
+
+	var probe ffprobeOutput
+	if err := json.Unmarshal(out, &probe); err != nil {
+		return VideoInfo{}, fmt.Errorf("invalid ffprobe output: %w", err)
+	}
+
+	for _, s := range probe.Streams {
+		if s.CodecType != "video" {
+			continue
+		}
+		info := VideoInfo{Width: s.Width, Height: s.Height, Codec: s.CodecName}
+
+		rotation, _ := strconv.ParseFloat(s.Tags["rotate"], 64)
+		for _, sd := range s.SideDataList {
+			if sd.Rotation != 0 {
+				rotation = sd.Rotation
+			}
+		}
+		if int(math.Abs(rotation))%180 == 90 {
+			info.Width, info.Height = info.Height, info.Width
+		}
+
+		duration := s.Duration
+		if duration == "" {
+			duration = probe.Format.Duration
+		}
+		if secs, err := strconv.ParseFloat(duration, 64); err == nil {
+			info.DurationMs = int64(secs * 1000)
+		}
+		return info, nil
+	}
+	return VideoInfo{}, fmt.Errorf("%w: no video stream", ErrUnsupportedType)
+}
+
+func extractFrame(c context.Context, src, dst string, atMs int64) error {
+	cmd := exec.CommandContext(c,
+		"ffmpeg", "-y",
+		"-ss", fmt.Sprintf("%.3f", float64(atMs)/1000),
+		"-i", src,
+		"-frames:v", "1",
+		"-q:v", "2",
+		dst,
+	)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("frame extraction failed: %s", out)
+	}
+	return nil
+}
+
+// ExtractVideoFrames writes a poster frame and up to n keyframes next to
+// the stored file, returning their paths. The keyframes are the I-frames
+// nearest to n points spread evenly over the video.
+func ExtractVideoFrames(c context.Context, path string, durationMs int64, n int) (string, []string, error) {
+	base := strings.TrimSuffix(path, filepath.Ext(path))
+
+	poster := base + "_poster.jpg"
+	if err := extractFrame(c, path, poster, min(1000, durationMs/2)); err != nil {
+		return "", nil, err
+	}
+
+	times, err := videoKeyframeTimes(c, path)
+	if err != nil {
+		os.Remove(poster)
+		return "", nil, err
+	}
+
+	keyframes := make([]string, 0, n)
+	for i, at := range pickKeyframes(times, durationMs, n) {
+		dst := fmt.Sprintf("%s_kf%d.jpg", base, i)
+		if err := extractFrame(c, path, dst, at); err != nil {
+			RemoveVideoFrames(poster, keyframes)
+			return "", nil, err
+		}
+		keyframes = append(keyframes, dst)
+	}
+	return poster, keyframes, nil
+}
+
+// videoKeyframeTimes lists when the I-frames of the first video stream
+// start, in milliseconds from the start of the video. It reads the packet
+// flags, so nothing is decoded.
+func videoKeyframeTimes(c context.Context, path string) ([]int64, error) {
+	out, err := exec.CommandContext(c,
+		"ffprobe", "-v", "error",
+		"-select_streams", "v:0",
+		"-show_entries", "packet=pts_time,flags:format=start_time",
+		"-print_format", "json",
+		path,
+	).Output()
+	if err != nil {
+		return nil, fmt.Errorf("listing keyframes failed: %w", err)
+	}
+	return parseKeyframeTimes(out)
+}
+
+// parseKeyframeTimes reads the keyframe packets out of ffprobe's JSON.
+// Times are made relative to the start time, which is how ffmpeg -ss
+// counts.
+func parseKeyframeTimes(out []byte) ([]int64, error) {
+	var probe struct {
+		Packets []struct {
+			PTSTime string `json:"pts_time"`
+			Flags   string `json:"flags"`
+		} `json:"packets"`
+		Format struct {
+			StartTime string `json:"start_time"`
+		} `json:"format"`
+	}
+	if err := json.Unmarshal(out, &probe); err != nil {
+		return nil, fmt.Errorf("invalid ffprobe output: %w", err)
+	}
+	start, _ := strconv.ParseFloat(probe.Format.StartTime, 64)
+
+	var times []int64
+	for _, p := range probe.Packets {
+		if !strings.Contains(p.Flags, "K") {
+			continue
+		}
+		// Packets without a timestamp report N/A.
+		pts, err := strconv.ParseFloat(p.PTSTime, 64)
+		if err != nil {
+			continue
+		}
+		times = append(times, max(0, int64((pts-start)*1000)))
+	}
+	slices.Sort(times)
+	return slices.Compact(times), nil
+}
+
+// pickKeyframes chooses up to n of the sorted keyframe times, each the
+// nearest to its share of the video that keeps them in order. A video with
+// no keyframes listed falls back to the evenly spaced points themselves.
+func pickKeyframes(times []int64, durationMs int64, n int) []int64 {
+	target := func(i int) int64 {
+		return int64((float64(i) + 0.5) * float64(durationMs) / float64(n))
+	}
+	if len(times) == 0 {
+		picked := make([]int64, n)
+		for i := range picked {
+			picked[i] = target(i)
+		}
+		return picked
+	}
+	if len(times) <= n {
+		return slices.Clone(times)
+	}
+
+	picked := make([]int64, 0, n)
+	next := 0
+	for i := 0; i < n; i++ {
+		// Leave a keyframe for each of the points still to come.
+		last := len(times) - (n - i)
+		best := next
+		for j := next + 1; j <= last; j++ {
+			if abs(times[j]-target(i)) < abs(times[best]-target(i)) {
+				best = j
+			}
+		}
+		picked = append(picked, times[best])
+		next = best + 1
+	}
+	return picked
+}
+
+func abs(v int64) int64 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+func RemoveVideoFrames(poster string, keyframes []string) {
+	if poster != "" {
+		os.Remove(poster)
+	}
+	for _, kf := range keyframes {
+		os.Remove(kf)
+	}
+}
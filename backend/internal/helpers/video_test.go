@@ -0,0 +1,54 @@
+package helpers
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseKeyframeTimes(t *testing.T) {
+	out := []byte(`{
+		"packets": [
+			{"pts_time": "1.400000", "flags": "K__"},
+			{"pts_time": "1.433333", "flags": "___"},
+			{"pts_time": "N/A", "flags": "K__"},
+			{"pts_time": "3.400000", "flags": "K_D"},
+			{"pts_time": "1.400000", "flags": "K__"},
+			{"pts_time": "1.350000", "flags": "K__"}
+		],
+		"format": {"start_time": "1.400000"}
+	}`)
+	got, err := parseKeyframeTimes(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Times count from the start time; one before it is clamped to 0.
+	if want := []int64{0, 2000}; !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	if _, err := parseKeyframeTimes([]byte("not json")); err == nil {
+		t.Error("invalid output was accepted")
+	}
+}
+
+func TestPickKeyframes(t *testing.T) {
+	tests := []struct {
+		name     string
+		times    []int64
+		duration int64
+		n        int
+		want     []int64
+	}{
+		// Targets are 1000, 3000, 5000 and 7000 ms.
+		{"nearest", []int64{0, 2000, 4000, 6000, 8000}, 8000, 4, []int64{0, 2000, 4000, 6000}},
+		{"one per gop", []int64{0, 900, 1100, 2900, 5100, 6900, 7900}, 8000, 4, []int64{900, 2900, 5100, 6900}},
+		{"clustered at the start", []int64{0, 100, 200, 300, 7900}, 8000, 4, []int64{100, 200, 300, 7900}},
+		{"no more than there are", []int64{0, 4000}, 8000, 4, []int64{0, 4000}},
+		{"none listed", nil, 8000, 4, []int64{1000, 3000, 5000, 7000}},
+	}
+	for _, tt := range tests {
+		if got := pickKeyframes(tt.times, tt.duration, tt.n); !slices.Equal(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
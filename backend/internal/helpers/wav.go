@@ -0,0 +1,139 @@
+package helpers
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// wavFile describes the PCM data of a WAV file as produced by the ffmpeg
+// normalization step, so windows of it can be copied out without decoding.
+type wavFile struct {
+	path       string
+	channels   uint16
+	sampleRate uint32
+	bits       uint16
+	dataOffset int64
+	dataSize   int64
+}
+
+func openWAV(path string) (*wavFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open wav: %w", err)
+	}
+	defer f.Close()
+
+	var riff [12]byte
+	if _, err := io.ReadFull(f, riff[:]); err != nil {
+		return nil, fmt.Errorf("read wav header: %w", err)
+	}
+	if string(riff[0:4]) != "RIFF" || string(riff[8:12]) != "WAVE" {
+		return nil, errors.New("not a WAV file")
+	}
+
+	w := &wavFile{path: path}
+	offset := int64(12)
+	for {
+		var hdr [8]byte
+		if _, err := io.ReadFull(f, hdr[:]); err != nil {
+			return nil, fmt.Errorf("read wav chunk: %w", err)
+		}
+		id, size := string(hdr[0:4]), int64(binary.LittleEndian.Uint32(hdr[4:8]))
+		offset += 8
+
+		switch id {
+		case "fmt ":
+			var fmtChunk [16]byte
+			if _, err := io.ReadFull(f, fmtChunk[:]); err != nil {
+				return nil, fmt.Errorf("read wav format: %w", err)
+			}
+			if binary.LittleEndian.Uint16(fmtChunk[0:2]) != 1 {
+				return nil, errors.New("WAV is not PCM")
+			}
+			w.channels = binary.LittleEndian.Uint16(fmtChunk[2:4])
+			w.sampleRate = binary.LittleEndian.Uint32(fmtChunk[4:8])
+			w.bits = binary.LittleEndian.Uint16(fmtChunk[14:16])
+		case "data":
+			if w.sampleRate == 0 {
+				return nil, errors.New("WAV data before format chunk")
+			}
+			w.dataOffset = offset
+			w.dataSize = size
+			// ffmpeg writes a placeholder size when streaming; trust the file.
+			if st, err := f.Stat(); err == nil && (size == 0 || size == 0xFFFFFFFF || offset+size > st.Size()) {
+				w.dataSize = st.Size() - offset
+			}
+			return w, nil
+		}
+
+		offset += size + size%2
+		if _, err := f.Seek(offset, io.SeekStart); err != nil {
+			return nil, fmt.Errorf("seek wav: %w", err)
+		}
+	}
+}
+
+func (w *wavFile) blockAlign() int64 {
+	return int64(w.channels) * int64(w.bits/8)
+}
+
+func (w *wavFile) Duration() time.Duration {
+	frames := w.dataSize / w.blockAlign()
+	return time.Duration(frames) * time.Second / time.Duration(w.sampleRate)
+}
+
+// WriteWindow copies the samples in [start, start+length) into a standalone
+// WAV file at dst.
+func (w *wavFile) WriteWindow(dst string, start, length time.Duration) error {
+	align := w.blockAlign()
+	from := int64(start) * int64(w.sampleRate) / int64(time.Second) * align
+	n := int64(length) * int64(w.sampleRate) / int64(time.Second) * align
+	if from+n > w.dataSize {
+		n = w.dataSize - from
+	}
+	if n <= 0 {
+		return errors.New("window is outside the audio")
+	}
+
+	src, err := os.Open(w.path)
+	if err != nil {
+		return fmt.Errorf("open wav: %w", err)
+	}
+	defer src.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return fmt.Errorf("create window: %w", err)
+	}
+
+	hdr := make([]byte, 44)
+	copy(hdr[0:4], "RIFF")
+	binary.LittleEndian.PutUint32(hdr[4:8], uint32(36+n))
+	copy(hdr[8:16], "WAVEfmt ")
+	binary.LittleEndian.PutUint32(hdr[16:20], 16)
+	binary.LittleEndian.PutUint16(hdr[20:22], 1)
+	binary.LittleEndian.PutUint16(hdr[22:24], w.channels)
+	binary.LittleEndian.PutUint32(hdr[24:28], w.sampleRate)
+	binary.LittleEndian.PutUint32(hdr[28:32], w.sampleRate*uint32(align))
+	binary.LittleEndian.PutUint16(hdr[32:34], uint16(align))
+	binary.LittleEndian.PutUint16(hdr[34:36], w.bits)
+	copy(hdr[36:40], "data")
+	binary.LittleEndian.PutUint32(hdr[40:44], uint32(n))
+
+	_, err = out.Write(hdr)
+	if err == nil {
+		_, err = io.Copy(out, io.NewSectionReader(src, w.dataOffset+from, n))
+	}
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(dst)
+		return fmt.Errorf("write window: %w", err)
+	}
+	return nil
+}
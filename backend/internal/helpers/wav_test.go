@@ -0,0 +1,148 @@
+package helpers
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// wavChunk is one RIFF chunk of a test WAV file.
+type wavChunk struct {
+	id   string
+	data []byte
+	// size overrides the recorded size, like ffmpeg's streaming placeholder.
+	size *uint32
+}
+
+func pcmFormat(format, channels uint16, rate uint32, bits uint16) wavChunk {
+	b := make([]byte, 16)
+	binary.LittleEndian.PutUint16(b[0:2], format)
+	binary.LittleEndian.PutUint16(b[2:4], channels)
+	binary.LittleEndian.PutUint32(b[4:8], rate)
+	binary.LittleEndian.PutUint32(b[8:12], rate*uint32(channels*bits/8))
+	binary.LittleEndian.PutUint16(b[12:14], channels*bits/8)
+	binary.LittleEndian.PutUint16(b[14:16], bits)
+	return wavChunk{id: "fmt ", data: b}
+}
+
+func writeTestWAV(t *testing.T, chunks ...wavChunk) string {
+	t.Helper()
+	var body bytes.Buffer
+	body.WriteString("WAVE")
+	for _, c := range chunks {
+		size := uint32(len(c.data))
+		if c.size != nil {
+			size = *c.size
+		}
+		body.WriteString(c.id)
+		binary.Write(&body, binary.LittleEndian, size)
+		body.Write(c.data)
+		if len(c.data)%2 == 1 {
+			body.WriteByte(0)
+		}
+	}
+	var file bytes.Buffer
+	file.WriteString("RIFF")
+	binary.Write(&file, binary.LittleEndian, uint32(body.Len()))
+	file.Write(body.Bytes())
+
+	path := filepath.Join(t.TempDir(), "audio.wav")
+	if err := os.WriteFile(path, file.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// samples returns n 16-bit mono frames counting up from zero.
+func samples(n int) []byte {
+	b := make([]byte, 2*n)
+	for i := 0; i < n; i++ {
+		binary.LittleEndian.PutUint16(b[2*i:], uint16(i))
+	}
+	return b
+}
+
+func TestOpenWAV(t *testing.T) {
+	placeholder := uint32(0xFFFFFFFF)
+	zero := uint32(0)
+	data := samples(2500)
+
+	tests := []struct {
+		name     string
+		chunks   []wavChunk
+		duration time.Duration
+		wantErr  bool
+	}{
+		{"plain", []wavChunk{pcmFormat(1, 1, 1000, 16), {id: "data", data: data}}, 2500 * time.Millisecond, false},
+		{"stereo", []wavChunk{pcmFormat(1, 2, 1000, 16), {id: "data", data: data}}, 1250 * time.Millisecond, false},
+		{"odd sized chunk before data", []wavChunk{pcmFormat(1, 1, 1000, 16), {id: "LIST", data: []byte("abc")}, {id: "data", data: data}},
+			2500 * time.Millisecond, false},
+		{"streaming placeholder size", []wavChunk{pcmFormat(1, 1, 1000, 16), {id: "data", data: data, size: &placeholder}}, 2500 * time.Millisecond, false},
+		{"zero size", []wavChunk{pcmFormat(1, 1, 1000, 16), {id: "data", data: data, size: &zero}}, 2500 * time.Millisecond, false},
+		{"not PCM", []wavChunk{pcmFormat(3, 1, 1000, 32), {id: "data", data: data}}, 0, true},
+		{"data before format", []wavChunk{{id: "data", data: data}, pcmFormat(1, 1, 1000, 16)}, 0, true},
+		{"no data", []wavChunk{pcmFormat(1, 1, 1000, 16)}, 0, true},
+	}
+	for _, tt := range tests {
+		w, err := openWAV(writeTestWAV(t, tt.chunks...))
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: error %v, want error %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if err == nil && w.Duration() != tt.duration {
+			t.Errorf("%s: duration %s, want %s", tt.name, w.Duration(), tt.duration)
+		}
+	}
+
+	notWAV := filepath.Join(t.TempDir(), "audio.mp3")
+	os.WriteFile(notWAV, []byte("ID3\x04\x00\x00\x00\x00\x00\x00\x00\x00"), 0644)
+	if _, err := openWAV(notWAV); err == nil {
+		t.Error("opened an mp3 as WAV")
+	}
+}
+
+func TestWAVWriteWindow(t *testing.T) {
+	data := samples(2500)
+	w, err := openWAV(writeTestWAV(t, pcmFormat(1, 1, 1000, 16), wavChunk{id: "LIST", data: []byte("x")}, wavChunk{id: "data", data: data}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name          string
+		start, length time.Duration
+		from, to      int // frames
+		wantErr       bool
+	}{
+		{"start", 0, time.Second, 0, 1000, false},
+		{"middle", time.Second, 500 * time.Millisecond, 1000, 1500, false},
+		{"cut short at the end", 2 * time.Second, time.Second, 2000, 2500, false},
+		{"past the end", 3 * time.Second, time.Second, 0, 0, true},
+	}
+	for _, tt := range tests {
+		dst := filepath.Join(t.TempDir(), "window.wav")
+		err := w.WriteWindow(dst, tt.start, tt.length)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: error %v, want error %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+
+		win, err := openWAV(dst)
+		if err != nil {
+			t.Fatalf("%s: the window isn't a valid WAV: %v", tt.name, err)
+		}
+		if win.channels != 1 || win.sampleRate != 1000 || win.bits != 16 {
+			t.Errorf("%s: format %d ch, %d Hz, %d bits", tt.name, win.channels, win.sampleRate, win.bits)
+		}
+		raw, _ := os.ReadFile(dst)
+		if got := raw[win.dataOffset:]; !bytes.Equal(got, data[2*tt.from:2*tt.to]) {
+			t.Errorf("%s: got %d bytes of samples, want frames %d to %d", tt.name, len(got), tt.from, tt.to)
+		}
+	}
+}
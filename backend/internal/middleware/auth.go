@@ -3,16 +3,16 @@ package middleware
 import (
 	"fmt"
 	"net/http"
-	"os"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt"
 )
 
-var jwtKey = []byte(os.Getenv("JWT_SECRET"))
-
-func AuthMiddleware() gin.HandlerFunc {
+// AuthMiddleware accepts requests carrying a JWT signed with secret and
+// sets userID to its subject.
+func AuthMiddleware(secret string) gin.HandlerFunc {
+	key := []byte(secret)
 	return func(c *gin.Context) {
 		auth := c.GetHeader("Authorization")
 		parts := strings.SplitN(auth, " ", 2)
@@ -27,7 +27,7 @@ func AuthMiddleware() gin.HandlerFunc {
 			if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
 				return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
 			}
-			return jwtKey, nil
+			return key, nil
 		})
 		if err != nil || !token.Valid {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid or expired token"})
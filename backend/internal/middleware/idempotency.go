@@ -0,0 +1,149 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Pranjal095/Memora/backend/internal/helpers"
+)
+
+type recordingWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *recordingWriter) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *recordingWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// SmallBodyBytes is the body limit for IdempotencyMiddleware on routes that
+// take JSON or no body at all.
+const SmallBodyBytes = 16 << 10
+
+// IdempotencyMiddleware replays the stored response, headers included, when a
+// request is retried with the same Idempotency-Key and payload. It must run after
+// AuthMiddleware on authenticated routes so keys are scoped per user;
+// anonymous requests share the user id 0. Bodies of keyed requests over
+// maxBody bytes are rejected before they are spooled any further.
+func IdempotencyMiddleware(maxBody int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader("Idempotency-Key")
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > 255 {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Idempotency-Key is too long"})
+			return
+		}
+
+		userID := c.GetString("userID")
+		if userID == "" {
+			userID = "0"
+		}
+
+		// The body is spooled to disk so it can be hashed and then handed to
+		// the handler without holding uploads in memory.
+		spool, err := helpers.StreamToTemp(c.Request.Body, maxBody)
+		if err != nil {
+			status := http.StatusBadRequest
+			if errors.Is(err, helpers.ErrUploadTooLarge) {
+				status = http.StatusRequestEntityTooLarge
+			}
+			c.AbortWithStatusJSON(status, gin.H{"error": err.Error()})
+			return
+		}
+		defer os.Remove(spool)
+
+		f, err := os.Open(spool)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "could not read request"})
+			return
+		}
+		defer f.Close()
+
+		hash, err := helpers.HashRequest(c.Request.Method, c.Request.URL.RequestURI(), c.ContentType(), f)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "could not read request"})
+			return
+		}
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "could not read request"})
+			return
+		}
+		c.Request.Body = f
+
+		prev, err := helpers.BeginIdempotentRequest(c.Request.Context(), userID, key, hash)
+		switch {
+		case errors.Is(err, helpers.ErrIdempotencyInProgress):
+			c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		case errors.Is(err, helpers.ErrIdempotencyKeyReused):
+			c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+			return
+		case err != nil:
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "could not check idempotency key"})
+			return
+		case prev != nil:
+			for name, values := range prev.Header {
+				c.Writer.Header()[name] = values
+			}
+			c.Header("Idempotent-Replayed", "true")
+			c.Data(prev.Status, prev.ContentType, prev.Body)
+			c.Abort()
+			return
+		}
+
+		completed := false
+		defer func() {
+			if !completed {
+				releaseKey(userID, key)
+			}
+		}()
+
+		rec := &recordingWriter{ResponseWriter: c.Writer}
+		c.Writer = rec
+		c.Next()
+
+		if rec.Status() >= http.StatusInternalServerError {
+			return
+		}
+		header := rec.Header().Clone()
+		for _, name := range []string{"Content-Type", "Content-Length", "Date"} {
+			header.Del(name)
+		}
+		// The response has been sent either way; if it can't be stored, the
+		// key is released so that a retry runs the request again instead of
+		// finding it in progress until the key expires.
+		err = helpers.CompleteIdempotentRequest(context.Background(), userID, key, helpers.IdempotentResponse{
+			Status:      rec.Status(),
+			ContentType: rec.Header().Get("Content-Type"),
+			Header:      header,
+			Body:        rec.body.Bytes(),
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "idempotency: %v\n", err)
+			return
+		}
+		completed = true
+	}
+}
+
+func releaseKey(userID, key string) {
+	if err := helpers.ReleaseIdempotencyKey(context.Background(), userID, key); err != nil {
+		fmt.Fprintf(os.Stderr, "idempotency: %v\n", err)
+	}
+}
@@ -0,0 +1,283 @@
+// Package migrate applies the versioned schema migrations embedded in the
+// binary. Each migration is a pair of files sql/NNNN_name.up.sql and
+// sql/NNNN_name.down.sql, applied in version order inside a transaction and
+// recorded in schema_migrations with the checksum of its up file.
+package migrate
+
+import (
+	"context"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+//go:embed sql/*.sql
+var files embed.FS
+
+var (
+	ErrChecksumMismatch = errors.New("applied migration differs from the embedded one")
+	ErrUnknownVersion   = errors.New("database has a migration this build doesn't know")
+	ErrNoDown           = errors.New("migration has no down file")
+)
+
+// lockKey identifies the advisory lock held while migrating, so replicas
+// starting together apply each migration once.
+const lockKey = 0x6d6967726174
+
+var fileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+type Migration struct {
+	Version  int64
+	Name     string
+	Up       string
+	Down     string
+	Checksum string
+}
+
+type Status struct {
+	Migration
+	Applied   bool
+	AppliedAt *time.Time
+	// Modified is set when the applied checksum differs from the file's.
+	Modified bool
+}
+
+// Load returns the embedded migrations in version order.
+func Load() ([]Migration, error) {
+	entries, err := fs.ReadDir(files, "sql")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, e := range entries {
+		m := fileName.FindStringSubmatch(e.Name())
+		if m == nil {
+			return nil, fmt.Errorf("unexpected migration file %s", e.Name())
+		}
+		version, _ := strconv.ParseInt(m[1], 10, 64)
+		body, err := fs.ReadFile(files, path.Join("sql", e.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		mig, ok := byVersion[version]
+		if !ok {
+			mig = &Migration{Version: version, Name: m[2]}
+			byVersion[version] = mig
+		} else if mig.Name != m[2] {
+			return nil, fmt.Errorf("migration %d has two names, %s and %s", version, mig.Name, m[2])
+		}
+		if m[3] == "up" {
+			sum := sha256.Sum256(body)
+			mig.Up, mig.Checksum = string(body), hex.EncodeToString(sum[:])
+		} else {
+			mig.Down = string(body)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, mig := range byVersion {
+		if mig.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up file", mig.Version, mig.Name)
+		}
+		migrations = append(migrations, *mig)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+type applied struct {
+	checksum  string
+	appliedAt time.Time
+}
+
+// withLock runs fn on a connection holding the migration lock, after making
+// sure schema_migrations exists and matches the embedded migrations.
+func withLock(c context.Context, db *pgxpool.Pool, fn func(conn *pgxpool.Conn, migrations []Migration, done map[int64]applied) error) error {
+	migrations, err := Load()
+	if err != nil {
+		return err
+	}
+
+	conn, err := db.Acquire(c)
+	if err != nil {
+		return fmt.Errorf("acquire connection: %w", err)
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(c, `SELECT pg_advisory_lock($1)`, lockKey); err != nil {
+		return fmt.Errorf("take migration lock: %w", err)
+	}
+	defer conn.Exec(context.Background(), `SELECT pg_advisory_unlock($1)`, lockKey)
+
+	_, err = conn.Exec(c, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version     BIGINT PRIMARY KEY,
+		name        TEXT NOT NULL,
+		checksum    TEXT NOT NULL,
+		applied_at  TIMESTAMP NOT NULL DEFAULT NOW()
+	)`)
+	if err != nil {
+		return fmt.Errorf("create schema_migrations: %w", err)
+	}
+
+	done, err := appliedMigrations(c, conn)
+	if err != nil {
+		return err
+	}
+	if err := verify(migrations, done); err != nil {
+		return err
+	}
+	return fn(conn, migrations, done)
+}
+
+func appliedMigrations(c context.Context, conn *pgxpool.Conn) (map[int64]applied, error) {
+	rows, err := conn.Query(c, `SELECT version, checksum, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("read schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	done := make(map[int64]applied)
+	for rows.Next() {
+		var (
+			version int64
+			a       applied
+		)
+		if err := rows.Scan(&version, &a.checksum, &a.appliedAt); err != nil {
+			return nil, fmt.Errorf("read schema_migrations: %w", err)
+		}
+		done[version] = a
+	}
+	return done, rows.Err()
+}
+
+// verify fails if an applied migration was edited after it ran or is
+// missing from this build.
+func verify(migrations []Migration, done map[int64]applied) error {
+	known := make(map[int64]bool, len(migrations))
+	for _, m := range migrations {
+		known[m.Version] = true
+		if a, ok := done[m.Version]; ok && a.checksum != m.Checksum {
+			return fmt.Errorf("%w: %d_%s", ErrChecksumMismatch, m.Version, m.Name)
+		}
+	}
+	for version := range done {
+		if !known[version] {
+			return fmt.Errorf("%w: %d", ErrUnknownVersion, version)
+		}
+	}
+	return nil
+}
+
+// Up applies every pending migration and returns the ones it applied.
+// progress, if set, is called before each one.
+func Up(c context.Context, db *pgxpool.Pool, progress func(Migration)) ([]Migration, error) {
+	var ran []Migration
+	err := withLock(c, db, func(conn *pgxpool.Conn, migrations []Migration, done map[int64]applied) error {
+		for _, m := range migrations {
+			if _, ok := done[m.Version]; ok {
+				continue
+			}
+			if progress != nil {
+				progress(m)
+			}
+			err := pgx.BeginFunc(c, conn, func(tx pgx.Tx) error {
+				if _, err := tx.Exec(c, m.Up); err != nil {
+					return err
+				}
+				_, err := tx.Exec(c,
+					`INSERT INTO schema_migrations(version, name, checksum) VALUES($1, $2, $3)`,
+					m.Version, m.Name, m.Checksum)
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("migration %d_%s: %w", m.Version, m.Name, err)
+			}
+			ran = append(ran, m)
+		}
+		return nil
+	})
+	return ran, err
+}
+
+// Down reverts the last steps applied migrations, newest first, and returns
+// the ones it reverted.
+func Down(c context.Context, db *pgxpool.Pool, steps int, progress func(Migration)) ([]Migration, error) {
+	var reverted []Migration
+	err := withLock(c, db, func(conn *pgxpool.Conn, migrations []Migration, done map[int64]applied) error {
+		for i := len(migrations) - 1; i >= 0 && len(reverted) < steps; i-- {
+			m := migrations[i]
+			if _, ok := done[m.Version]; !ok {
+				continue
+			}
+			if m.Down == "" {
+				return fmt.Errorf("%w: %d_%s", ErrNoDown, m.Version, m.Name)
+			}
+			if progress != nil {
+				progress(m)
+			}
+			err := pgx.BeginFunc(c, conn, func(tx pgx.Tx) error {
+				if _, err := tx.Exec(c, m.Down); err != nil {
+					return err
+				}
+				_, err := tx.Exec(c, `DELETE FROM schema_migrations WHERE version=$1`, m.Version)
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("migration %d_%s: %w", m.Version, m.Name, err)
+			}
+			reverted = append(reverted, m)
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// Statuses lists every embedded migration with whether it has been applied.
+// Unlike Up and Down it doesn't fail on a checksum mismatch but reports it.
+func Statuses(c context.Context, db *pgxpool.Pool) ([]Status, error) {
+	migrations, err := Load()
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := db.Acquire(c)
+	if err != nil {
+		return nil, fmt.Errorf("acquire connection: %w", err)
+	}
+	defer conn.Release()
+
+	var exists bool
+	if err := conn.QueryRow(c, `SELECT to_regclass('schema_migrations') IS NOT NULL`).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("read schema_migrations: %w", err)
+	}
+	done := map[int64]applied{}
+	if exists {
+		if done, err = appliedMigrations(c, conn); err != nil {
+			return nil, err
+		}
+	}
+
+	statuses := make([]Status, len(migrations))
+	for i, m := range migrations {
+		statuses[i].Migration = m
+		if a, ok := done[m.Version]; ok {
+			statuses[i].Applied = true
+			statuses[i].AppliedAt = &a.appliedAt
+			statuses[i].Modified = a.checksum != m.Checksum
+		}
+	}
+	return statuses, nil
+}
@@ -0,0 +1,90 @@
+package migrate
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func TestLoad(t *testing.T) {
+	migrations, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, m := range migrations {
+		if m.Version != int64(i+1) {
+			t.Errorf("migration %d_%s: want version %d", m.Version, m.Name, i+1)
+		}
+		if m.Down == "" {
+			t.Errorf("migration %d_%s has no down file", m.Version, m.Name)
+		}
+	}
+}
+
+// legacyInitSQL is the schema the old sql/init.sql created.
+const legacyInitSQL = `
+CREATE TABLE users (
+  id          BIGSERIAL PRIMARY KEY,
+  username    TEXT UNIQUE NOT NULL,
+  email       TEXT UNIQUE NOT NULL,
+  password    TEXT NOT NULL,
+  created_at  TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE photos (
+  id          BIGSERIAL PRIMARY KEY,
+  user_id     BIGINT NOT NULL REFERENCES users(id),
+  url         TEXT NOT NULL,
+  note        TEXT,
+  created_at  TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+INSERT INTO users(username, email, password) VALUES ('ada', 'ada@example.com', 'hash');
+INSERT INTO photos(user_id, url, note) VALUES (1, 'uploads/a.jpg', 'tram');`
+
+// TestAdoptLegacyDatabase migrates a database created by the old init.sql,
+// reverts every migration and applies them again. It drops every table the
+// migrations know, so don't point TEST_DATABASE_URL at real data.
+func TestAdoptLegacyDatabase(t *testing.T) {
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+
+	c := context.Background()
+	db, err := pgxpool.New(c, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	_, err = db.Exec(c, `DROP TABLE IF EXISTS schema_migrations, reindex_runs, fsck_runs, photo_embeddings,
+		analysis_results, analysis_jobs, idempotency_keys, tus_uploads, photos, users CASCADE`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(c, legacyInitSQL); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Up(c, db, nil); err != nil {
+		t.Fatalf("migrating the legacy schema: %v", err)
+	}
+	var mediaType string
+	var isAdmin bool
+	err = db.QueryRow(c, `SELECT p.media_type, u.is_admin FROM photos p JOIN users u ON u.id=p.user_id WHERE p.search_tsv @@ to_tsquery('tram')`).
+		Scan(&mediaType, &isAdmin)
+	if err != nil || mediaType != "image" || isAdmin {
+		t.Errorf("legacy photo after migrating: media type %q, admin %v, %v", mediaType, isAdmin, err)
+	}
+
+	migrations, _ := Load()
+	if _, err := Down(c, db, len(migrations), nil); err != nil {
+		t.Fatalf("reverting: %v", err)
+	}
+	if _, err := Up(c, db, nil); err != nil {
+		t.Fatalf("migrating again: %v", err)
+	}
+}
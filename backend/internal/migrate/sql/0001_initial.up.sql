@@ -1,5 +1,6 @@
-DROP TABLE IF EXISTS users CASCADE;
-DROP TABLE IF EXISTS photos CASCADE;
+-- The schema of the old sql/init.sql, without its DROP statements. A
+-- database created from that file already matches it, so running the
+-- migrations on it changes nothing here and continues with 0002.
 
 CREATE TABLE IF NOT EXISTS users (
   id          BIGSERIAL PRIMARY KEY,
@@ -15,4 +16,4 @@ CREATE TABLE IF NOT EXISTS photos (
   url         TEXT NOT NULL,
   note        TEXT,
   created_at  TIMESTAMP NOT NULL DEFAULT NOW()
-);
\ No newline at end of file
+);
@@ -0,0 +1,21 @@
+DROP INDEX IF EXISTS photos_search_idx;
+DROP INDEX IF EXISTS photos_user_hash_idx;
+
+ALTER TABLE photos
+  DROP COLUMN IF EXISTS search_tsv,
+  DROP COLUMN IF EXISTS mime_type,
+  DROP COLUMN IF EXISTS size_bytes,
+  DROP COLUMN IF EXISTS width,
+  DROP COLUMN IF EXISTS height,
+  DROP COLUMN IF EXISTS content_hash,
+  DROP COLUMN IF EXISTS media_type,
+  DROP COLUMN IF EXISTS duration_ms,
+  DROP COLUMN IF EXISTS video_codec,
+  DROP COLUMN IF EXISTS poster_url,
+  DROP COLUMN IF EXISTS keyframes,
+  DROP COLUMN IF EXISTS city,
+  DROP COLUMN IF EXISTS country,
+  DROP COLUMN IF EXISTS caption,
+  DROP COLUMN IF EXISTS caption_edited;
+
+ALTER TABLE users DROP COLUMN IF EXISTS is_admin;
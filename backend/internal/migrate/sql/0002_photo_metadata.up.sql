@@ -0,0 +1,28 @@
+-- Upload metadata, video details, places and captions on photos, with the
+-- full text column keyword search reads, and the admin flag on users.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS is_admin BOOLEAN NOT NULL DEFAULT FALSE;
+
+ALTER TABLE photos
+  ADD COLUMN IF NOT EXISTS mime_type      TEXT,
+  ADD COLUMN IF NOT EXISTS size_bytes     BIGINT,
+  ADD COLUMN IF NOT EXISTS width          INT,
+  ADD COLUMN IF NOT EXISTS height         INT,
+  ADD COLUMN IF NOT EXISTS content_hash   TEXT,
+  ADD COLUMN IF NOT EXISTS media_type     TEXT NOT NULL DEFAULT 'image',
+  ADD COLUMN IF NOT EXISTS duration_ms    BIGINT,
+  ADD COLUMN IF NOT EXISTS video_codec    TEXT,
+  ADD COLUMN IF NOT EXISTS poster_url     TEXT,
+  ADD COLUMN IF NOT EXISTS keyframes      TEXT[],
+  ADD COLUMN IF NOT EXISTS city           TEXT,
+  ADD COLUMN IF NOT EXISTS country        TEXT,
+  ADD COLUMN IF NOT EXISTS caption        TEXT,
+  ADD COLUMN IF NOT EXISTS caption_edited BOOLEAN NOT NULL DEFAULT FALSE;
+
+ALTER TABLE photos ADD COLUMN IF NOT EXISTS search_tsv TSVECTOR GENERATED ALWAYS AS (
+  setweight(to_tsvector('english', coalesce(note, '')), 'A') ||
+  setweight(to_tsvector('english', coalesce(caption, '')), 'B') ||
+  setweight(to_tsvector('english', coalesce(city, '') || ' ' || coalesce(country, '')), 'C')
+) STORED;
+
+CREATE INDEX IF NOT EXISTS photos_user_hash_idx ON photos(user_id, content_hash);
+CREATE INDEX IF NOT EXISTS photos_search_idx ON photos USING GIN (search_tsv);
@@ -0,0 +1,22 @@
+-- Resumable tus uploads and the responses stored for Idempotency-Key.
+CREATE TABLE IF NOT EXISTS tus_uploads (
+  id             TEXT PRIMARY KEY,
+  user_id        BIGINT NOT NULL REFERENCES users(id),
+  upload_length  BIGINT NOT NULL,
+  upload_offset  BIGINT NOT NULL DEFAULT 0,
+  filename       TEXT NOT NULL DEFAULT '',
+  note           TEXT NOT NULL DEFAULT '',
+  photo_id       BIGINT REFERENCES photos(id),
+  created_at     TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+  user_id       BIGINT NOT NULL,
+  key           TEXT NOT NULL,
+  request_hash  TEXT NOT NULL,
+  status        INT,
+  content_type  TEXT,
+  body          BYTEA,
+  created_at    TIMESTAMP NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (user_id, key)
+);
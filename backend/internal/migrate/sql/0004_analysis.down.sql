@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS analysis_results;
+DROP TABLE IF EXISTS analysis_jobs;
@@ -0,0 +1,27 @@
+CREATE TABLE IF NOT EXISTS analysis_jobs (
+  id           BIGSERIAL PRIMARY KEY,
+  user_id      BIGINT NOT NULL REFERENCES users(id),
+  source_url   TEXT,
+  filename     TEXT,
+  options      JSONB,
+  status       TEXT NOT NULL DEFAULT 'queued',
+  probability  DOUBLE PRECISION,
+  label        TEXT,
+  cached       BOOLEAN NOT NULL DEFAULT FALSE,
+  timeline     JSONB,
+  error        TEXT,
+  created_at   TIMESTAMP NOT NULL DEFAULT NOW(),
+  started_at   TIMESTAMP,
+  finished_at  TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS analysis_jobs_user_idx ON analysis_jobs(user_id, created_at DESC);
+
+CREATE TABLE IF NOT EXISTS analysis_results (
+  content_hash   TEXT NOT NULL,
+  model_version  TEXT NOT NULL,
+  params         TEXT NOT NULL DEFAULT '',
+  result         JSONB NOT NULL,
+  created_at     TIMESTAMP NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (content_hash, model_version, params)
+);
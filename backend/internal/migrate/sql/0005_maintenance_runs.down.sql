@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS fsck_runs;
+DROP TABLE IF EXISTS reindex_runs;
@@ -0,0 +1,31 @@
+-- Rebuilds of the vector index. A full run writes to the generation named in
+-- target and promotes it when done; filtered runs have no target and
+-- overwrite vectors in place. checkpoint_id is the last photo id processed.
+CREATE TABLE IF NOT EXISTS reindex_runs (
+  id             BIGSERIAL PRIMARY KEY,
+  status         TEXT NOT NULL DEFAULT 'paused',
+  user_id        BIGINT,
+  from_ts        TIMESTAMP,
+  to_ts          TIMESTAMP,
+  target         TEXT,
+  total          BIGINT NOT NULL DEFAULT 0,
+  done           BIGINT NOT NULL DEFAULT 0,
+  failed         BIGINT NOT NULL DEFAULT 0,
+  checkpoint_id  BIGINT NOT NULL DEFAULT 0,
+  error          TEXT,
+  created_at     TIMESTAMP NOT NULL DEFAULT NOW(),
+  updated_at     TIMESTAMP NOT NULL DEFAULT NOW(),
+  finished_at    TIMESTAMP
+);
+
+-- Consistency checks started from the admin API, with the repairs requested
+-- and the findings.
+CREATE TABLE IF NOT EXISTS fsck_runs (
+  id           BIGSERIAL PRIMARY KEY,
+  status       TEXT NOT NULL DEFAULT 'running',
+  repair       TEXT[] NOT NULL DEFAULT '{}',
+  report       JSONB,
+  error        TEXT,
+  created_at   TIMESTAMP NOT NULL DEFAULT NOW(),
+  finished_at  TIMESTAMP
+);
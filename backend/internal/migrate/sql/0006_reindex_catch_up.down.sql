@@ -0,0 +1,2 @@
+ALTER TABLE photos DROP COLUMN IF EXISTS caption_edited_at;
+ALTER TABLE reindex_runs DROP COLUMN IF EXISTS failed_ids;
@@ -0,0 +1,5 @@
+-- Photos a reindex run couldn't embed, retried before it finishes, and when
+-- each caption was last edited, so edits made during a run can be replayed
+-- into its target generation.
+ALTER TABLE reindex_runs ADD COLUMN IF NOT EXISTS failed_ids BIGINT[] NOT NULL DEFAULT '{}';
+ALTER TABLE photos ADD COLUMN IF NOT EXISTS caption_edited_at TIMESTAMP;
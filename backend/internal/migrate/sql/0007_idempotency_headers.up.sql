@@ -0,0 +1,3 @@
+-- Response headers replayed with the stored body, such as the Location of a
+-- created tus upload.
+ALTER TABLE idempotency_keys ADD COLUMN IF NOT EXISTS headers JSONB;
@@ -0,0 +1,2 @@
+-- When a tus upload was last written to, so abandoned ones can expire.
+ALTER TABLE tus_uploads ADD COLUMN IF NOT EXISTS updated_at TIMESTAMP NOT NULL DEFAULT NOW();
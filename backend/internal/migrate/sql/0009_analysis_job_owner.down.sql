@@ -0,0 +1,2 @@
+ALTER TABLE analysis_jobs DROP COLUMN IF EXISTS heartbeat_at;
+ALTER TABLE analysis_jobs DROP COLUMN IF EXISTS owner;
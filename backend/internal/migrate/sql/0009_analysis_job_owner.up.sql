@@ -0,0 +1,4 @@
+-- Which process holds a queued or running analysis job, and when it last
+-- said so, so a restart only fails the jobs of processes that are gone.
+ALTER TABLE analysis_jobs ADD COLUMN IF NOT EXISTS owner TEXT;
+ALTER TABLE analysis_jobs ADD COLUMN IF NOT EXISTS heartbeat_at TIMESTAMP;
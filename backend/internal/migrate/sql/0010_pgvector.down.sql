@@ -0,0 +1,2 @@
+-- The extension is left installed; retired generations may still use it.
+DROP TABLE IF EXISTS photo_embeddings;
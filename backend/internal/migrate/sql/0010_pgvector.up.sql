@@ -0,0 +1,23 @@
+-- The vector table of VECTOR_BACKEND=pgvector, sized for the 768 dimensions
+-- of the clip-ViT-L-14 model the embedding service runs. It is only created
+-- where the vector extension is available, so installs that keep their
+-- vectors in Qdrant migrate without it. Reindex generations copy this table.
+DO $$
+BEGIN
+  IF EXISTS (SELECT 1 FROM pg_available_extensions WHERE name = 'vector') THEN
+    CREATE EXTENSION IF NOT EXISTS vector;
+    CREATE TABLE IF NOT EXISTS photo_embeddings (
+      photo_id    BIGINT PRIMARY KEY,
+      user_id     BIGINT NOT NULL,
+      created_at  TIMESTAMP NOT NULL,
+      note        TEXT NOT NULL DEFAULT '',
+      caption     TEXT NOT NULL DEFAULT '',
+      city        TEXT NOT NULL DEFAULT '',
+      country     TEXT NOT NULL DEFAULT '',
+      media_type  TEXT NOT NULL DEFAULT 'image',
+      embedding   vector(768) NOT NULL
+    );
+    CREATE INDEX IF NOT EXISTS photo_embeddings_hnsw_idx ON photo_embeddings USING hnsw (embedding vector_cosine_ops);
+    CREATE INDEX IF NOT EXISTS photo_embeddings_user_idx ON photo_embeddings(user_id, created_at);
+  END IF;
+END $$;
@@ -0,0 +1,552 @@
+package repository
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/Pranjal095/Memora/backend/internal/schema"
+)
+
+// MemoryUsers keeps users in process memory, for tests.
+type MemoryUsers struct {
+	mu     sync.RWMutex
+	nextID int64
+	users  map[int64]User
+}
+
+func NewMemoryUsers() *MemoryUsers {
+	return &MemoryUsers{users: make(map[int64]User)}
+}
+
+func (m *MemoryUsers) Create(_ context.Context, username, email, passwordHash string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, u := range m.users {
+		if u.Username == username || u.Email == email {
+			return 0, ErrUserExists
+		}
+	}
+	m.nextID++
+	m.users[m.nextID] = User{
+		ID:           m.nextID,
+		Username:     username,
+		Email:        email,
+		PasswordHash: passwordHash,
+		CreatedAt:    time.Now(),
+	}
+	return m.nextID, nil
+}
+
+func (m *MemoryUsers) GetByID(_ context.Context, id string) (User, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	n, err := strconv.ParseInt(id, 10, 64)
+	if u, ok := m.users[n]; err == nil && ok {
+		return u, nil
+	}
+	return User{}, ErrUserNotFound
+}
+
+func (m *MemoryUsers) GetByUsername(_ context.Context, username string) (User, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, u := range m.users {
+		if u.Username == username {
+			return u, nil
+		}
+	}
+	return User{}, ErrUserNotFound
+}
+
+// SetAdmin grants or revokes admin rights, which only the database can do
+// otherwise.
+func (m *MemoryUsers) SetAdmin(id int64, admin bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if u, ok := m.users[id]; ok {
+		u.IsAdmin = admin
+		m.users[id] = u
+	}
+}
+
+type memoryPhoto struct {
+	NewPhoto
+	id              int64
+	caption         string
+	captionEdited   bool
+	captionEditedAt time.Time
+	createdAt       time.Time
+}
+
+// response renders the row the way PgPhotos reads it back, with the
+// NULLed zero values left out.
+func (p memoryPhoto) response() schema.PhotoResponse {
+	r := schema.PhotoResponse{
+		ID:            p.id,
+		URL:           p.URL,
+		Note:          &p.Note,
+		MimeType:      p.MimeType,
+		SizeBytes:     p.SizeBytes,
+		MediaType:     p.MediaType,
+		CaptionEdited: p.captionEdited,
+		CreatedAt:     p.createdAt.Format(time.RFC3339),
+	}
+	set := func(dst **string, v string) {
+		if v != "" {
+			*dst = &v
+		}
+	}
+	if p.Width != 0 {
+		r.Width = &p.Width
+	}
+	if p.Height != 0 {
+		r.Height = &p.Height
+	}
+	if p.DurationMs != 0 {
+		r.DurationMs = &p.DurationMs
+	}
+	set(&r.PosterURL, p.PosterURL)
+	set(&r.City, p.City)
+	set(&r.Country, p.Country)
+	set(&r.Caption, p.caption)
+	return r
+}
+
+func (p memoryPhoto) matches(f schema.SearchFilters) bool {
+	switch {
+	case f.From != nil && p.createdAt.Before(*f.From),
+		f.To != nil && !p.createdAt.Before(*f.To),
+		f.City != "" && !strings.EqualFold(p.City, f.City),
+		f.Country != "" && !strings.EqualFold(p.Country, f.Country),
+		f.MediaType != "" && p.MediaType != f.MediaType,
+		f.HasNote != nil && (p.Note != "") != *f.HasNote:
+		return false
+	}
+	return true
+}
+
+// MemoryPhotos keeps photos in process memory, for tests. It doesn't check
+// that the owning user exists.
+type MemoryPhotos struct {
+	mu     sync.RWMutex
+	nextID int64
+	photos map[int64]memoryPhoto
+}
+
+func NewMemoryPhotos() *MemoryPhotos {
+	return &MemoryPhotos{photos: make(map[int64]memoryPhoto)}
+}
+
+func (m *MemoryPhotos) Create(_ context.Context, p NewPhoto) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.nextID++
+	p.Keyframes = slices.Clone(p.Keyframes)
+	m.photos[m.nextID] = memoryPhoto{NewPhoto: p, id: m.nextID, createdAt: time.Now()}
+	return m.nextID, nil
+}
+
+func (m *MemoryPhotos) FindByHash(_ context.Context, userID, hash string) (int64, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var found int64
+	for id, p := range m.photos {
+		if p.UserID == userID && p.ContentHash == hash && (found == 0 || id < found) {
+			found = id
+		}
+	}
+	return found, nil
+}
+
+func (m *MemoryPhotos) ListByUser(_ context.Context, userID string) ([]schema.PhotoResponse, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var photos []schema.PhotoResponse
+	for _, p := range m.rows(userID, schema.SearchFilters{}) {
+		photos = append(photos, p.response())
+	}
+	return photos, nil
+}
+
+func (m *MemoryPhotos) GetByIDs(_ context.Context, userID string, ids []int64, f schema.SearchFilters) (map[int64]schema.PhotoResponse, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	photos := make(map[int64]schema.PhotoResponse, len(ids))
+	for _, id := range ids {
+		if p, ok := m.photos[id]; ok && p.UserID == userID && p.matches(f) {
+			photos[id] = p.response()
+		}
+	}
+	return photos, nil
+}
+
+func (m *MemoryPhotos) UpdateCaption(_ context.Context, userID string, id int64, caption string) (schema.PhotoResponse, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	p, ok := m.photos[id]
+	if !ok || p.UserID != userID {
+		return schema.PhotoResponse{}, ErrPhotoNotFound
+	}
+	if caption != "" {
+		p.caption = caption
+	}
+	p.captionEdited, p.captionEditedAt = caption != "", time.Now()
+	m.photos[id] = p
+	return p.response(), nil
+}
+
+func (m *MemoryPhotos) StoreCaptions(_ context.Context, captions map[int64]string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for id, caption := range captions {
+		if p, ok := m.photos[id]; ok && !p.captionEdited {
+			p.caption = caption
+			m.photos[id] = p
+		}
+	}
+	return nil
+}
+
+func (m *MemoryPhotos) Delete(_ context.Context, userID string, id int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if p, ok := m.photos[id]; !ok || p.UserID != userID {
+		return ErrPhotoNotFound
+	}
+	delete(m.photos, id)
+	return nil
+}
+
+// rows returns the user's photos that match f, newest first. The caller
+// holds m.mu.
+func (m *MemoryPhotos) rows(userID string, f schema.SearchFilters) []memoryPhoto {
+	var rows []memoryPhoto
+	for _, p := range m.photos {
+		if p.UserID == userID && p.matches(f) {
+			rows = append(rows, p)
+		}
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if !rows[i].createdAt.Equal(rows[j].createdAt) {
+			return rows[i].createdAt.After(rows[j].createdAt)
+		}
+		return rows[i].id > rows[j].id
+	})
+	return rows
+}
+
+// KeywordSearch approximates the Postgres full-text search: words match
+// whole and case-insensitively but aren't stemmed, and a match counts as
+// much as ts_rank_cd's default weight for the field it is in.
+func (m *MemoryPhotos) KeywordSearch(_ context.Context, userID, q string, f schema.SearchFilters, limit int) ([]ScoredPhoto, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	query := parseKeywordQuery(q)
+	var hits []ScoredPhoto
+	for _, p := range m.rows(userID, f) {
+		fields := []weightedText{
+			{words(p.Note), 1},
+			{words(p.caption), 0.4},
+			{words(p.City + " " + p.Country), 0.2},
+		}
+		if score, ok := query.score(fields); ok {
+			hits = append(hits, ScoredPhoto{ID: p.id, Score: score})
+		}
+	}
+	sort.SliceStable(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].ID > hits[j].ID
+	})
+	return hits[:min(limit, len(hits))], nil
+}
+
+func (m *MemoryPhotos) Filter(_ context.Context, userID string, f schema.SearchFilters, limit int) ([]int64, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var ids []int64
+	for _, p := range m.rows(userID, f) {
+		if len(ids) == limit {
+			break
+		}
+		ids = append(ids, p.id)
+	}
+	return ids, nil
+}
+
+func (m *MemoryPhotos) Places(_ context.Context, userID string) ([]string, []string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var cities, countries []string
+	for _, p := range m.photos {
+		if p.UserID != userID {
+			continue
+		}
+		if p.City != "" && !slices.Contains(cities, p.City) {
+			cities = append(cities, p.City)
+		}
+		if p.Country != "" && !slices.Contains(countries, p.Country) {
+			countries = append(countries, p.Country)
+		}
+	}
+	return cities, countries, nil
+}
+
+func (m *MemoryPhotos) EmbedSource(_ context.Context, userID string, id int64) (EmbedSource, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	p, ok := m.photos[id]
+	if !ok || p.UserID != userID {
+		return EmbedSource{}, ErrPhotoNotFound
+	}
+	return p.embedSource(), nil
+}
+
+func (p memoryPhoto) owner() int64 {
+	id, _ := strconv.ParseInt(p.UserID, 10, 64)
+	return id
+}
+
+func (p memoryPhoto) embedSource() EmbedSource {
+	s := EmbedSource{
+		ID:        p.id,
+		UserID:    p.owner(),
+		URL:       p.URL,
+		Note:      p.Note,
+		MediaType: p.MediaType,
+		PosterURL: p.PosterURL,
+		Keyframes: slices.Clone(p.Keyframes),
+		City:      p.City,
+		Country:   p.Country,
+		CreatedAt: p.createdAt,
+	}
+	if p.captionEdited {
+		s.Caption = p.caption
+	}
+	return s
+}
+
+// byID returns every photo that keep reports true for, in id order. The
+// caller holds m.mu.
+func (m *MemoryPhotos) byID(keep func(memoryPhoto) bool) []memoryPhoto {
+	var rows []memoryPhoto
+	for _, p := range m.photos {
+		if keep(p) {
+			rows = append(rows, p)
+		}
+	}
+	slices.SortFunc(rows, func(a, b memoryPhoto) int { return cmp.Compare(a.id, b.id) })
+	return rows
+}
+
+func (p memoryPhoto) inScope(s PhotoScope) bool {
+	switch {
+	case s.UserID != 0 && p.owner() != s.UserID,
+		s.From != nil && p.createdAt.Before(*s.From),
+		s.To != nil && !p.createdAt.Before(*s.To):
+		return false
+	}
+	return true
+}
+
+func embedSources(rows []memoryPhoto) []EmbedSource {
+	var sources []EmbedSource
+	for _, p := range rows {
+		sources = append(sources, p.embedSource())
+	}
+	return sources
+}
+
+func (m *MemoryPhotos) Count(_ context.Context, s PhotoScope) (int64, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var n int64
+	for _, p := range m.photos {
+		if p.inScope(s) {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (m *MemoryPhotos) EmbedSourcesAfter(_ context.Context, s PhotoScope, after int64, limit int) ([]EmbedSource, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	rows := m.byID(func(p memoryPhoto) bool { return p.id > after && p.inScope(s) })
+	return embedSources(rows[:min(limit, len(rows))]), nil
+}
+
+func (m *MemoryPhotos) EmbedSourcesByIDs(_ context.Context, ids []int64) ([]EmbedSource, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return embedSources(m.byID(func(p memoryPhoto) bool { return slices.Contains(ids, p.id) })), nil
+}
+
+func (m *MemoryPhotos) EditedEmbedSources(_ context.Context, since time.Time, maxID int64) ([]EmbedSource, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	rows := m.byID(func(p memoryPhoto) bool {
+		return p.id <= maxID && !p.captionEditedAt.IsZero() && !p.captionEditedAt.Before(since)
+	})
+	return embedSources(rows), nil
+}
+
+func (m *MemoryPhotos) ExistingIDs(_ context.Context, ids []int64) ([]int64, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var existing []int64
+	for _, p := range m.byID(func(p memoryPhoto) bool { return slices.Contains(ids, p.id) }) {
+		existing = append(existing, p.id)
+	}
+	return existing, nil
+}
+
+func (m *MemoryPhotos) FileRefs(_ context.Context) ([]FileRefs, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var refs []FileRefs
+	for _, p := range m.byID(func(memoryPhoto) bool { return true }) {
+		refs = append(refs, FileRefs{
+			ID:        p.id,
+			UserID:    p.owner(),
+			URL:       p.URL,
+			PosterURL: p.PosterURL,
+			Keyframes: slices.Clone(p.Keyframes),
+		})
+	}
+	return refs, nil
+}
+
+// keywordQuery is a parsed web search style query: every clause must match
+// one of its alternatives, and no excluded phrase may match.
+type keywordQuery struct {
+	clauses  [][][]string
+	excluded [][]string
+}
+
+type weightedText struct {
+	words  []string
+	weight float64
+}
+
+func words(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// parseKeywordQuery splits q into quoted phrases, -excluded terms and
+// alternatives joined by "or".
+func parseKeywordQuery(q string) keywordQuery {
+	var query keywordQuery
+	joinNext := false
+	for i, part := range strings.Split(q, `"`) {
+		quoted := i%2 == 1
+		var tokens []string
+		if quoted {
+			tokens = []string{part}
+		} else {
+			tokens = strings.Fields(part)
+		}
+		for _, tok := range tokens {
+			if !quoted && strings.EqualFold(tok, "or") {
+				joinNext = len(query.clauses) > 0
+				continue
+			}
+			if !quoted && strings.HasPrefix(tok, "-") {
+				if phrase := words(tok); len(phrase) > 0 {
+					query.excluded = append(query.excluded, phrase)
+				}
+				continue
+			}
+			phrase := words(tok)
+			if len(phrase) == 0 {
+				continue
+			}
+			if joinNext {
+				last := len(query.clauses) - 1
+				query.clauses[last] = append(query.clauses[last], phrase)
+			} else {
+				query.clauses = append(query.clauses, [][]string{phrase})
+			}
+			joinNext = false
+		}
+	}
+	return query
+}
+
+// score reports whether fields match the query and how well.
+func (q keywordQuery) score(fields []weightedText) (float64, bool) {
+	if len(q.clauses) == 0 {
+		return 0, false
+	}
+	best := func(phrase []string) float64 {
+		var w float64
+		for _, f := range fields {
+			if containsPhrase(f.words, phrase) {
+				w = max(w, f.weight)
+			}
+		}
+		return w
+	}
+	for _, phrase := range q.excluded {
+		if best(phrase) > 0 {
+			return 0, false
+		}
+	}
+	var total float64
+	for _, alternatives := range q.clauses {
+		var w float64
+		for _, phrase := range alternatives {
+			w = max(w, best(phrase))
+		}
+		if w == 0 {
+			return 0, false
+		}
+		total += w
+	}
+	return total, true
+}
+
+func containsPhrase(words, phrase []string) bool {
+	for i := 0; i+len(phrase) <= len(words); i++ {
+		if slices.Equal(words[i:i+len(phrase)], phrase) {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	_ UserRepository  = (*MemoryUsers)(nil)
+	_ PhotoRepository = (*MemoryPhotos)(nil)
+)
@@ -0,0 +1,377 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/Pranjal095/Memora/backend/internal/schema"
+)
+
+// PgPhotos keeps photos in the photos table.
+type PgPhotos struct {
+	db *pgxpool.Pool
+}
+
+func NewPgPhotos(db *pgxpool.Pool) *PgPhotos {
+	return &PgPhotos{db: db}
+}
+
+// PhotoFilterSQL renders f as SQL predicates on photos, appending their
+// arguments to args.
+func PhotoFilterSQL(f schema.SearchFilters, args []any) (string, []any) {
+	var preds []string
+	add := func(pred string, arg any) {
+		args = append(args, arg)
+		preds = append(preds, fmt.Sprintf(pred, len(args)))
+	}
+	if f.From != nil {
+		add("created_at >= $%d", *f.From)
+	}
+	if f.To != nil {
+		add("created_at < $%d", *f.To)
+	}
+	if f.City != "" {
+		add("lower(city) = lower($%d)", f.City)
+	}
+	if f.Country != "" {
+		add("lower(country) = lower($%d)", f.Country)
+	}
+	if f.MediaType != "" {
+		add("media_type = $%d", f.MediaType)
+	}
+	if f.HasNote != nil {
+		add("(coalesce(note, '') <> '') = $%d", *f.HasNote)
+	}
+	if len(preds) == 0 {
+		return "", args
+	}
+	return " AND " + strings.Join(preds, " AND "), args
+}
+
+func (r *PgPhotos) Create(c context.Context, p NewPhoto) (int64, error) {
+	var id int64
+	err := r.db.QueryRow(
+		c,
+		`INSERT INTO photos(user_id,url,note,mime_type,size_bytes,width,height,content_hash,
+		                    media_type,duration_ms,video_codec,poster_url,keyframes,city,country)
+		 VALUES($1,$2,$3,$4,$5,NULLIF($6,0),NULLIF($7,0),$8,$9,NULLIF($10,0),NULLIF($11,''),NULLIF($12,''),$13,
+		        NULLIF($14,''),NULLIF($15,''))
+		 RETURNING id`,
+		p.UserID, p.URL, p.Note, p.MimeType, p.SizeBytes, p.Width, p.Height, p.ContentHash,
+		p.MediaType, p.DurationMs, p.VideoCodec, p.PosterURL, p.Keyframes, p.City, p.Country,
+	).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create photo record: %w", err)
+	}
+	return id, nil
+}
+
+func (r *PgPhotos) FindByHash(c context.Context, userID, hash string) (int64, error) {
+	var id int64
+	err := r.db.QueryRow(c,
+		`SELECT id FROM photos WHERE user_id=$1 AND content_hash=$2 ORDER BY id LIMIT 1`,
+		userID, hash).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to look up photo hash: %w", err)
+	}
+	return id, nil
+}
+
+const photoColumns = `id,url,note,mime_type,size_bytes,width,height,media_type,duration_ms,poster_url,city,country,caption,caption_edited,created_at`
+
+func scanPhoto(row pgx.Row) (schema.PhotoResponse, error) {
+	var p schema.PhotoResponse
+	var note, mimeType sql.NullString
+	var size sql.NullInt64
+	var createdAt time.Time
+
+	err := row.Scan(&p.ID, &p.URL, &note, &mimeType, &size, &p.Width, &p.Height,
+		&p.MediaType, &p.DurationMs, &p.PosterURL, &p.City, &p.Country, &p.Caption, &p.CaptionEdited, &createdAt)
+	if err != nil {
+		return p, err
+	}
+
+	if note.Valid {
+		p.Note = &note.String
+	}
+	p.MimeType = mimeType.String
+	p.SizeBytes = size.Int64
+
+	p.CreatedAt = createdAt.Format(time.RFC3339)
+	return p, nil
+}
+
+func (r *PgPhotos) ListByUser(c context.Context, userID string) ([]schema.PhotoResponse, error) {
+	rows, err := r.db.Query(c,
+		`SELECT `+photoColumns+` FROM photos WHERE user_id=$1 ORDER BY created_at DESC, id DESC`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query photos: %w", err)
+	}
+	defer rows.Close()
+
+	var photos []schema.PhotoResponse
+	for rows.Next() {
+		p, err := scanPhoto(rows)
+		if err != nil {
+			continue
+		}
+		photos = append(photos, p)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error reading photos: %w", err)
+	}
+
+	return photos, nil
+}
+
+func (r *PgPhotos) GetByIDs(c context.Context, userID string, ids []int64, f schema.SearchFilters) (map[int64]schema.PhotoResponse, error) {
+	photos := make(map[int64]schema.PhotoResponse, len(ids))
+	if len(ids) == 0 {
+		return photos, nil
+	}
+
+	where, args := PhotoFilterSQL(f, []any{userID, ids})
+	rows, err := r.db.Query(c,
+		`SELECT `+photoColumns+` FROM photos WHERE user_id=$1 AND id = ANY($2)`+where, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query photos: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		p, err := scanPhoto(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error reading photos: %w", err)
+		}
+		photos[p.ID] = p
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error reading photos: %w", err)
+	}
+	return photos, nil
+}
+
+func (r *PgPhotos) UpdateCaption(c context.Context, userID string, id int64, caption string) (schema.PhotoResponse, error) {
+	row := r.db.QueryRow(c,
+		`UPDATE photos SET caption=COALESCE(NULLIF($3,''),caption), caption_edited=($3<>''), caption_edited_at=NOW()
+		 WHERE id=$1 AND user_id=$2 RETURNING `+photoColumns,
+		id, userID, caption)
+	p, err := scanPhoto(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return p, ErrPhotoNotFound
+	}
+	if err != nil {
+		return p, fmt.Errorf("failed to update caption: %w", err)
+	}
+	return p, nil
+}
+
+func (r *PgPhotos) StoreCaptions(c context.Context, captions map[int64]string) error {
+	batch := &pgx.Batch{}
+	for id, caption := range captions {
+		batch.Queue(`UPDATE photos SET caption=NULLIF($2,'') WHERE id=$1 AND NOT caption_edited`, id, caption)
+	}
+	if err := r.db.SendBatch(c, batch).Close(); err != nil {
+		return fmt.Errorf("failed to store captions: %w", err)
+	}
+	return nil
+}
+
+func (r *PgPhotos) Delete(c context.Context, userID string, id int64) error {
+	tag, err := r.db.Exec(c, `DELETE FROM photos WHERE id=$1 AND user_id=$2`, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete photo: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrPhotoNotFound
+	}
+	return nil
+}
+
+func (r *PgPhotos) KeywordSearch(c context.Context, userID, q string, f schema.SearchFilters, limit int) ([]ScoredPhoto, error) {
+	where, args := PhotoFilterSQL(f, []any{userID, q, limit})
+	rows, err := r.db.Query(c,
+		`SELECT id, ts_rank_cd(search_tsv, query) AS rank
+		 FROM photos, websearch_to_tsquery('english', $2) query
+		 WHERE user_id=$1 AND search_tsv @@ query`+where+`
+		 ORDER BY rank DESC, id DESC
+		 LIMIT $3`,
+		args...)
+	if err != nil {
+		return nil, fmt.Errorf("keyword search: %w", err)
+	}
+	hits, err := pgx.CollectRows(rows, pgx.RowToStructByPos[ScoredPhoto])
+	if err != nil {
+		return nil, fmt.Errorf("keyword search: %w", err)
+	}
+	return hits, nil
+}
+
+func (r *PgPhotos) Filter(c context.Context, userID string, f schema.SearchFilters, limit int) ([]int64, error) {
+	where, args := PhotoFilterSQL(f, []any{userID, limit})
+	rows, err := r.db.Query(c,
+		`SELECT id FROM photos WHERE user_id=$1`+where+` ORDER BY created_at DESC, id DESC LIMIT $2`,
+		args...)
+	if err != nil {
+		return nil, fmt.Errorf("filter photos: %w", err)
+	}
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[int64])
+	if err != nil {
+		return nil, fmt.Errorf("filter photos: %w", err)
+	}
+	return ids, nil
+}
+
+func (r *PgPhotos) Places(c context.Context, userID string) ([]string, []string, error) {
+	rows, err := r.db.Query(c,
+		`SELECT DISTINCT 'city', city FROM photos WHERE user_id=$1 AND city IS NOT NULL
+		 UNION
+		 SELECT DISTINCT 'country', country FROM photos WHERE user_id=$1 AND country IS NOT NULL`,
+		userID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to query places: %w", err)
+	}
+	defer rows.Close()
+
+	var cities, countries []string
+	for rows.Next() {
+		var kind, name string
+		if err := rows.Scan(&kind, &name); err != nil {
+			return nil, nil, fmt.Errorf("failed to read places: %w", err)
+		}
+		if kind == "city" {
+			cities = append(cities, name)
+		} else {
+			countries = append(countries, name)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("failed to read places: %w", err)
+	}
+	return cities, countries, nil
+}
+
+// embedSourceColumns are the columns of photos p that scanEmbedSource reads.
+const embedSourceColumns = `p.id, p.user_id, p.url, COALESCE(p.note,''), p.media_type, COALESCE(p.poster_url,''), p.keyframes,
+	COALESCE(p.city,''), COALESCE(p.country,''), CASE WHEN p.caption_edited THEN p.caption ELSE '' END, p.created_at`
+
+func scanEmbedSource(row pgx.Row) (EmbedSource, error) {
+	var s EmbedSource
+	err := row.Scan(&s.ID, &s.UserID, &s.URL, &s.Note, &s.MediaType, &s.PosterURL, &s.Keyframes,
+		&s.City, &s.Country, &s.Caption, &s.CreatedAt)
+	return s, err
+}
+
+func (r *PgPhotos) EmbedSource(c context.Context, userID string, id int64) (EmbedSource, error) {
+	row := r.db.QueryRow(c,
+		`SELECT `+embedSourceColumns+` FROM photos p WHERE p.id=$1 AND p.user_id=$2`, id, userID)
+	s, err := scanEmbedSource(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return s, ErrPhotoNotFound
+	}
+	if err != nil {
+		return s, fmt.Errorf("failed to query photo: %w", err)
+	}
+	return s, nil
+}
+
+// scopeSQL renders s as SQL predicates on photos p, appending their
+// arguments to args.
+func scopeSQL(s PhotoScope, args []any) (string, []any) {
+	var sql string
+	add := func(pred string, arg any) {
+		args = append(args, arg)
+		sql += fmt.Sprintf(" AND "+pred, len(args))
+	}
+	if s.UserID != 0 {
+		add("p.user_id = $%d", s.UserID)
+	}
+	if s.From != nil {
+		add("p.created_at >= $%d", *s.From)
+	}
+	if s.To != nil {
+		add("p.created_at < $%d", *s.To)
+	}
+	return sql, args
+}
+
+func (r *PgPhotos) Count(c context.Context, s PhotoScope) (int64, error) {
+	scope, args := scopeSQL(s, nil)
+	var n int64
+	if err := r.db.QueryRow(c, `SELECT COUNT(*) FROM photos p WHERE TRUE`+scope, args...).Scan(&n); err != nil {
+		return 0, fmt.Errorf("failed to count photos: %w", err)
+	}
+	return n, nil
+}
+
+func (r *PgPhotos) EmbedSourcesAfter(c context.Context, s PhotoScope, after int64, limit int) ([]EmbedSource, error) {
+	scope, args := scopeSQL(s, []any{after, limit})
+	return r.embedSources(c,
+		`SELECT `+embedSourceColumns+` FROM photos p WHERE p.id > $1`+scope+` ORDER BY p.id LIMIT $2`, args...)
+}
+
+func (r *PgPhotos) EmbedSourcesByIDs(c context.Context, ids []int64) ([]EmbedSource, error) {
+	return r.embedSources(c,
+		`SELECT `+embedSourceColumns+` FROM photos p WHERE p.id = ANY($1) ORDER BY p.id`, ids)
+}
+
+func (r *PgPhotos) EditedEmbedSources(c context.Context, since time.Time, maxID int64) ([]EmbedSource, error) {
+	return r.embedSources(c,
+		`SELECT `+embedSourceColumns+` FROM photos p WHERE p.id <= $1 AND p.caption_edited_at >= $2 ORDER BY p.id`,
+		maxID, since)
+}
+
+func (r *PgPhotos) embedSources(c context.Context, query string, args ...any) ([]EmbedSource, error) {
+	rows, err := r.db.Query(c, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query photos: %w", err)
+	}
+	sources, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (EmbedSource, error) {
+		return scanEmbedSource(row)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error reading photos: %w", err)
+	}
+	return sources, nil
+}
+
+func (r *PgPhotos) ExistingIDs(c context.Context, ids []int64) ([]int64, error) {
+	rows, err := r.db.Query(c, `SELECT id FROM photos WHERE id = ANY($1) ORDER BY id`, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query photos: %w", err)
+	}
+	existing, err := pgx.CollectRows(rows, pgx.RowTo[int64])
+	if err != nil {
+		return nil, fmt.Errorf("error reading photos: %w", err)
+	}
+	return existing, nil
+}
+
+func (r *PgPhotos) FileRefs(c context.Context) ([]FileRefs, error) {
+	rows, err := r.db.Query(c, `SELECT id, user_id, url, COALESCE(poster_url,''), keyframes FROM photos ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query photos: %w", err)
+	}
+	refs, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (FileRefs, error) {
+		var f FileRefs
+		err := row.Scan(&f.ID, &f.UserID, &f.URL, &f.PosterURL, &f.Keyframes)
+		return f, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error reading photos: %w", err)
+	}
+	return refs, nil
+}
+
+var _ PhotoRepository = (*PgPhotos)(nil)
@@ -0,0 +1,152 @@
+// Package repository holds the data access for users and photos behind
+// interfaces, with a Postgres implementation and in-memory fakes that
+// behave the same way, so handlers can be exercised without a database.
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Pranjal095/Memora/backend/internal/schema"
+)
+
+var (
+	ErrUserNotFound  = errors.New("user not found")
+	ErrUserExists    = errors.New("username or email already in use")
+	ErrPhotoNotFound = errors.New("photo not found")
+)
+
+type User struct {
+	ID           int64
+	Username     string
+	Email        string
+	PasswordHash string
+	IsAdmin      bool
+	CreatedAt    time.Time
+}
+
+// UserRepository stores accounts. User ids are passed around as the strings
+// carried in the JWT subject, the same as everywhere else in the backend.
+type UserRepository interface {
+	// Create adds a user and returns its id, or ErrUserExists when the
+	// username or email is taken.
+	Create(c context.Context, username, email, passwordHash string) (int64, error)
+	GetByID(c context.Context, id string) (User, error)
+	GetByUsername(c context.Context, username string) (User, error)
+}
+
+// NewPhoto is a photo row about to be inserted. Zero dimensions, duration,
+// codec, poster and place are stored as NULL.
+type NewPhoto struct {
+	UserID      string
+	URL         string
+	Note        string
+	MimeType    string
+	SizeBytes   int64
+	Width       int
+	Height      int
+	ContentHash string
+	MediaType   string
+	DurationMs  int64
+	VideoCodec  string
+	PosterURL   string
+	Keyframes   []string
+	City        string
+	Country     string
+}
+
+// ScoredPhoto is a photo id with the score a search gave it.
+type ScoredPhoto struct {
+	ID    int64
+	Score float64
+}
+
+// EmbedSource is what the embed request of a photo is rebuilt from.
+type EmbedSource struct {
+	ID        int64
+	UserID    int64
+	URL       string
+	Note      string
+	MediaType string
+	PosterURL string
+	Keyframes []string
+	City      string
+	Country   string
+	// Caption is only set when the user wrote it; generated ones are left
+	// for the embed service to redo.
+	Caption   string
+	CreatedAt time.Time
+}
+
+// PhotoScope narrows the photos a maintenance run reads to one user's and
+// to a range of upload times. Zero fields don't narrow it.
+type PhotoScope struct {
+	UserID int64
+	From   *time.Time
+	To     *time.Time
+}
+
+// FileRefs are the files a photo row points at. Paths that aren't URLs are
+// relative to the working directory.
+type FileRefs struct {
+	ID        int64
+	UserID    int64
+	URL       string
+	PosterURL string
+	Keyframes []string
+}
+
+// PhotoRepository stores photo rows. Every read and update is scoped to the
+// owning user; other users' photos behave as if they didn't exist. The
+// exception is the reads for maintenance runs at the end, which span all
+// users.
+type PhotoRepository interface {
+	Create(c context.Context, p NewPhoto) (int64, error)
+	// FindByHash returns the id of the user's oldest photo with the given
+	// content hash, or 0 when there is none.
+	FindByHash(c context.Context, userID, hash string) (int64, error)
+	// ListByUser returns the user's photos, newest first.
+	ListByUser(c context.Context, userID string) ([]schema.PhotoResponse, error)
+	// GetByIDs returns the user's photos among ids that match f, keyed by id.
+	GetByIDs(c context.Context, userID string, ids []int64, f schema.SearchFilters) (map[int64]schema.PhotoResponse, error)
+	// UpdateCaption sets a caption the user wrote and marks it as edited. An
+	// empty caption keeps the current one but clears the mark.
+	UpdateCaption(c context.Context, userID string, id int64, caption string) (schema.PhotoResponse, error)
+	// StoreCaptions records generated captions by photo id, leaving the ones
+	// the user edited alone. An empty caption clears it.
+	StoreCaptions(c context.Context, captions map[int64]string) error
+	// Delete removes one of the user's photos, or fails with
+	// ErrPhotoNotFound.
+	Delete(c context.Context, userID string, id int64) error
+
+	// KeywordSearch ranks the user's photos that match f against q, a web
+	// search style query (quoted phrases, -term, or) over notes, captions
+	// and place names, best first.
+	KeywordSearch(c context.Context, userID, q string, f schema.SearchFilters, limit int) ([]ScoredPhoto, error)
+	// Filter returns the ids of the user's photos that match f, newest
+	// first.
+	Filter(c context.Context, userID string, f schema.SearchFilters, limit int) ([]int64, error)
+	// Places lists the distinct cities and countries of the user's photos.
+	Places(c context.Context, userID string) (cities, countries []string, err error)
+	// EmbedSource returns what one of the user's photos is embedded from,
+	// or fails with ErrPhotoNotFound.
+	EmbedSource(c context.Context, userID string, id int64) (EmbedSource, error)
+
+	// Count returns how many photos are in scope.
+	Count(c context.Context, s PhotoScope) (int64, error)
+	// EmbedSourcesAfter pages through the photos in scope in id order,
+	// returning up to limit of those with an id above after.
+	EmbedSourcesAfter(c context.Context, s PhotoScope, after int64, limit int) ([]EmbedSource, error)
+	// EmbedSourcesByIDs returns the photos among ids in id order, skipping
+	// ids that have none.
+	EmbedSourcesByIDs(c context.Context, ids []int64) ([]EmbedSource, error)
+	// EditedEmbedSources returns the photos with an id up to maxID whose
+	// caption was edited at or after since, in id order.
+	EditedEmbedSources(c context.Context, since time.Time, maxID int64) ([]EmbedSource, error)
+	// ExistingIDs returns the ids among ids that still have a photo, in
+	// order.
+	ExistingIDs(c context.Context, ids []int64) ([]int64, error)
+	// FileRefs lists the files of every photo, in id order.
+	FileRefs(c context.Context) ([]FileRefs, error)
+}
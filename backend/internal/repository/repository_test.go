@@ -0,0 +1,543 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"os"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/Pranjal095/Memora/backend/internal/migrate"
+	"github.com/Pranjal095/Memora/backend/internal/schema"
+)
+
+// repositories returns an empty pair of repositories for one test case.
+type repositories func(t *testing.T) (UserRepository, PhotoRepository)
+
+func TestMemoryRepositories(t *testing.T) {
+	runSuite(t, func(*testing.T) (UserRepository, PhotoRepository) {
+		return NewMemoryUsers(), NewMemoryPhotos()
+	})
+}
+
+// TestPgRepositories runs the suite against the database at
+// TEST_DATABASE_URL. It migrates the database and empties the users and
+// photos tables before every case, so don't point it at real data.
+func TestPgRepositories(t *testing.T) {
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+
+	c := context.Background()
+	db, err := pgxpool.New(c, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := migrate.Up(c, db, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	runSuite(t, func(t *testing.T) (UserRepository, PhotoRepository) {
+		if _, err := db.Exec(c, `TRUNCATE users, photos RESTART IDENTITY CASCADE`); err != nil {
+			t.Fatal(err)
+		}
+		return NewPgUsers(db), NewPgPhotos(db)
+	})
+}
+
+type suiteCase struct {
+	name string
+	run  func(t *testing.T, c context.Context, users UserRepository, photos PhotoRepository)
+}
+
+func runSuite(t *testing.T, fresh repositories) {
+	for _, tc := range slices.Concat(userCases, photoCases) {
+		t.Run(tc.name, func(t *testing.T) {
+			users, photos := fresh(t)
+			tc.run(t, context.Background(), users, photos)
+		})
+	}
+}
+
+func createUser(t *testing.T, c context.Context, users UserRepository, username string) string {
+	t.Helper()
+	id, err := users.Create(c, username, username+"@example.com", "hash")
+	if err != nil {
+		t.Fatalf("create user %s: %v", username, err)
+	}
+	return strconv.FormatInt(id, 10)
+}
+
+func createPhoto(t *testing.T, c context.Context, photos PhotoRepository, p NewPhoto) int64 {
+	t.Helper()
+	if p.URL == "" {
+		p.URL = "uploads/photo.jpg"
+	}
+	if p.MediaType == "" {
+		p.MediaType = "image"
+	}
+	id, err := photos.Create(c, p)
+	if err != nil {
+		t.Fatalf("create photo: %v", err)
+	}
+	return id
+}
+
+var userCases = []suiteCase{
+	{"user round trip", func(t *testing.T, c context.Context, users UserRepository, _ PhotoRepository) {
+		id, err := users.Create(c, "ada", "ada@example.com", "hash")
+		if err != nil {
+			t.Fatal(err)
+		}
+		byName, err := users.GetByUsername(c, "ada")
+		if err != nil {
+			t.Fatal(err)
+		}
+		byID, err := users.GetByID(c, strconv.FormatInt(id, 10))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, u := range []User{byName, byID} {
+			if u.ID != id || u.Username != "ada" || u.Email != "ada@example.com" || u.PasswordHash != "hash" || u.IsAdmin {
+				t.Errorf("got %+v", u)
+			}
+		}
+	}},
+	{"duplicate users", func(t *testing.T, c context.Context, users UserRepository, _ PhotoRepository) {
+		createUser(t, c, users, "ada")
+		tests := []struct{ username, email string }{
+			{"ada", "other@example.com"},
+			{"other", "ada@example.com"},
+		}
+		for _, tt := range tests {
+			if _, err := users.Create(c, tt.username, tt.email, "hash"); !errors.Is(err, ErrUserExists) {
+				t.Errorf("create %s <%s>: got %v, want ErrUserExists", tt.username, tt.email, err)
+			}
+		}
+	}},
+	{"missing users", func(t *testing.T, c context.Context, users UserRepository, _ PhotoRepository) {
+		createUser(t, c, users, "ada")
+		if _, err := users.GetByUsername(c, "grace"); !errors.Is(err, ErrUserNotFound) {
+			t.Errorf("GetByUsername: got %v, want ErrUserNotFound", err)
+		}
+		if _, err := users.GetByID(c, "999999"); !errors.Is(err, ErrUserNotFound) {
+			t.Errorf("GetByID: got %v, want ErrUserNotFound", err)
+		}
+	}},
+}
+
+var photoCases = []suiteCase{
+	{"list is per user and newest first", func(t *testing.T, c context.Context, users UserRepository, photos PhotoRepository) {
+		ada, grace := createUser(t, c, users, "ada"), createUser(t, c, users, "grace")
+		first := createPhoto(t, c, photos, NewPhoto{UserID: ada})
+		second := createPhoto(t, c, photos, NewPhoto{UserID: ada})
+		createPhoto(t, c, photos, NewPhoto{UserID: grace})
+
+		list, err := photos.ListByUser(c, ada)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(list) != 2 || list[0].ID != second || list[1].ID != first {
+			t.Errorf("got %+v, want photos %d then %d", list, second, first)
+		}
+	}},
+	{"zero values read back as absent", func(t *testing.T, c context.Context, users UserRepository, photos PhotoRepository) {
+		ada := createUser(t, c, users, "ada")
+		bare := createPhoto(t, c, photos, NewPhoto{UserID: ada, MimeType: "image/jpeg", SizeBytes: 10})
+		full := createPhoto(t, c, photos, NewPhoto{
+			UserID: ada, Note: "beach", MediaType: "video", Width: 640, Height: 480,
+			DurationMs: 1500, PosterURL: "uploads/poster.jpg", City: "Lisbon", Country: "Portugal",
+		})
+
+		got, err := photos.GetByIDs(c, ada, []int64{bare, full}, schema.SearchFilters{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		b := got[bare]
+		if b.Note == nil || *b.Note != "" || b.Width != nil || b.Height != nil || b.DurationMs != nil ||
+			b.PosterURL != nil || b.City != nil || b.Country != nil || b.Caption != nil ||
+			b.MimeType != "image/jpeg" || b.SizeBytes != 10 || b.MediaType != "image" {
+			t.Errorf("bare photo: got %+v", b)
+		}
+		f := got[full]
+		if f.Note == nil || *f.Note != "beach" || f.Width == nil || *f.Width != 640 || f.Height == nil || *f.Height != 480 ||
+			f.DurationMs == nil || *f.DurationMs != 1500 || f.PosterURL == nil || f.City == nil || *f.City != "Lisbon" ||
+			f.Country == nil || f.MediaType != "video" {
+			t.Errorf("full photo: got %+v", f)
+		}
+	}},
+	{"find by hash", func(t *testing.T, c context.Context, users UserRepository, photos PhotoRepository) {
+		ada, grace := createUser(t, c, users, "ada"), createUser(t, c, users, "grace")
+		first := createPhoto(t, c, photos, NewPhoto{UserID: ada, ContentHash: "abc"})
+		createPhoto(t, c, photos, NewPhoto{UserID: ada, ContentHash: "abc"})
+
+		tests := []struct {
+			user, hash string
+			want       int64
+		}{
+			{ada, "abc", first},
+			{ada, "def", 0},
+			{grace, "abc", 0},
+		}
+		for _, tt := range tests {
+			got, err := photos.FindByHash(c, tt.user, tt.hash)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("FindByHash(%s, %s) = %d, want %d", tt.user, tt.hash, got, tt.want)
+			}
+		}
+	}},
+	{"get by ids applies filters", func(t *testing.T, c context.Context, users UserRepository, photos PhotoRepository) {
+		ada, grace := createUser(t, c, users, "ada"), createUser(t, c, users, "grace")
+		lisbon := createPhoto(t, c, photos, NewPhoto{UserID: ada, Note: "tram", City: "Lisbon", Country: "Portugal"})
+		video := createPhoto(t, c, photos, NewPhoto{UserID: ada, MediaType: "video"})
+		other := createPhoto(t, c, photos, NewPhoto{UserID: grace, City: "Lisbon"})
+		ids := []int64{lisbon, video, other, 999999}
+
+		yes, no := true, false
+		past, future := time.Now().Add(-24*time.Hour), time.Now().Add(24*time.Hour)
+		tests := []struct {
+			name   string
+			filter schema.SearchFilters
+			want   []int64
+		}{
+			{"none", schema.SearchFilters{}, []int64{lisbon, video}},
+			{"city ignores case", schema.SearchFilters{City: "lisbon"}, []int64{lisbon}},
+			{"country", schema.SearchFilters{Country: "PORTUGAL"}, []int64{lisbon}},
+			{"media type", schema.SearchFilters{MediaType: "video"}, []int64{video}},
+			{"has note", schema.SearchFilters{HasNote: &yes}, []int64{lisbon}},
+			{"has no note", schema.SearchFilters{HasNote: &no}, []int64{video}},
+			{"from", schema.SearchFilters{From: &future}, nil},
+			{"to", schema.SearchFilters{To: &past}, nil},
+			{"range", schema.SearchFilters{From: &past, To: &future}, []int64{lisbon, video}},
+		}
+		for _, tt := range tests {
+			got, err := photos.GetByIDs(c, ada, ids, tt.filter)
+			if err != nil {
+				t.Fatalf("%s: %v", tt.name, err)
+			}
+			if len(got) != len(tt.want) {
+				t.Errorf("%s: got %d photos, want %v", tt.name, len(got), tt.want)
+				continue
+			}
+			for _, id := range tt.want {
+				if _, ok := got[id]; !ok {
+					t.Errorf("%s: photo %d missing", tt.name, id)
+				}
+			}
+		}
+	}},
+	{"captions", func(t *testing.T, c context.Context, users UserRepository, photos PhotoRepository) {
+		ada, grace := createUser(t, c, users, "ada"), createUser(t, c, users, "grace")
+		edited := createPhoto(t, c, photos, NewPhoto{UserID: ada})
+		generated := createPhoto(t, c, photos, NewPhoto{UserID: ada})
+
+		if _, err := photos.UpdateCaption(c, grace, edited, "mine"); !errors.Is(err, ErrPhotoNotFound) {
+			t.Errorf("update another user's photo: got %v, want ErrPhotoNotFound", err)
+		}
+		p, err := photos.UpdateCaption(c, ada, edited, "my caption")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p.Caption == nil || *p.Caption != "my caption" || !p.CaptionEdited {
+			t.Errorf("after update: got %+v", p)
+		}
+
+		err = photos.StoreCaptions(c, map[int64]string{edited: "a dog", generated: "a cat"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := photos.GetByIDs(c, ada, []int64{edited, generated}, schema.SearchFilters{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if e := got[edited]; e.Caption == nil || *e.Caption != "my caption" {
+			t.Errorf("edited caption was overwritten: %+v", e)
+		}
+		if g := got[generated]; g.Caption == nil || *g.Caption != "a cat" || g.CaptionEdited {
+			t.Errorf("generated caption: got %+v", g)
+		}
+
+		p, err = photos.UpdateCaption(c, ada, edited, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p.Caption == nil || *p.Caption != "my caption" || p.CaptionEdited {
+			t.Errorf("after clearing: got %+v", p)
+		}
+		if err := photos.StoreCaptions(c, map[int64]string{edited: "a dog"}); err != nil {
+			t.Fatal(err)
+		}
+		got, _ = photos.GetByIDs(c, ada, []int64{edited}, schema.SearchFilters{})
+		if e := got[edited]; e.Caption == nil || *e.Caption != "a dog" {
+			t.Errorf("cleared caption wasn't regenerated: %+v", e)
+		}
+	}},
+	{"keyword search", func(t *testing.T, c context.Context, users UserRepository, photos PhotoRepository) {
+		ada, grace := createUser(t, c, users, "ada"), createUser(t, c, users, "grace")
+		hills := createPhoto(t, c, photos, NewPhoto{UserID: ada, Note: "tram in the hills", City: "Lisbon", Country: "Portugal"})
+		yellow := createPhoto(t, c, photos, NewPhoto{UserID: ada})
+		river := createPhoto(t, c, photos, NewPhoto{UserID: ada, Note: "river", City: "Porto", Country: "Portugal"})
+		theirs := createPhoto(t, c, photos, NewPhoto{UserID: grace, Note: "tram"})
+		if err := photos.StoreCaptions(c, map[int64]string{yellow: "a yellow tram"}); err != nil {
+			t.Fatal(err)
+		}
+
+		tests := []struct {
+			user, q string
+			filter  schema.SearchFilters
+			limit   int
+			want    []int64
+		}{
+			// A note outweighs a caption, which outweighs a place.
+			{ada, "tram", schema.SearchFilters{}, 10, []int64{hills, yellow}},
+			{ada, "tram", schema.SearchFilters{}, 1, []int64{hills}},
+			{ada, "tram -yellow", schema.SearchFilters{}, 10, []int64{hills}},
+			{ada, `"yellow tram"`, schema.SearchFilters{}, 10, []int64{yellow}},
+			{ada, `"tram yellow"`, schema.SearchFilters{}, 10, nil},
+			// Equal scores are ordered newest id first.
+			{ada, "river or hills", schema.SearchFilters{}, 10, []int64{river, hills}},
+			{ada, "LISBON", schema.SearchFilters{}, 10, []int64{hills}},
+			{ada, "tram", schema.SearchFilters{City: "Porto"}, 10, nil},
+			{grace, "tram", schema.SearchFilters{}, 10, []int64{theirs}},
+		}
+		for _, tt := range tests {
+			hits, err := photos.KeywordSearch(c, tt.user, tt.q, tt.filter, tt.limit)
+			if err != nil {
+				t.Fatalf("%s: %v", tt.q, err)
+			}
+			var got []int64
+			for _, h := range hits {
+				got = append(got, h.ID)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("KeywordSearch(%q, %+v): got %v, want %v", tt.q, tt.filter, got, tt.want)
+			}
+		}
+	}},
+	{"filter", func(t *testing.T, c context.Context, users UserRepository, photos PhotoRepository) {
+		ada, grace := createUser(t, c, users, "ada"), createUser(t, c, users, "grace")
+		lisbon := createPhoto(t, c, photos, NewPhoto{UserID: ada, City: "Lisbon"})
+		video := createPhoto(t, c, photos, NewPhoto{UserID: ada, MediaType: "video"})
+		last := createPhoto(t, c, photos, NewPhoto{UserID: ada})
+		createPhoto(t, c, photos, NewPhoto{UserID: grace, City: "Lisbon"})
+
+		tests := []struct {
+			filter schema.SearchFilters
+			limit  int
+			want   []int64
+		}{
+			{schema.SearchFilters{}, 10, []int64{last, video, lisbon}},
+			{schema.SearchFilters{}, 2, []int64{last, video}},
+			{schema.SearchFilters{City: "LISBON"}, 10, []int64{lisbon}},
+			{schema.SearchFilters{MediaType: "video"}, 10, []int64{video}},
+			{schema.SearchFilters{Country: "France"}, 10, nil},
+		}
+		for _, tt := range tests {
+			got, err := photos.Filter(c, ada, tt.filter, tt.limit)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Filter(%+v, %d): got %v, want %v", tt.filter, tt.limit, got, tt.want)
+			}
+		}
+	}},
+	{"places", func(t *testing.T, c context.Context, users UserRepository, photos PhotoRepository) {
+		ada, grace := createUser(t, c, users, "ada"), createUser(t, c, users, "grace")
+		createPhoto(t, c, photos, NewPhoto{UserID: ada, City: "Lisbon", Country: "Portugal"})
+		createPhoto(t, c, photos, NewPhoto{UserID: ada, City: "Porto", Country: "Portugal"})
+		createPhoto(t, c, photos, NewPhoto{UserID: ada})
+		createPhoto(t, c, photos, NewPhoto{UserID: grace, City: "Paris", Country: "France"})
+
+		cities, countries, err := photos.Places(c, ada)
+		if err != nil {
+			t.Fatal(err)
+		}
+		slices.Sort(cities)
+		if !slices.Equal(cities, []string{"Lisbon", "Porto"}) || !slices.Equal(countries, []string{"Portugal"}) {
+			t.Errorf("got cities %v, countries %v", cities, countries)
+		}
+	}},
+	{"embed source", func(t *testing.T, c context.Context, users UserRepository, photos PhotoRepository) {
+		ada, grace := createUser(t, c, users, "ada"), createUser(t, c, users, "grace")
+		video := createPhoto(t, c, photos, NewPhoto{
+			UserID: ada, URL: "uploads/clip.mp4", Note: "surf", MediaType: "video", PosterURL: "uploads/poster.jpg",
+			Keyframes: []string{"uploads/k1.jpg", "uploads/k2.jpg"}, City: "Lisbon", Country: "Portugal",
+		})
+		image := createPhoto(t, c, photos, NewPhoto{UserID: ada})
+		if err := photos.StoreCaptions(c, map[int64]string{video: "waves", image: "a cat"}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := photos.UpdateCaption(c, ada, image, "my cat"); err != nil {
+			t.Fatal(err)
+		}
+
+		s, err := photos.EmbedSource(c, ada, video)
+		if err != nil {
+			t.Fatal(err)
+		}
+		owner, _ := strconv.ParseInt(ada, 10, 64)
+		if s.ID != video || s.UserID != owner || s.URL != "uploads/clip.mp4" || s.Note != "surf" ||
+			s.MediaType != "video" || s.PosterURL != "uploads/poster.jpg" || len(s.Keyframes) != 2 ||
+			s.City != "Lisbon" || s.Country != "Portugal" || s.CreatedAt.IsZero() {
+			t.Errorf("video: got %+v", s)
+		}
+		// Generated captions are made again; only the user's own is kept.
+		if s.Caption != "" {
+			t.Errorf("video: got generated caption %q", s.Caption)
+		}
+		if s, err := photos.EmbedSource(c, ada, image); err != nil || s.Caption != "my cat" || s.PosterURL != "" {
+			t.Errorf("image: got %+v, %v", s, err)
+		}
+		if _, err := photos.EmbedSource(c, grace, video); !errors.Is(err, ErrPhotoNotFound) {
+			t.Errorf("another user's photo: got %v, want ErrPhotoNotFound", err)
+		}
+	}},
+	{"maintenance reads", func(t *testing.T, c context.Context, users UserRepository, photos PhotoRepository) {
+		ada, grace := createUser(t, c, users, "ada"), createUser(t, c, users, "grace")
+		var ids []int64
+		for _, owner := range []string{ada, grace, ada, ada} {
+			ids = append(ids, createPhoto(t, c, photos, NewPhoto{UserID: owner}))
+		}
+		adaID, _ := strconv.ParseInt(ada, 10, 64)
+		sourceIDs := func(sources []EmbedSource, err error) []int64 {
+			t.Helper()
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []int64
+			for _, s := range sources {
+				got = append(got, s.ID)
+			}
+			return got
+		}
+
+		// Timestamps are compared two days out, so that the database's time
+		// zone doesn't matter.
+		later := time.Now().Add(48 * time.Hour)
+		counts := []struct {
+			scope PhotoScope
+			want  int64
+		}{
+			{PhotoScope{}, 4},
+			{PhotoScope{UserID: adaID}, 3},
+			{PhotoScope{To: &later}, 4},
+			{PhotoScope{From: &later}, 0},
+		}
+		for _, tt := range counts {
+			if n, err := photos.Count(c, tt.scope); err != nil || n != tt.want {
+				t.Errorf("count %+v: got %d, %v, want %d", tt.scope, n, err, tt.want)
+			}
+		}
+
+		scope := PhotoScope{UserID: adaID}
+		if got := sourceIDs(photos.EmbedSourcesAfter(c, scope, 0, 2)); !slices.Equal(got, []int64{ids[0], ids[2]}) {
+			t.Errorf("first page: got %v", got)
+		}
+		if got := sourceIDs(photos.EmbedSourcesAfter(c, scope, ids[2], 2)); !slices.Equal(got, []int64{ids[3]}) {
+			t.Errorf("last page: got %v", got)
+		}
+
+		if err := photos.Delete(c, grace, ids[1]); err != nil {
+			t.Fatal(err)
+		}
+		asked := []int64{ids[3], ids[1], ids[0]}
+		if got := sourceIDs(photos.EmbedSourcesByIDs(c, asked)); !slices.Equal(got, []int64{ids[0], ids[3]}) {
+			t.Errorf("by ids: got %v", got)
+		}
+		if got, err := photos.ExistingIDs(c, asked); err != nil || !slices.Equal(got, []int64{ids[0], ids[3]}) {
+			t.Errorf("existing: got %v, %v", got, err)
+		}
+	}},
+	{"edited embed sources", func(t *testing.T, c context.Context, users UserRepository, photos PhotoRepository) {
+		ada := createUser(t, c, users, "ada")
+		var ids []int64
+		for range 3 {
+			ids = append(ids, createPhoto(t, c, photos, NewPhoto{UserID: ada}))
+		}
+		for _, id := range []int64{ids[0], ids[2]} {
+			if _, err := photos.UpdateCaption(c, ada, id, "mine"); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		earlier, later := time.Now().Add(-48*time.Hour), time.Now().Add(48*time.Hour)
+		tests := []struct {
+			name  string
+			since time.Time
+			maxID int64
+			want  []int64
+		}{
+			{"all edited", earlier, ids[2], []int64{ids[0], ids[2]}},
+			{"up to an id", earlier, ids[1], []int64{ids[0]}},
+			{"none since", later, ids[2], nil},
+		}
+		for _, tt := range tests {
+			sources, err := photos.EditedEmbedSources(c, tt.since, tt.maxID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []int64
+			for _, s := range sources {
+				got = append(got, s.ID)
+				if s.Caption != "mine" {
+					t.Errorf("%s: photo %d has caption %q", tt.name, s.ID, s.Caption)
+				}
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+			}
+		}
+	}},
+	{"file refs", func(t *testing.T, c context.Context, users UserRepository, photos PhotoRepository) {
+		ada, grace := createUser(t, c, users, "ada"), createUser(t, c, users, "grace")
+		image := createPhoto(t, c, photos, NewPhoto{UserID: ada, URL: "uploads/a.jpg"})
+		video := createPhoto(t, c, photos, NewPhoto{
+			UserID: grace, URL: "uploads/b.mp4", MediaType: "video", PosterURL: "uploads/b.jpg",
+			Keyframes: []string{"uploads/b1.jpg", "uploads/b2.jpg"},
+		})
+
+		refs, err := photos.FileRefs(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(refs) != 2 {
+			t.Fatalf("got %+v, want two photos", refs)
+		}
+		adaID, _ := strconv.ParseInt(ada, 10, 64)
+		graceID, _ := strconv.ParseInt(grace, 10, 64)
+		if r := refs[0]; r.ID != image || r.UserID != adaID || r.URL != "uploads/a.jpg" || r.PosterURL != "" || len(r.Keyframes) != 0 {
+			t.Errorf("image: got %+v", r)
+		}
+		if r := refs[1]; r.ID != video || r.UserID != graceID || r.URL != "uploads/b.mp4" || r.PosterURL != "uploads/b.jpg" ||
+			!slices.Equal(r.Keyframes, []string{"uploads/b1.jpg", "uploads/b2.jpg"}) {
+			t.Errorf("video: got %+v", r)
+		}
+	}},
+	{"delete", func(t *testing.T, c context.Context, users UserRepository, photos PhotoRepository) {
+		ada, grace := createUser(t, c, users, "ada"), createUser(t, c, users, "grace")
+		id := createPhoto(t, c, photos, NewPhoto{UserID: ada})
+
+		if err := photos.Delete(c, grace, id); !errors.Is(err, ErrPhotoNotFound) {
+			t.Errorf("delete another user's photo: got %v, want ErrPhotoNotFound", err)
+		}
+		if err := photos.Delete(c, ada, id); err != nil {
+			t.Fatal(err)
+		}
+		if err := photos.Delete(c, ada, id); !errors.Is(err, ErrPhotoNotFound) {
+			t.Errorf("delete twice: got %v, want ErrPhotoNotFound", err)
+		}
+		if list, _ := photos.ListByUser(c, ada); len(list) != 0 {
+			t.Errorf("deleted photo still listed: %+v", list)
+		}
+	}},
+}
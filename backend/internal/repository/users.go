@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PgUsers keeps users in the users table.
+type PgUsers struct {
+	db *pgxpool.Pool
+}
+
+func NewPgUsers(db *pgxpool.Pool) *PgUsers {
+	return &PgUsers{db: db}
+}
+
+func (r *PgUsers) Create(c context.Context, username, email, passwordHash string) (int64, error) {
+	var id int64
+	err := r.db.QueryRow(c,
+		"INSERT INTO users(username,email,password) VALUES($1,$2,$3) RETURNING id",
+		username, email, passwordHash).Scan(&id)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return 0, ErrUserExists
+	}
+	if err != nil {
+		return 0, fmt.Errorf("create user: %w", err)
+	}
+	return id, nil
+}
+
+const userColumns = `id,username,email,password,is_admin,created_at`
+
+func (r *PgUsers) getUser(c context.Context, where string, arg any) (User, error) {
+	var u User
+	err := r.db.QueryRow(c, `SELECT `+userColumns+` FROM users WHERE `+where, arg).
+		Scan(&u.ID, &u.Username, &u.Email, &u.PasswordHash, &u.IsAdmin, &u.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return u, ErrUserNotFound
+	}
+	if err != nil {
+		return u, fmt.Errorf("lookup user: %w", err)
+	}
+	return u, nil
+}
+
+func (r *PgUsers) GetByID(c context.Context, id string) (User, error) {
+	return r.getUser(c, "id=$1", id)
+}
+
+func (r *PgUsers) GetByUsername(c context.Context, username string) (User, error) {
+	return r.getUser(c, "username=$1", username)
+}
+
+var _ UserRepository = (*PgUsers)(nil)
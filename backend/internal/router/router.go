@@ -2,8 +2,11 @@ package router
 
 import (
 	"fmt"
+	"net/http"
 	"os"
 
+	"github.com/Pranjal095/Memora/backend/config"
+	"github.com/Pranjal095/Memora/backend/internal/repository"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 )
@@ -22,7 +25,7 @@ func LogFormatter(params gin.LogFormatterParams) string {
 	)
 }
 
-func SetupRouter() *gin.Engine {
+func SetupRouter(cfg *config.Config, users repository.UserRepository, photos repository.PhotoRepository) *gin.Engine {
 	gin.SetMode(gin.ReleaseMode)
 	router := gin.Default()
 	fmt.Println("\033[36mGo Gin server started.\033[0m")
@@ -31,17 +34,21 @@ func SetupRouter() *gin.Engine {
 		Formatter: LogFormatter,
 	}))
 
-	config := cors.DefaultConfig()
-	config.AllowOrigins = []string{"*"}
-	config.AllowMethods = []string{"GET", "POST", "PUT", "DELETE"}
-	config.AllowHeaders = []string{"*"}
-	config.AllowHeaders = []string{"Content-Type"}
-	config.AllowHeaders = []string{"X-Requested-With", "Content-Type", "Accept"}
-	config.AllowCredentials = true
-	router.Use(cors.New(config))
+	corsConfig := cors.DefaultConfig()
+	corsConfig.AllowOrigins = []string{"*"}
+	corsConfig.AllowMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS"}
+	corsConfig.AllowHeaders = []string{"*"}
+	corsConfig.AllowHeaders = []string{"Content-Type"}
+	corsConfig.AllowHeaders = []string{"X-Requested-With", "Content-Type", "Accept", "Authorization",
+		"Tus-Resumable", "Upload-Length", "Upload-Metadata", "Upload-Offset", "Upload-Checksum", "Idempotency-Key"}
+	corsConfig.ExposeHeaders = []string{"Location", "Tus-Resumable", "Tus-Version", "Tus-Extension",
+		"Tus-Max-Size", "Tus-Checksum-Algorithm", "Upload-Offset", "Upload-Length", "Upload-Expires", "Memora-Photo-Id", "Idempotent-Replayed"}
+	corsConfig.AllowCredentials = true
+	router.Use(cors.New(corsConfig))
 
-	router.Static("/uploads", "./uploads")
-	SetupRoutes(router)
+	// GET only: HEAD on /uploads/tus/:id belongs to the tus handlers.
+	router.GET("/uploads/*filepath", gin.WrapH(http.StripPrefix("/uploads", http.FileServer(gin.Dir("./uploads", false)))))
+	SetupRoutes(router, cfg, users, photos)
 
 	return router
 }
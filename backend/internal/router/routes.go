@@ -3,8 +3,11 @@ package router
 import (
 	"net/http"
 
+	"github.com/Pranjal095/Memora/backend/config"
 	"github.com/Pranjal095/Memora/backend/internal/controller"
+	"github.com/Pranjal095/Memora/backend/internal/helpers"
 	"github.com/Pranjal095/Memora/backend/internal/middleware"
+	"github.com/Pranjal095/Memora/backend/internal/repository"
 	"github.com/gin-gonic/gin"
 )
 
@@ -15,13 +18,50 @@ func home(c *gin.Context) {
 	c.Writer.Write([]byte(HTMLString))
 }
 
-func SetupRoutes(router *gin.Engine) {
+func SetupRoutes(router *gin.Engine, cfg *config.Config, users repository.UserRepository, photos repository.PhotoRepository) {
+	h := controller.New(cfg, users, photos)
+	auth := middleware.AuthMiddleware(cfg.JWTSecret)
+	idempotent := middleware.IdempotencyMiddleware(middleware.SmallBodyBytes)
+	idempotentUpload := middleware.IdempotencyMiddleware(helpers.MaxRequestBytes(cfg.MaxUploadBytes, 1))
+	idempotentBatch := middleware.IdempotencyMiddleware(helpers.MaxRequestBytes(cfg.MaxUploadBytes, cfg.BatchUploadMaxFiles))
+
 	router.GET("/", home)
-	router.POST("/signup", middleware.RateLimitMiddleware(), controller.Signup)
-	router.POST("/login", middleware.RateLimitMiddleware(), controller.Login)
-	router.POST("2fa/setup", middleware.RateLimitMiddleware(), controller.Setup2FA)
-	router.POST("2fa/verify", middleware.RateLimitMiddleware(), controller.Verify2FA)
-	router.POST("/photos", middleware.AuthMiddleware(), controller.AddPhoto)
-	router.GET("/photos", middleware.AuthMiddleware(), controller.ListPhotos)
-	router.GET("/search", middleware.AuthMiddleware(), controller.SearchPhotos)
+	router.GET("/readyz", controller.Readyz)
+	router.POST("/signup", middleware.RateLimitMiddleware(), idempotent, h.Signup)
+	router.POST("/login", middleware.RateLimitMiddleware(), h.Login)
+	router.POST("2fa/setup", middleware.RateLimitMiddleware(), h.Setup2FA)
+	router.POST("2fa/verify", middleware.RateLimitMiddleware(), h.Verify2FA)
+	router.POST("/photos", auth, idempotentUpload, h.AddPhoto)
+	router.POST("/photos/batch", auth, idempotentBatch, h.AddPhotosBatch)
+	router.GET("/photos", auth, h.ListPhotos)
+	router.PATCH("/photos/:id", auth, h.UpdatePhoto)
+	router.GET("/photos/:id/similar", auth, h.SimilarPhotos)
+
+	router.POST("/analyze", auth, idempotentUpload, h.SubmitAnalysis)
+	router.GET("/analyze", auth, controller.ListAnalyses)
+	router.GET("/analyze/:id", auth, controller.GetAnalysis)
+	router.DELETE("/analyze/:id", auth, controller.CancelAnalysis)
+
+	admin := router.Group("/admin", auth, middleware.AdminMiddleware(users))
+	admin.DELETE("/analysis/cache", controller.InvalidateAnalysisCache)
+	admin.GET("/inference/stats", controller.InferenceStats)
+	admin.POST("/reindex", h.StartReindex)
+	admin.GET("/reindex", controller.ListReindexRuns)
+	admin.GET("/reindex/:id", controller.GetReindexRun)
+	admin.POST("/reindex/:id/resume", h.ResumeReindex)
+	admin.DELETE("/reindex/:id", controller.PauseReindex)
+	admin.POST("/fsck", h.StartFsck)
+	admin.GET("/fsck", controller.ListFsckRuns)
+	admin.GET("/fsck/:id", controller.GetFsckRun)
+
+	tus := router.Group("/uploads/tus", middleware.TusMiddleware())
+	tus.OPTIONS("", h.TusOptions)
+	tus.OPTIONS("/:id", h.TusOptions)
+	tus.POST("", auth, idempotent, h.TusCreate)
+	tus.HEAD("/:id", auth, h.TusHead)
+	tus.PATCH("/:id", auth, h.TusPatch)
+	tus.DELETE("/:id", auth, h.TusDelete)
+
+	router.GET("/search", auth, h.SearchPhotos)
+	router.POST("/search/by-image", auth, h.SearchByImage)
 }
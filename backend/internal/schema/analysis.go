@@ -0,0 +1,52 @@
+package schema
+
+type SegmentOptions struct {
+	WindowSeconds float64 `json:"window_seconds,omitempty" form:"window_seconds"`
+	StrideSeconds float64 `json:"stride_seconds,omitempty" form:"stride_seconds"`
+	Threshold     float64 `json:"threshold,omitempty" form:"threshold"`
+	FlaggedOnly   bool    `json:"flagged_only,omitempty" form:"flagged_only"`
+}
+
+type AnalyzeRequest struct {
+	URL string `json:"url" binding:"required,url"`
+	SegmentOptions
+}
+
+type AnalysisSegment struct {
+	StartMs     int64   `json:"start_ms"`
+	EndMs       int64   `json:"end_ms"`
+	Probability float64 `json:"probability"`
+	Label       string  `json:"label"`
+	Flagged     bool    `json:"flagged"`
+}
+
+type TimeRange struct {
+	StartMs int64 `json:"start_ms"`
+	EndMs   int64 `json:"end_ms"`
+}
+
+type SegmentStats struct {
+	Count         int     `json:"count"`
+	Threshold     float64 `json:"threshold"`
+	Max           float64 `json:"max"`
+	Mean          float64 `json:"mean"`
+	FractionAbove float64 `json:"fraction_above"`
+}
+
+type AnalysisJobResponse struct {
+	ID            int64             `json:"id"`
+	Status        string            `json:"status"`
+	SourceURL     *string           `json:"source_url,omitempty"`
+	Filename      *string           `json:"filename,omitempty"`
+	Options       *SegmentOptions   `json:"options,omitempty"`
+	Probability   *float64          `json:"probability,omitempty"`
+	Label         *string           `json:"label,omitempty"`
+	Cached        bool              `json:"cached"`
+	Segments      []AnalysisSegment `json:"segments,omitempty"`
+	Stats         *SegmentStats     `json:"stats,omitempty"`
+	FlaggedRanges []TimeRange       `json:"flagged_ranges,omitempty"`
+	Error         *string           `json:"error,omitempty"`
+	CreatedAt     string            `json:"created_at"`
+	StartedAt     *string           `json:"started_at,omitempty"`
+	FinishedAt    *string           `json:"finished_at,omitempty"`
+}
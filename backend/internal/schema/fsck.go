@@ -0,0 +1,36 @@
+package schema
+
+type FsckRequest struct {
+	Repair []string `json:"repair,omitempty"`
+}
+
+type FsckMissingFile struct {
+	PhotoID int64  `json:"photo_id"`
+	UserID  int64  `json:"user_id"`
+	Path    string `json:"path"`
+}
+
+type FsckReport struct {
+	Photos         int64             `json:"photos"`
+	Files          int64             `json:"files"`
+	Vectors        int64             `json:"vectors"`
+	MissingFiles   []FsckMissingFile `json:"missing_files"`
+	OrphanFiles    []string          `json:"orphan_files"`
+	MissingVectors []int64           `json:"missing_vectors"`
+	OrphanVectors  []int64           `json:"orphan_vectors"`
+	Reembedded     int               `json:"reembedded,omitempty"`
+	ReembedFailed  int               `json:"reembed_failed,omitempty"`
+	DeletedFiles   int               `json:"deleted_files,omitempty"`
+	DeletedVectors int               `json:"deleted_vectors,omitempty"`
+	Quarantined    int               `json:"quarantined,omitempty"`
+}
+
+type FsckRun struct {
+	ID         int64       `json:"id"`
+	Status     string      `json:"status"`
+	Repair     []string    `json:"repair"`
+	Report     *FsckReport `json:"report,omitempty"`
+	Error      *string     `json:"error,omitempty"`
+	CreatedAt  string      `json:"created_at"`
+	FinishedAt *string     `json:"finished_at,omitempty"`
+}
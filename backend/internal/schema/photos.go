@@ -5,8 +5,38 @@ type AddPhotoRequest struct {
 }
 
 type PhotoResponse struct {
-	ID        int64   `json:"id"`
-	URL       string  `json:"url"`
-	Note      *string `json:"note,omitempty"`
-	CreatedAt string  `json:"created_at"`
+	ID            int64   `json:"id"`
+	URL           string  `json:"url"`
+	Note          *string `json:"note,omitempty"`
+	MimeType      string  `json:"mime_type,omitempty"`
+	SizeBytes     int64   `json:"size_bytes,omitempty"`
+	Width         *int    `json:"width,omitempty"`
+	Height        *int    `json:"height,omitempty"`
+	MediaType     string  `json:"media_type"`
+	DurationMs    *int64  `json:"duration_ms,omitempty"`
+	PosterURL     *string `json:"poster_url,omitempty"`
+	City          *string `json:"city,omitempty"`
+	Country       *string `json:"country,omitempty"`
+	Caption       *string `json:"caption,omitempty"`
+	CaptionEdited bool    `json:"caption_edited"`
+	CreatedAt     string  `json:"created_at"`
+}
+
+// UpdatePhotoRequest overrides the generated caption. An empty caption
+// goes back to the generated one.
+type UpdatePhotoRequest struct {
+	Caption *string `json:"caption"`
+}
+
+type BatchUploadResult struct {
+	Index     int            `json:"index"`
+	Filename  string         `json:"filename"`
+	ID        int64          `json:"id,omitempty"`
+	Duplicate bool           `json:"duplicate"`
+	Error     string         `json:"error,omitempty"`
+	Photo     *PhotoResponse `json:"photo,omitempty"`
+}
+
+type BatchUploadResponse struct {
+	Results []BatchUploadResult `json:"results"`
 }
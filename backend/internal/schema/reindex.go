@@ -0,0 +1,27 @@
+package schema
+
+import "time"
+
+type ReindexRequest struct {
+	UserID      int64      `json:"user_id,omitempty"`
+	From        *time.Time `json:"from,omitempty"`
+	To          *time.Time `json:"to,omitempty"`
+	Concurrency int        `json:"concurrency,omitempty"`
+}
+
+type ReindexRun struct {
+	ID           int64   `json:"id"`
+	Status       string  `json:"status"`
+	UserID       *int64  `json:"user_id,omitempty"`
+	From         *string `json:"from,omitempty"`
+	To           *string `json:"to,omitempty"`
+	Target       *string `json:"target,omitempty"`
+	Total        int64   `json:"total"`
+	Done         int64   `json:"done"`
+	Failed       int64   `json:"failed"`
+	CheckpointID int64   `json:"checkpoint_id"`
+	Error        *string `json:"error,omitempty"`
+	CreatedAt    string  `json:"created_at"`
+	UpdatedAt    string  `json:"updated_at"`
+	FinishedAt   *string `json:"finished_at,omitempty"`
+}
@@ -0,0 +1,48 @@
+package schema
+
+import "time"
+
+// SearchFilters narrow a search to a subset of the user's photos. To is
+// exclusive.
+type SearchFilters struct {
+	From      *time.Time `json:"from,omitempty"`
+	To        *time.Time `json:"to,omitempty"`
+	City      string     `json:"city,omitempty"`
+	Country   string     `json:"country,omitempty"`
+	MediaType string     `json:"media_type,omitempty"`
+	HasNote   *bool      `json:"has_note,omitempty"`
+}
+
+// InterpretedFilter is a filter read out of the query text. Text is the
+// phrase it came from, so clients can show it as a removable chip.
+type InterpretedFilter struct {
+	Field string     `json:"field"`
+	Text  string     `json:"text"`
+	Value string     `json:"value,omitempty"`
+	From  *time.Time `json:"from,omitempty"`
+	To    *time.Time `json:"to,omitempty"`
+}
+
+type SearchResult struct {
+	PhotoResponse
+	Score        float64 `json:"score"`
+	KeywordRank  *int    `json:"keyword_rank,omitempty"`
+	SemanticRank *int    `json:"semantic_rank,omitempty"`
+}
+
+type SearchResponse struct {
+	Query       string              `json:"query"`
+	Text        string              `json:"text"`
+	Mode        string              `json:"mode"`
+	Filters     SearchFilters       `json:"filters"`
+	Interpreted []InterpretedFilter `json:"interpreted"`
+	Offset      int                 `json:"offset"`
+	Limit       int                 `json:"limit"`
+	NextOffset  *int                `json:"next_offset,omitempty"`
+	Results     []SearchResult      `json:"results"`
+}
+
+type SimilarPhotosResponse struct {
+	PhotoID *int64         `json:"photo_id,omitempty"`
+	Results []SearchResult `json:"results"`
+}
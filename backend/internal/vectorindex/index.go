@@ -0,0 +1,71 @@
+// Package vectorindex stores photo embeddings and answers nearest-neighbour
+// queries over them.
+package vectorindex
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var ErrDimensionMismatch = errors.New("vector has the wrong number of dimensions")
+
+// Payload is the photo metadata stored next to each vector so that searches
+// can be filtered without a round trip to Postgres.
+type Payload struct {
+	UserID    int64  `json:"user_id"`
+	CreatedAt int64  `json:"created_at"`
+	Note      string `json:"note"`
+	Caption   string `json:"caption"`
+	City      string `json:"city"`
+	Country   string `json:"country"`
+	MediaType string `json:"media_type"`
+}
+
+// Point is one photo's embedding. Its ID is the photo id.
+type Point struct {
+	ID      int64
+	Vector  []float32
+	Payload Payload
+}
+
+// Filter restricts a query to the points matching every set field. To is
+// exclusive; City and Country match case-insensitively.
+type Filter struct {
+	UserID     int64
+	IDs        []int64
+	ExcludeIDs []int64
+	From       *time.Time
+	To         *time.Time
+	City       string
+	Country    string
+	MediaType  string
+	HasNote    *bool
+}
+
+type Hit struct {
+	ID    int64   `json:"id"`
+	Score float64 `json:"score"`
+}
+
+// VectorIndex is implemented by each vector store backend. Scores are cosine
+// similarities, higher is closer.
+type VectorIndex interface {
+	Upsert(c context.Context, points ...Point) error
+	Delete(c context.Context, ids ...int64) error
+	Search(c context.Context, vector []float32, f Filter, limit int) ([]Hit, error)
+	Count(c context.Context, f Filter) (int64, error)
+	// Scroll returns up to limit points with ids from `from` upwards in id
+	// order, vectors included, and the id to continue from, or 0 at the end.
+	Scroll(c context.Context, f Filter, from int64, limit int) ([]Point, int64, error)
+}
+
+// Generations is implemented by backends that can build a new generation of
+// the index next to the live one and then switch readers over atomically.
+type Generations interface {
+	// Generation returns an index that writes to the named generation,
+	// creating it if needed.
+	Generation(c context.Context, name string) (VectorIndex, error)
+	// Promote makes the named generation the live index.
+	Promote(c context.Context, name string) error
+}
@@ -0,0 +1,152 @@
+package vectorindex
+
+import (
+	"context"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Memory is a brute-force index kept in process memory, for tests and local
+// runs. Every search scans all points. Like a Qdrant collection, it is sized
+// to the first vector written.
+type Memory struct {
+	mu     sync.RWMutex
+	dims   int
+	points map[int64]Point
+}
+
+func NewMemory() *Memory {
+	return &Memory{points: make(map[int64]Point)}
+}
+
+// Upsert stores all of points or, if any has the wrong dimensions, none.
+func (m *Memory) Upsert(_ context.Context, points ...Point) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	dims := m.dims
+	for _, p := range points {
+		if dims == 0 {
+			dims = len(p.Vector)
+		}
+		if len(p.Vector) != dims {
+			return ErrDimensionMismatch
+		}
+	}
+
+	m.dims = dims
+	for _, p := range points {
+		p.Vector = slices.Clone(p.Vector)
+		m.points[p.ID] = p
+	}
+	return nil
+}
+
+func (m *Memory) Delete(_ context.Context, ids ...int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, id := range ids {
+		delete(m.points, id)
+	}
+	return nil
+}
+
+func (m *Memory) Search(_ context.Context, vector []float32, f Filter, limit int) ([]Hit, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var hits []Hit
+	for _, p := range m.points {
+		if !f.matches(p) {
+			continue
+		}
+		if len(p.Vector) != len(vector) {
+			return nil, ErrDimensionMismatch
+		}
+		hits = append(hits, Hit{ID: p.ID, Score: cosine(vector, p.Vector)})
+	}
+
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].ID < hits[j].ID
+	})
+	if len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return hits, nil
+}
+
+func (m *Memory) Count(_ context.Context, f Filter) (int64, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var n int64
+	for _, p := range m.points {
+		if f.matches(p) {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (m *Memory) Scroll(_ context.Context, f Filter, from int64, limit int) ([]Point, int64, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var ids []int64
+	for id, p := range m.points {
+		if id >= from && f.matches(p) {
+			ids = append(ids, id)
+		}
+	}
+	slices.Sort(ids)
+
+	var next int64
+	if len(ids) > limit {
+		next = ids[limit]
+		ids = ids[:limit]
+	}
+	points := make([]Point, len(ids))
+	for i, id := range ids {
+		points[i] = m.points[id]
+		points[i].Vector = slices.Clone(points[i].Vector)
+	}
+	return points, next, nil
+}
+
+func (f Filter) matches(p Point) bool {
+	switch {
+	case f.UserID != 0 && p.Payload.UserID != f.UserID,
+		len(f.IDs) > 0 && !slices.Contains(f.IDs, p.ID),
+		slices.Contains(f.ExcludeIDs, p.ID),
+		f.From != nil && p.Payload.CreatedAt < f.From.Unix(),
+		f.To != nil && p.Payload.CreatedAt >= f.To.Unix(),
+		f.City != "" && !strings.EqualFold(p.Payload.City, f.City),
+		f.Country != "" && !strings.EqualFold(p.Payload.Country, f.Country),
+		f.MediaType != "" && p.Payload.MediaType != f.MediaType,
+		f.HasNote != nil && (strings.TrimSpace(p.Payload.Note) != "") != *f.HasNote:
+		return false
+	}
+	return true
+}
+
+func cosine(a, b []float32) float64 {
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(na) * math.Sqrt(nb))
+}
+
+var _ VectorIndex = (*Memory)(nil)
@@ -0,0 +1,224 @@
+package vectorindex
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+// day is midnight UTC on the given day of January 2024.
+func day(d int) time.Time {
+	return time.Date(2024, time.January, d, 0, 0, 0, 0, time.UTC)
+}
+
+// memoryFixture holds five photos of two users, all with the same vector so
+// that only filters decide what is found.
+func memoryFixture(t *testing.T) *Memory {
+	t.Helper()
+	m := NewMemory()
+	v := []float32{1, 0, 0}
+	points := []Point{
+		{ID: 1, Vector: v, Payload: Payload{UserID: 7, CreatedAt: day(1).Unix(), City: "Lisbon", Country: "Portugal", MediaType: "image", Note: "tram"}},
+		{ID: 2, Vector: v, Payload: Payload{UserID: 7, CreatedAt: day(2).Unix(), City: "Porto", Country: "Portugal", MediaType: "video", Note: "  "}},
+		{ID: 3, Vector: v, Payload: Payload{UserID: 7, CreatedAt: day(3).Unix(), City: "Paris", Country: "France", MediaType: "image"}},
+		{ID: 4, Vector: v, Payload: Payload{UserID: 8, CreatedAt: day(1).Unix(), City: "Lisbon", Country: "Portugal", MediaType: "image", Note: "bridge"}},
+		{ID: 5, Vector: v, Payload: Payload{UserID: 8, CreatedAt: day(4).Unix(), MediaType: "video"}},
+	}
+	if err := m.Upsert(context.Background(), points...); err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestMemoryFilters(t *testing.T) {
+	m := memoryFixture(t)
+	from, to := day(2), day(3)
+	yes, no := true, false
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   []int64
+	}{
+		{"none", Filter{}, []int64{1, 2, 3, 4, 5}},
+		{"user", Filter{UserID: 7}, []int64{1, 2, 3}},
+		{"ids", Filter{IDs: []int64{2, 4, 99}}, []int64{2, 4}},
+		{"exclude ids", Filter{UserID: 7, ExcludeIDs: []int64{1}}, []int64{2, 3}},
+		{"from is inclusive", Filter{From: &from}, []int64{2, 3, 5}},
+		{"to is exclusive", Filter{To: &to}, []int64{1, 2, 4}},
+		{"range", Filter{From: &from, To: &to}, []int64{2}},
+		{"city ignores case", Filter{City: "LISBON"}, []int64{1, 4}},
+		{"country", Filter{Country: "portugal", UserID: 7}, []int64{1, 2}},
+		{"media type", Filter{MediaType: "video"}, []int64{2, 5}},
+		{"has note", Filter{HasNote: &yes}, []int64{1, 4}},
+		{"blank note counts as none", Filter{HasNote: &no, UserID: 7}, []int64{2, 3}},
+		{"no match", Filter{UserID: 7, City: "Berlin"}, nil},
+	}
+	c := context.Background()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hits, err := m.Search(c, []float32{1, 0, 0}, tt.filter, 10)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []int64
+			for _, h := range hits {
+				got = append(got, h.ID)
+			}
+			slices.Sort(got)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Search: got %v, want %v", got, tt.want)
+			}
+
+			n, err := m.Count(c, tt.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n != int64(len(tt.want)) {
+				t.Errorf("Count: got %d, want %d", n, len(tt.want))
+			}
+		})
+	}
+}
+
+func TestMemorySearchOrdersByCosine(t *testing.T) {
+	c := context.Background()
+	m := NewMemory()
+	err := m.Upsert(c,
+		Point{ID: 1, Vector: []float32{0, 1}},
+		Point{ID: 2, Vector: []float32{1, 1}},
+		Point{ID: 3, Vector: []float32{2, 0}},
+		Point{ID: 4, Vector: []float32{-1, 0}},
+		Point{ID: 5, Vector: []float32{3, 0}},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		limit int
+		want  []int64
+	}{
+		// 3 and 5 point the same way; equal scores are ordered by id.
+		{"all", 10, []int64{3, 5, 2, 1, 4}},
+		{"limited", 2, []int64{3, 5}},
+	}
+	for _, tt := range tests {
+		hits, err := m.Search(c, []float32{1, 0}, Filter{}, tt.limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []int64
+		for _, h := range hits {
+			got = append(got, h.ID)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+		if hits[0].Score < 0.999 || hits[0].Score > 1.001 {
+			t.Errorf("%s: identical direction scored %v, want 1", tt.name, hits[0].Score)
+		}
+	}
+}
+
+func TestMemoryScroll(t *testing.T) {
+	m := memoryFixture(t)
+	c := context.Background()
+
+	tests := []struct {
+		name   string
+		filter Filter
+		limit  int
+		pages  [][]int64
+	}{
+		{"one page", Filter{}, 10, [][]int64{{1, 2, 3, 4, 5}}},
+		{"exact pages", Filter{}, 5, [][]int64{{1, 2, 3, 4, 5}}},
+		{"several pages", Filter{}, 2, [][]int64{{1, 2}, {3, 4}, {5}}},
+		{"filtered", Filter{UserID: 8}, 1, [][]int64{{4}, {5}}},
+		{"empty", Filter{City: "Berlin"}, 2, [][]int64{nil}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var from int64
+			for i, want := range tt.pages {
+				points, next, err := m.Scroll(c, tt.filter, from, tt.limit)
+				if err != nil {
+					t.Fatal(err)
+				}
+				var got []int64
+				for _, p := range points {
+					got = append(got, p.ID)
+				}
+				if !slices.Equal(got, want) {
+					t.Fatalf("page %d: got %v, want %v", i, got, want)
+				}
+				if last := i == len(tt.pages)-1; last != (next == 0) {
+					t.Fatalf("page %d: next %d, last page %v", i, next, last)
+				}
+				from = next
+			}
+		})
+	}
+}
+
+func TestMemoryScrollCopiesVectors(t *testing.T) {
+	m := memoryFixture(t)
+	points, _, _ := m.Scroll(context.Background(), Filter{IDs: []int64{1}}, 0, 1)
+	points[0].Vector[0] = 42
+
+	again, _, _ := m.Scroll(context.Background(), Filter{IDs: []int64{1}}, 0, 1)
+	if again[0].Vector[0] != 1 {
+		t.Errorf("changing a scrolled vector changed the index: %v", again[0].Vector)
+	}
+}
+
+func TestMemoryUpsertDimensions(t *testing.T) {
+	c := context.Background()
+	tests := []struct {
+		name    string
+		batches [][]Point
+		wantErr bool
+		want    int64
+	}{
+		{"first vector sets the size", [][]Point{
+			{{ID: 1, Vector: []float32{1, 2}}},
+			{{ID: 2, Vector: []float32{3, 4}}},
+		}, false, 2},
+		{"mismatch against stored points", [][]Point{
+			{{ID: 1, Vector: []float32{1, 2}}},
+			{{ID: 2, Vector: []float32{1, 2, 3}}},
+		}, true, 1},
+		{"mismatch within a batch stores nothing", [][]Point{
+			{{ID: 1, Vector: []float32{1, 2}}, {ID: 2, Vector: []float32{1}}},
+		}, true, 0},
+		{"size is kept after deleting everything", [][]Point{
+			{{ID: 1, Vector: []float32{1, 2}}},
+			nil,
+			{{ID: 2, Vector: []float32{1, 2, 3}}},
+		}, true, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMemory()
+			var err error
+			for _, batch := range tt.batches {
+				if batch == nil {
+					m.Delete(c, 1)
+					continue
+				}
+				if err = m.Upsert(c, batch...); err != nil {
+					break
+				}
+			}
+			if tt.wantErr != errors.Is(err, ErrDimensionMismatch) {
+				t.Errorf("got error %v, want mismatch %v", err, tt.wantErr)
+			}
+			if n, _ := m.Count(c, Filter{}); n != tt.want {
+				t.Errorf("stored %d points, want %d", n, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,390 @@
+package vectorindex
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PgVector keeps the embeddings in a Postgres table using the pgvector
+// extension, so small installs don't need a separate vector database.
+// Filters are evaluated in SQL next to the HNSW scan. The table comes from
+// the migrations, or from Generation for a reindex; a missing one reads as
+// empty and fails writes.
+type PgVector struct {
+	db    *pgxpool.Pool
+	name  string
+	table string
+
+	mu          sync.Mutex
+	ready       bool
+	scanChecked bool
+	iterative   bool
+}
+
+func NewPgVector(db *pgxpool.Pool, table string) *PgVector {
+	return &PgVector{db: db, name: table, table: pgx.Identifier{table}.Sanitize()}
+}
+
+// vectorLiteral renders v in pgvector's text format.
+func vectorLiteral(v []float32) string {
+	var b strings.Builder
+	b.WriteByte('[')
+	for i, x := range v {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(strconv.FormatFloat(float64(x), 'g', -1, 32))
+	}
+	b.WriteByte(']')
+	return b.String()
+}
+
+func parseVector(s string) ([]float32, error) {
+	s = strings.TrimSuffix(strings.TrimPrefix(s, "["), "]")
+	if s == "" {
+		return nil, nil
+	}
+	parts := strings.Split(s, ",")
+	v := make([]float32, len(parts))
+	for i, p := range parts {
+		f, err := strconv.ParseFloat(p, 32)
+		if err != nil {
+			return nil, fmt.Errorf("pgvector: parse vector: %w", err)
+		}
+		v[i] = float32(f)
+	}
+	return v, nil
+}
+
+// ensureTable checks before the first write that the table exists, so a
+// missing one fails with a hint rather than a bare SQL error.
+func (p *PgVector) ensureTable(c context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.ready {
+		return nil
+	}
+
+	var exists bool
+	if err := p.db.QueryRow(c, `SELECT to_regclass($1) IS NOT NULL`, p.table).Scan(&exists); err != nil {
+		return fmt.Errorf("pgvector: check %s: %w", p.name, err)
+	}
+	if !exists {
+		return fmt.Errorf("pgvector: table %s doesn't exist; the migrations create photo_embeddings where the vector extension is available", p.name)
+	}
+	p.ready = true
+	return nil
+}
+
+// missing reports a query against a table that hasn't been created yet,
+// which reads as empty.
+func missing(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "42P01"
+}
+
+// pgvectorError maps pgvector's dimension check to ErrDimensionMismatch.
+func pgvectorError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && strings.Contains(pgErr.Message, "dimensions") {
+		return fmt.Errorf("%w: %s", ErrDimensionMismatch, pgErr.Message)
+	}
+	return err
+}
+
+func (p *PgVector) Upsert(c context.Context, points ...Point) error {
+	if len(points) == 0 {
+		return nil
+	}
+	if err := p.ensureTable(c); err != nil {
+		return err
+	}
+
+	batch := &pgx.Batch{}
+	for _, pt := range points {
+		batch.Queue(
+			`INSERT INTO `+p.table+`(photo_id,user_id,created_at,note,caption,city,country,media_type,embedding)
+			 VALUES($1,$2,to_timestamp($3) AT TIME ZONE 'UTC',$4,$5,$6,$7,$8,$9::vector)
+			 ON CONFLICT (photo_id) DO UPDATE SET user_id=$2, created_at=to_timestamp($3) AT TIME ZONE 'UTC',
+			   note=$4, caption=$5, city=$6, country=$7, media_type=$8, embedding=$9::vector`,
+			pt.ID, pt.Payload.UserID, pt.Payload.CreatedAt, pt.Payload.Note, pt.Payload.Caption,
+			pt.Payload.City, pt.Payload.Country, pt.Payload.MediaType, vectorLiteral(pt.Vector))
+	}
+	if err := p.db.SendBatch(c, batch).Close(); err != nil {
+		return fmt.Errorf("pgvector: upsert: %w", pgvectorError(err))
+	}
+	return nil
+}
+
+func (p *PgVector) Delete(c context.Context, ids ...int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	if _, err := p.db.Exec(c, `DELETE FROM `+p.table+` WHERE photo_id = ANY($1)`, ids); err != nil && !missing(err) {
+		return fmt.Errorf("pgvector: delete: %w", err)
+	}
+	return nil
+}
+
+// efSearch is the HNSW candidate list used for filtered searches on
+// pgvector releases without iterative scans. It is the largest pgvector
+// accepts; the default of 40 leaves a selective filter with few or no rows.
+const efSearch = 1000
+
+// supportsIterativeScan reports whether a pgvector release has
+// hnsw.iterative_scan, which arrived in 0.8.0.
+func supportsIterativeScan(version string) bool {
+	parts := strings.SplitN(version, ".", 3)
+	if len(parts) < 2 {
+		return false
+	}
+	major, err1 := strconv.Atoi(parts[0])
+	minor, err2 := strconv.Atoi(parts[1])
+	if err1 != nil || err2 != nil {
+		return false
+	}
+	return major > 0 || minor >= 8
+}
+
+// iterativeScan reports whether the installed extension can keep scanning
+// the HNSW index until a filtered query has enough rows. The answer is
+// cached once the extension exists.
+func (p *PgVector) iterativeScan(c context.Context, q pgx.Tx) (bool, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.scanChecked {
+		return p.iterative, nil
+	}
+	var version string
+	err := q.QueryRow(c, `SELECT extversion FROM pg_extension WHERE extname = 'vector'`).Scan(&version)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	p.scanChecked, p.iterative = true, supportsIterativeScan(version)
+	return p.iterative, nil
+}
+
+// Search ranks by cosine similarity. The HNSW index applies the filter to
+// the candidates it has found rather than while it walks the graph, so a
+// selective filter could leave fewer than limit hits. Searches by id list
+// therefore rank the listed rows exactly, and other filtered searches let
+// the scan continue until it has limit matches (pgvector 0.8 and later) or
+// widen the candidate list.
+func (p *PgVector) Search(c context.Context, vector []float32, f Filter, limit int) ([]Hit, error) {
+	where, args := pgvectorFilter(f, []any{vectorLiteral(vector), limit})
+
+	var query string
+	if len(f.IDs) > 0 {
+		query = `WITH candidates AS MATERIALIZED (SELECT photo_id, embedding FROM ` + p.table + where + `)
+			SELECT photo_id, 1 - (embedding <=> $1::vector) FROM candidates
+			ORDER BY embedding <=> $1::vector, photo_id LIMIT $2`
+	} else {
+		// A relaxed iterative scan can return rows slightly out of order,
+		// so they are sorted again once found.
+		query = `WITH hits AS MATERIALIZED (
+				SELECT photo_id, embedding <=> $1::vector AS distance FROM ` + p.table + where + `
+				ORDER BY distance LIMIT $2)
+			SELECT photo_id, 1 - distance FROM hits ORDER BY distance, photo_id`
+	}
+
+	var hits []Hit
+	err := pgx.BeginFunc(c, p.db, func(tx pgx.Tx) error {
+		if where != "" && len(f.IDs) == 0 {
+			iterative, err := p.iterativeScan(c, tx)
+			if err != nil {
+				return err
+			}
+			setting := `SET LOCAL hnsw.ef_search = ` + strconv.Itoa(efSearch)
+			if iterative {
+				setting = `SET LOCAL hnsw.iterative_scan = relaxed_order`
+			}
+			if _, err := tx.Exec(c, setting); err != nil {
+				return err
+			}
+		}
+
+		rows, err := tx.Query(c, query, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var h Hit
+			if err := rows.Scan(&h.ID, &h.Score); err != nil {
+				return err
+			}
+			hits = append(hits, h)
+		}
+		return rows.Err()
+	})
+	if missing(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("pgvector: search: %w", pgvectorError(err))
+	}
+	return hits, nil
+}
+
+func (p *PgVector) Count(c context.Context, f Filter) (int64, error) {
+	where, args := pgvectorFilter(f, nil)
+	var n int64
+	err := p.db.QueryRow(c, `SELECT count(*) FROM `+p.table+where, args...).Scan(&n)
+	if missing(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("pgvector: count: %w", err)
+	}
+	return n, nil
+}
+
+func (p *PgVector) Scroll(c context.Context, f Filter, from int64, limit int) ([]Point, int64, error) {
+	where, args := pgvectorFilter(f, []any{from, limit + 1})
+	if where == "" {
+		where = " WHERE photo_id >= $1"
+	} else {
+		where += " AND photo_id >= $1"
+	}
+
+	rows, err := p.db.Query(c,
+		`SELECT photo_id,user_id,created_at,note,caption,city,country,media_type,embedding::text
+		 FROM `+p.table+where+` ORDER BY photo_id LIMIT $2`,
+		args...)
+	if missing(err) {
+		return nil, 0, nil
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("pgvector: scroll: %w", err)
+	}
+	defer rows.Close()
+
+	var points []Point
+	for rows.Next() {
+		var (
+			pt        Point
+			createdAt time.Time
+			embedding string
+		)
+		err := rows.Scan(&pt.ID, &pt.Payload.UserID, &createdAt, &pt.Payload.Note, &pt.Payload.Caption,
+			&pt.Payload.City, &pt.Payload.Country, &pt.Payload.MediaType, &embedding)
+		if err != nil {
+			return nil, 0, fmt.Errorf("pgvector: scroll: %w", err)
+		}
+		pt.Payload.CreatedAt = createdAt.Unix()
+		if pt.Vector, err = parseVector(embedding); err != nil {
+			return nil, 0, err
+		}
+		points = append(points, pt)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("pgvector: scroll: %w", err)
+	}
+
+	var next int64
+	if len(points) > limit {
+		next = points[limit].ID
+		points = points[:limit]
+	}
+	return points, next, nil
+}
+
+// pgvectorFilter renders f as a WHERE clause, appending its arguments to
+// args.
+func pgvectorFilter(f Filter, args []any) (string, []any) {
+	var preds []string
+	add := func(pred string, arg any) {
+		args = append(args, arg)
+		preds = append(preds, fmt.Sprintf(pred, len(args)))
+	}
+
+	if f.UserID != 0 {
+		add("user_id = $%d", f.UserID)
+	}
+	if len(f.IDs) > 0 {
+		add("photo_id = ANY($%d)", f.IDs)
+	}
+	if len(f.ExcludeIDs) > 0 {
+		add("NOT (photo_id = ANY($%d))", f.ExcludeIDs)
+	}
+	if f.From != nil {
+		add("created_at >= $%d", f.From.UTC())
+	}
+	if f.To != nil {
+		add("created_at < $%d", f.To.UTC())
+	}
+	if f.City != "" {
+		add("lower(city) = lower($%d)", strings.TrimSpace(f.City))
+	}
+	if f.Country != "" {
+		add("lower(country) = lower($%d)", strings.TrimSpace(f.Country))
+	}
+	if f.MediaType != "" {
+		add("media_type = $%d", f.MediaType)
+	}
+	if f.HasNote != nil {
+		add("(btrim(note) <> '') = $%d", *f.HasNote)
+	}
+
+	if len(preds) == 0 {
+		return "", args
+	}
+	return " WHERE " + strings.Join(preds, " AND "), args
+}
+
+// Generation returns an index on the table called name, creating it as a
+// copy of the live table's columns and indexes if it doesn't exist yet. A
+// model with other dimensions needs a migration of the live table first.
+func (p *PgVector) Generation(c context.Context, name string) (VectorIndex, error) {
+	gen := NewPgVector(p.db, name)
+	if _, err := p.db.Exec(c, `CREATE TABLE IF NOT EXISTS `+gen.table+` (LIKE `+p.table+` INCLUDING ALL)`); err != nil {
+		return nil, fmt.Errorf("pgvector: create %s: %w", name, err)
+	}
+	return gen, nil
+}
+
+// Promote swaps the generation table in under the live name in one
+// transaction. The previous table is kept as <table>_retired for rollback,
+// replacing the one retired before it.
+func (p *PgVector) Promote(c context.Context, name string) error {
+	tx, err := p.db.Begin(c)
+	if err != nil {
+		return fmt.Errorf("pgvector: promote: %w", err)
+	}
+	defer tx.Rollback(c)
+
+	retired := pgx.Identifier{p.name + "_retired"}.Sanitize()
+	if _, err := tx.Exec(c, `DROP TABLE IF EXISTS `+retired); err != nil {
+		return fmt.Errorf("pgvector: promote: %w", err)
+	}
+	if _, err := tx.Exec(c, `ALTER TABLE IF EXISTS `+p.table+` RENAME TO `+retired); err != nil {
+		return fmt.Errorf("pgvector: promote: %w", err)
+	}
+	if _, err := tx.Exec(c, `ALTER TABLE `+pgx.Identifier{name}.Sanitize()+` RENAME TO `+p.table); err != nil {
+		return fmt.Errorf("pgvector: promote: %w", err)
+	}
+	if err := tx.Commit(c); err != nil {
+		return fmt.Errorf("pgvector: promote: %w", err)
+	}
+	p.mu.Lock()
+	p.ready = true
+	p.mu.Unlock()
+	return nil
+}
+
+var (
+	_ VectorIndex = (*PgVector)(nil)
+	_ Generations = (*PgVector)(nil)
+)
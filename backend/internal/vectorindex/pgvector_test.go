@@ -0,0 +1,144 @@
+package vectorindex
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func TestSupportsIterativeScan(t *testing.T) {
+	tests := []struct {
+		version string
+		want    bool
+	}{
+		{"0.5.1", false},
+		{"0.7.4", false},
+		{"0.8.0", true},
+		{"0.10.0", true},
+		{"1.0", true},
+		{"", false},
+		{"dev", false},
+	}
+	for _, tt := range tests {
+		if got := supportsIterativeScan(tt.version); got != tt.want {
+			t.Errorf("supportsIterativeScan(%q) = %v, want %v", tt.version, got, tt.want)
+		}
+	}
+}
+
+// pgvectorTestDB connects to TEST_DATABASE_URL and creates the table
+// vectorindex_test for two dimensional vectors, shaped like the one the
+// migrations create. The database needs the vector extension available.
+func pgvectorTestDB(t *testing.T) *pgxpool.Pool {
+	t.Helper()
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	c := context.Background()
+	db, err := pgxpool.New(c, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	drop := func() {
+		db.Exec(c, `DROP TABLE IF EXISTS vectorindex_test, vectorindex_test_v1, vectorindex_test_retired, vectorindex_missing`)
+	}
+	drop()
+	t.Cleanup(func() {
+		drop()
+		db.Close()
+	})
+
+	_, err = db.Exec(c, `CREATE EXTENSION IF NOT EXISTS vector;
+		CREATE TABLE vectorindex_test (
+		  photo_id    BIGINT PRIMARY KEY,
+		  user_id     BIGINT NOT NULL,
+		  created_at  TIMESTAMP NOT NULL,
+		  note        TEXT NOT NULL DEFAULT '',
+		  caption     TEXT NOT NULL DEFAULT '',
+		  city        TEXT NOT NULL DEFAULT '',
+		  country     TEXT NOT NULL DEFAULT '',
+		  media_type  TEXT NOT NULL DEFAULT 'image',
+		  embedding   vector(2) NOT NULL
+		);
+		CREATE INDEX ON vectorindex_test USING hnsw (embedding vector_cosine_ops);
+		CREATE INDEX ON vectorindex_test(user_id, created_at);`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestPgVectorTables(t *testing.T) {
+	db := pgvectorTestDB(t)
+	c := context.Background()
+	pt := Point{ID: 1, Vector: []float32{1, 0}, Payload: Payload{UserID: 1}}
+
+	// Writes don't create a table that the migrations didn't.
+	if err := NewPgVector(db, "vectorindex_missing").Upsert(c, pt); err == nil {
+		t.Error("writing to a missing table succeeded")
+	}
+
+	live := NewPgVector(db, "vectorindex_test")
+	gen, err := live.Generation(c, "vectorindex_test_v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := gen.Upsert(c, pt); err != nil {
+		t.Fatal(err)
+	}
+	if err := live.Promote(c, "vectorindex_test_v1"); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := live.Count(c, Filter{}); err != nil || n != 1 {
+		t.Errorf("promoted generation has %d points, %v", n, err)
+	}
+}
+
+// TestPgVectorSelectiveFilter searches for a user whose few photos all lie
+// far from the query, behind many closer photos of another user, which a
+// plain HNSW scan would filter away.
+func TestPgVectorSelectiveFilter(t *testing.T) {
+	db := pgvectorTestDB(t)
+	c := context.Background()
+
+	p := NewPgVector(db, "vectorindex_test")
+	var points []Point
+	for i := int64(1); i <= 2000; i++ {
+		points = append(points, Point{ID: i, Vector: []float32{1, float32(i) / 1e4}, Payload: Payload{UserID: 1}})
+	}
+	for i := int64(1); i <= 5; i++ {
+		points = append(points, Point{ID: 10000 + i, Vector: []float32{-1, float32(i)}, Payload: Payload{UserID: 2}})
+	}
+	if err := p.Upsert(c, points...); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		filter Filter
+		limit  int
+		want   int
+	}{
+		{"user", Filter{UserID: 2}, 10, 5},
+		{"ids", Filter{UserID: 2, IDs: []int64{10001, 10003}}, 10, 2},
+		{"unfiltered", Filter{}, 10, 10},
+	}
+	for _, tt := range tests {
+		hits, err := p.Search(c, []float32{1, 0}, tt.filter, tt.limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(hits) != tt.want {
+			t.Errorf("%s: got %d hits, want %d", tt.name, len(hits), tt.want)
+		}
+		for i := 1; i < len(hits); i++ {
+			if hits[i].Score > hits[i-1].Score {
+				t.Errorf("%s: hits out of order: %v", tt.name, hits)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,420 @@
+package vectorindex
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Qdrant talks to a Qdrant server over its REST API. The collection is
+// created on first write, sized to the first vector, with payload indexes on
+// the filtered fields. The live index is an alias, so the collection behind
+// it can be swapped by Promote.
+type Qdrant struct {
+	baseURL    string
+	collection string
+	apiKey     string
+	client     *http.Client
+	// aliased is set on the live index, whose collection is created as
+	// <collection>_v0 behind an alias named collection.
+	aliased bool
+
+	mu    sync.Mutex
+	ready bool
+}
+
+func NewQdrant(baseURL, collection, apiKey string) *Qdrant {
+	return &Qdrant{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		collection: collection,
+		apiKey:     apiKey,
+		aliased:    true,
+		client: &http.Client{
+			Timeout: 30 * time.Second,
+			Transport: &http.Transport{
+				MaxIdleConns:        32,
+				MaxIdleConnsPerHost: 32,
+				IdleConnTimeout:     90 * time.Second,
+			},
+		},
+	}
+}
+
+// qdrantPayload is the stored payload. The lowercased keys back the case
+// insensitive place filters and has_note saves a text condition.
+type qdrantPayload struct {
+	Payload
+	CityKey    string `json:"city_key"`
+	CountryKey string `json:"country_key"`
+	HasNote    bool   `json:"has_note"`
+}
+
+type qdrantPoint struct {
+	ID      int64         `json:"id"`
+	Vector  []float32     `json:"vector,omitempty"`
+	Payload qdrantPayload `json:"payload"`
+}
+
+type qdrantError struct {
+	Status int
+	Body   string
+}
+
+func (e *qdrantError) Error() string {
+	return fmt.Sprintf("qdrant: %d %s", e.Status, e.Body)
+}
+
+func (q *Qdrant) do(c context.Context, method, path string, body, out any) error {
+	var r io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("qdrant: encode request: %w", err)
+		}
+		r = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequestWithContext(c, method, q.baseURL+path, r)
+	if err != nil {
+		return fmt.Errorf("qdrant: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if q.apiKey != "" {
+		req.Header.Set("api-key", q.apiKey)
+	}
+
+	resp, err := q.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("qdrant: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return &qdrantError{Status: resp.StatusCode, Body: strings.TrimSpace(string(msg))}
+	}
+	if out == nil {
+		return nil
+	}
+	var envelope struct {
+		Result json.RawMessage `json:"result"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&envelope); err != nil {
+		return fmt.Errorf("qdrant: decode response: %w", err)
+	}
+	if err := json.Unmarshal(envelope.Result, out); err != nil {
+		return fmt.Errorf("qdrant: decode result: %w", err)
+	}
+	return nil
+}
+
+func (q *Qdrant) points(suffix string) string {
+	return "/collections/" + url.PathEscape(q.collection) + "/points" + suffix
+}
+
+// ensureCollection creates the collection and its payload indexes unless it
+// already exists, and the alias in front of it for the live index.
+func (q *Qdrant) ensureCollection(c context.Context, dims int) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.ready {
+		return nil
+	}
+
+	err := q.do(c, http.MethodGet, "/collections/"+url.PathEscape(q.collection), nil, nil)
+	if q.missing(err) {
+		name := q.collection
+		if q.aliased {
+			name += "_v0"
+		}
+		if err := q.createCollection(c, name, dims); err != nil {
+			return err
+		}
+		if q.aliased {
+			err := q.updateAliases(c, map[string]any{
+				"create_alias": map[string]any{"collection_name": name, "alias_name": q.collection},
+			})
+			if err != nil {
+				return err
+			}
+		}
+	} else if err != nil {
+		return err
+	}
+
+	q.ready = true
+	return nil
+}
+
+// createCollection creates the collection called name with its payload
+// indexes. One left over from an attempt that failed halfway is reused.
+func (q *Qdrant) createCollection(c context.Context, name string, dims int) error {
+	path := "/collections/" + url.PathEscape(name)
+	err := q.do(c, http.MethodGet, path, nil, nil)
+	if err == nil || !q.missing(err) {
+		return err
+	}
+
+	err = q.do(c, http.MethodPut, path, map[string]any{
+		"vectors": map[string]any{"size": dims, "distance": "Cosine"},
+	}, nil)
+	if err != nil {
+		return err
+	}
+	for field, schema := range map[string]string{
+		"user_id":     "integer",
+		"created_at":  "integer",
+		"city_key":    "keyword",
+		"country_key": "keyword",
+		"media_type":  "keyword",
+		"has_note":    "bool",
+	} {
+		err := q.do(c, http.MethodPut, path+"/index?wait=true", map[string]any{
+			"field_name":   field,
+			"field_schema": schema,
+		}, nil)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// updateAliases applies the alias actions in one atomic request.
+func (q *Qdrant) updateAliases(c context.Context, actions ...map[string]any) error {
+	return q.do(c, http.MethodPost, "/collections/aliases", map[string]any{"actions": actions}, nil)
+}
+
+func (q *Qdrant) Upsert(c context.Context, points ...Point) error {
+	if len(points) == 0 {
+		return nil
+	}
+	if err := q.ensureCollection(c, len(points[0].Vector)); err != nil {
+		return err
+	}
+
+	body := make([]qdrantPoint, len(points))
+	for i, p := range points {
+		body[i] = qdrantPoint{
+			ID:     p.ID,
+			Vector: p.Vector,
+			Payload: qdrantPayload{
+				Payload:    p.Payload,
+				CityKey:    strings.ToLower(strings.TrimSpace(p.Payload.City)),
+				CountryKey: strings.ToLower(strings.TrimSpace(p.Payload.Country)),
+				HasNote:    strings.TrimSpace(p.Payload.Note) != "",
+			},
+		}
+	}
+	err := q.do(c, http.MethodPut, q.points("?wait=true"), map[string]any{"points": body}, nil)
+	var qerr *qdrantError
+	if errors.As(err, &qerr) && qerr.Status == http.StatusBadRequest && strings.Contains(qerr.Body, "dimension") {
+		return fmt.Errorf("%w: %s", ErrDimensionMismatch, qerr.Body)
+	}
+	return err
+}
+
+func (q *Qdrant) Delete(c context.Context, ids ...int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return q.do(c, http.MethodPost, q.points("/delete?wait=true"), map[string]any{"points": ids}, nil)
+}
+
+func (q *Qdrant) Search(c context.Context, vector []float32, f Filter, limit int) ([]Hit, error) {
+	var hits []Hit
+	err := q.do(c, http.MethodPost, q.points("/search"), map[string]any{
+		"vector":       vector,
+		"filter":       qdrantFilter(f),
+		"limit":        limit,
+		"with_payload": false,
+	}, &hits)
+	if q.missing(err) {
+		return nil, nil
+	}
+	return hits, err
+}
+
+func (q *Qdrant) Count(c context.Context, f Filter) (int64, error) {
+	var res struct {
+		Count int64 `json:"count"`
+	}
+	err := q.do(c, http.MethodPost, q.points("/count"), map[string]any{
+		"filter": qdrantFilter(f),
+		"exact":  true,
+	}, &res)
+	if q.missing(err) {
+		return 0, nil
+	}
+	return res.Count, err
+}
+
+func (q *Qdrant) Scroll(c context.Context, f Filter, from int64, limit int) ([]Point, int64, error) {
+	body := map[string]any{
+		"filter":       qdrantFilter(f),
+		"limit":        limit,
+		"with_payload": true,
+		"with_vector":  true,
+	}
+	if from > 0 {
+		body["offset"] = from
+	}
+
+	var res struct {
+		Points         []qdrantPoint `json:"points"`
+		NextPageOffset *int64        `json:"next_page_offset"`
+	}
+	err := q.do(c, http.MethodPost, q.points("/scroll"), body, &res)
+	if q.missing(err) {
+		return nil, 0, nil
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+
+	points := make([]Point, len(res.Points))
+	for i, p := range res.Points {
+		points[i] = Point{ID: p.ID, Vector: p.Vector, Payload: p.Payload.Payload}
+	}
+	var next int64
+	if res.NextPageOffset != nil {
+		next = *res.NextPageOffset
+	}
+	return points, next, nil
+}
+
+// missing reports a query against a collection that hasn't been created
+// yet, which reads as empty.
+func (q *Qdrant) missing(err error) bool {
+	var qerr *qdrantError
+	return errors.As(err, &qerr) && qerr.Status == http.StatusNotFound
+}
+
+func qdrantFilter(f Filter) map[string]any {
+	var must, mustNot []map[string]any
+	match := func(key string, value any) {
+		must = append(must, map[string]any{"key": key, "match": map[string]any{"value": value}})
+	}
+
+	if f.UserID != 0 {
+		match("user_id", f.UserID)
+	}
+	if len(f.IDs) > 0 {
+		must = append(must, map[string]any{"has_id": f.IDs})
+	}
+	if len(f.ExcludeIDs) > 0 {
+		mustNot = append(mustNot, map[string]any{"has_id": f.ExcludeIDs})
+	}
+	if f.From != nil || f.To != nil {
+		r := map[string]any{}
+		if f.From != nil {
+			r["gte"] = f.From.Unix()
+		}
+		if f.To != nil {
+			r["lt"] = f.To.Unix()
+		}
+		must = append(must, map[string]any{"key": "created_at", "range": r})
+	}
+	if f.City != "" {
+		match("city_key", strings.ToLower(strings.TrimSpace(f.City)))
+	}
+	if f.Country != "" {
+		match("country_key", strings.ToLower(strings.TrimSpace(f.Country)))
+	}
+	if f.MediaType != "" {
+		match("media_type", f.MediaType)
+	}
+	if f.HasNote != nil {
+		match("has_note", *f.HasNote)
+	}
+
+	if len(must) == 0 && len(mustNot) == 0 {
+		return nil
+	}
+	filter := map[string]any{}
+	if len(must) > 0 {
+		filter["must"] = must
+	}
+	if len(mustNot) > 0 {
+		filter["must_not"] = mustNot
+	}
+	return filter
+}
+
+// Generation returns an index on the collection called name. It is created
+// on first write like the live one, but without an alias.
+func (q *Qdrant) Generation(_ context.Context, name string) (VectorIndex, error) {
+	g := NewQdrant(q.baseURL, name, q.apiKey)
+	g.aliased = false
+	return g, nil
+}
+
+// Promote points the alias named after the live collection at name and then
+// deletes the collection it led to before. Moving the alias is atomic.
+//
+// A real collection holding the live name, from before the live index was an
+// alias, has to be deleted before the alias can be created, so searches come
+// back empty until that succeeds. The vectors are safe in name meanwhile, and
+// promoting again finishes the switch.
+func (q *Qdrant) Promote(c context.Context, name string) error {
+	var res struct {
+		Aliases []struct {
+			AliasName      string `json:"alias_name"`
+			CollectionName string `json:"collection_name"`
+		} `json:"aliases"`
+	}
+	if err := q.do(c, http.MethodGet, "/aliases", nil, &res); err != nil {
+		return err
+	}
+
+	previous, isAlias := "", false
+	for _, a := range res.Aliases {
+		if a.AliasName == q.collection {
+			previous, isAlias = a.CollectionName, true
+			break
+		}
+	}
+
+	var actions []map[string]any
+	if isAlias {
+		actions = append(actions, map[string]any{"delete_alias": map[string]any{"alias_name": q.collection}})
+	} else {
+		err := q.do(c, http.MethodDelete, "/collections/"+url.PathEscape(q.collection), nil, nil)
+		if err != nil && !q.missing(err) {
+			return err
+		}
+	}
+	actions = append(actions, map[string]any{
+		"create_alias": map[string]any{"collection_name": name, "alias_name": q.collection},
+	})
+	if err := q.updateAliases(c, actions...); err != nil {
+		return err
+	}
+	// Collection requests resolve aliases, so the alias now leads to an
+	// existing collection.
+	q.mu.Lock()
+	q.ready = true
+	q.mu.Unlock()
+
+	if previous == "" || previous == name {
+		return nil
+	}
+	err := q.do(c, http.MethodDelete, "/collections/"+url.PathEscape(previous), nil, nil)
+	if err != nil && !q.missing(err) {
+		return fmt.Errorf("promoted %s but could not delete the previous generation %s: %w", name, previous, err)
+	}
+	return nil
+}
+
+var (
+	_ VectorIndex = (*Qdrant)(nil)
+	_ Generations = (*Qdrant)(nil)
+)
@@ -0,0 +1,173 @@
+package vectorindex
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeQdrant keeps just enough state to follow collections and aliases.
+type fakeQdrant struct {
+	mu          sync.Mutex
+	collections map[string]bool
+	aliases     map[string]string
+	failAliases bool
+}
+
+func newFakeQdrant(t *testing.T) (*fakeQdrant, *httptest.Server) {
+	f := &fakeQdrant{collections: map[string]bool{}, aliases: map[string]string{}}
+	srv := httptest.NewServer(http.HandlerFunc(f.serve))
+	t.Cleanup(srv.Close)
+	return f, srv
+}
+
+func (f *fakeQdrant) serve(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	body, _ := io.ReadAll(r.Body)
+	reply := func(result any) {
+		json.NewEncoder(w).Encode(map[string]any{"result": result})
+	}
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	switch {
+	case r.URL.Path == "/aliases":
+		var list []map[string]string
+		for alias, name := range f.aliases {
+			list = append(list, map[string]string{"alias_name": alias, "collection_name": name})
+		}
+		reply(map[string]any{"aliases": list})
+	case r.URL.Path == "/collections/aliases":
+		if f.failAliases {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		var req struct {
+			Actions []map[string]map[string]string `json:"actions"`
+		}
+		json.Unmarshal(body, &req)
+		for _, a := range req.Actions {
+			if d, ok := a["delete_alias"]; ok {
+				delete(f.aliases, d["alias_name"])
+			}
+			if cr, ok := a["create_alias"]; ok {
+				f.aliases[cr["alias_name"]] = cr["collection_name"]
+			}
+		}
+		reply(true)
+	case len(parts) >= 2 && parts[0] == "collections":
+		name := parts[1]
+		if target, ok := f.aliases[name]; ok {
+			name = target
+		}
+		switch {
+		case len(parts) > 2:
+			if !f.collections[name] {
+				http.NotFound(w, r)
+				return
+			}
+			reply(map[string]any{})
+		case r.Method == http.MethodGet:
+			if !f.collections[name] {
+				http.NotFound(w, r)
+				return
+			}
+			reply(map[string]any{})
+		case r.Method == http.MethodPut:
+			f.collections[name] = true
+			reply(true)
+		case r.Method == http.MethodDelete:
+			delete(f.collections, name)
+			reply(true)
+		}
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (f *fakeQdrant) state() (map[string]bool, map[string]string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.collections, f.aliases
+}
+
+var testPoint = Point{ID: 1, Vector: []float32{1, 0}, Payload: Payload{UserID: 1}}
+
+func TestQdrantCreatesLiveCollectionBehindAlias(t *testing.T) {
+	f, srv := newFakeQdrant(t)
+	q := NewQdrant(srv.URL, "photos", "")
+	if err := q.Upsert(context.Background(), testPoint); err != nil {
+		t.Fatal(err)
+	}
+
+	collections, aliases := f.state()
+	if !collections["photos_v0"] || collections["photos"] || aliases["photos"] != "photos_v0" {
+		t.Errorf("collections %v, aliases %v", collections, aliases)
+	}
+}
+
+func TestQdrantPromote(t *testing.T) {
+	c := context.Background()
+	tests := []struct {
+		name   string
+		legacy bool
+	}{
+		{"alias", false},
+		{"legacy collection", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, srv := newFakeQdrant(t)
+			live := NewQdrant(srv.URL, "photos", "")
+			if tt.legacy {
+				f.collections["photos"] = true
+			} else if err := live.Upsert(c, testPoint); err != nil {
+				t.Fatal(err)
+			}
+
+			gen, _ := live.Generation(c, "photos_v7")
+			if err := gen.Upsert(c, testPoint); err != nil {
+				t.Fatal(err)
+			}
+			if err := live.Promote(c, "photos_v7"); err != nil {
+				t.Fatal(err)
+			}
+
+			collections, aliases := f.state()
+			if aliases["photos"] != "photos_v7" {
+				t.Errorf("alias leads to %q, want photos_v7", aliases["photos"])
+			}
+			if len(collections) != 1 || !collections["photos_v7"] {
+				t.Errorf("collections after promote: %v", collections)
+			}
+		})
+	}
+}
+
+func TestQdrantPromoteKeepsGenerationWhenAliasFails(t *testing.T) {
+	c := context.Background()
+	f, srv := newFakeQdrant(t)
+	live := NewQdrant(srv.URL, "photos", "")
+	if err := live.Upsert(c, testPoint); err != nil {
+		t.Fatal(err)
+	}
+	gen, _ := live.Generation(c, "photos_v7")
+	if err := gen.Upsert(c, testPoint); err != nil {
+		t.Fatal(err)
+	}
+
+	f.failAliases = true
+	if err := live.Promote(c, "photos_v7"); err == nil {
+		t.Fatal("promote succeeded although the alias update failed")
+	}
+	collections, aliases := f.state()
+	if !collections["photos_v0"] || !collections["photos_v7"] || aliases["photos"] != "photos_v0" {
+		t.Errorf("collections %v, aliases %v", collections, aliases)
+	}
+}
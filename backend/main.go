@@ -1,26 +1,57 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"strconv"
 
 	"github.com/Pranjal095/Memora/backend/config"
+	"github.com/Pranjal095/Memora/backend/internal/cli"
+	"github.com/Pranjal095/Memora/backend/internal/helpers"
+	"github.com/Pranjal095/Memora/backend/internal/migrate"
+	"github.com/Pranjal095/Memora/backend/internal/repository"
 	"github.com/Pranjal095/Memora/backend/internal/router"
 )
 
-func init() {
-	config.Init()
-}
-
 func main() {
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8000"
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "\033[31minvalid configuration:\033[0m\n%v\n", err)
+		os.Exit(1)
+	}
+	config.ConnectPSQL(cfg.DBURL)
+	helpers.OpenVectors(cfg)
+	helpers.OpenEmbedService(cfg)
+	helpers.OpenClassifier(cfg)
+
+	if len(os.Args) > 1 {
+		code := cli.Run(cfg, os.Args[1:])
+		config.DB.Close()
+		os.Exit(code)
 	}
 
+	port := strconv.Itoa(cfg.Port)
 	fmt.Printf("\033[1;36m%s\033[0m \033[1;32m%s%s\033[0m\n", "Server running on:", "http://localhost:", port)
 
-	r := router.SetupRouter()
+	if cfg.AutoMigrate {
+		ran, err := migrate.Up(context.Background(), config.DB, nil)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "migration failed: %v\n", err)
+			os.Exit(1)
+		}
+		if len(ran) > 0 {
+			fmt.Printf("applied %d migrations\n", len(ran))
+		}
+	}
+
+	helpers.StartAnalysisWorkers(context.Background(), cfg)
+	helpers.StartTusPurge(context.Background(), cfg.TusUploadDir, cfg.TusUploadExpiry)
+	helpers.StartIdempotencyPurge(context.Background())
+	helpers.StartInferenceProbe(context.Background())
+	helpers.StartEmbedProbe(context.Background())
+
+	r := router.SetupRouter(cfg, repository.NewPgUsers(config.DB), repository.NewPgPhotos(config.DB))
 	defer config.DB.Close()
 
 	r.Run(":" + port)
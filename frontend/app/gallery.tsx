@@ -25,6 +25,7 @@ interface Photo {
   id: number;
   url: string;
   note?: string;
+  caption?: string;
   created_at: string;
 }
 
@@ -96,9 +97,9 @@ export default function Photos() {
   const renderItem: ListRenderItem<Photo> = ({ item }) => (
     <View style={styles.card}>
       <Image source={{ uri: item.url }} style={styles.image} />
-      {item.note ? (
+      {item.note || item.caption ? (
         <View style={styles.overlay}>
-          <Text style={styles.note}>{item.note}</Text>
+          <Text style={styles.note}>{item.note || item.caption}</Text>
         </View>
       ) : null}
     </View>
@@ -24,10 +24,17 @@ interface Photo {
   created_at: string;
 }
 
-interface Hit {
-  id: string;
-  score: number;
-  payload: { note?: string; caption?: string };
+interface Interpreted {
+  field: 'date' | 'city' | 'country';
+  text: string;
+  value?: string;
+}
+
+interface SearchResponse {
+  query: string;
+  mode: 'keyword' | 'semantic' | 'hybrid';
+  interpreted: Interpreted[];
+  results: (Photo & { score: number })[];
 }
 
 export default function SearchScreen() {
@@ -36,6 +43,8 @@ export default function SearchScreen() {
   const [photos, setPhotos] = useState<Photo[]>([]);
   const [results, setResults] = useState<Photo[]>([]);
   const [loading, setLoading] = useState(false);
+  const [chips, setChips] = useState<Interpreted[]>([]);
+  const [ignored, setIgnored] = useState<string[]>([]);
 
   useEffect(() => {
     (async () => {
@@ -52,20 +61,22 @@ export default function SearchScreen() {
     })();
   }, []);
 
-  const handleSearch = async () => {
+  const handleSearch = async (ignore: string[] = []) => {
+    setIgnored(ignore);
     if (!query.trim()) {
       setResults(photos);
+      setChips([]);
       return;
     }
     setLoading(true);
     try {
       const token = await SecureStore.getItemAsync('token');
-      const { data } = await axios.get<Hit[]>(`${API}/search`, {
-        params: { q: query },
+      const { data } = await axios.get<SearchResponse>(`${API}/search`, {
+        params: { q: query, mode: 'hybrid', ignore: ignore.join(',') || undefined },
         headers: { Authorization: `Bearer ${token}` },
       });
-      const ids = data.map(h => parseInt(h.id, 10));
-      setResults(photos.filter(p => ids.includes(p.id)));
+      setResults(data.results);
+      setChips(data.interpreted);
     } catch (e) {
       console.error(e);
     } finally {
@@ -96,12 +107,25 @@ export default function SearchScreen() {
           placeholderTextColor="#888"
           value={query}
           onChangeText={setQuery}
-          onSubmitEditing={handleSearch}
+          onSubmitEditing={() => handleSearch()}
         />
-        <Pressable style={styles.button} onPress={handleSearch}>
+        <Pressable style={styles.button} onPress={() => handleSearch()}>
           <Text style={styles.buttonText}>Go</Text>
         </Pressable>
       </View>
+      {chips.length > 0 ? (
+        <View style={styles.chips}>
+          {chips.map(chip => (
+            <Pressable
+              key={chip.field}
+              style={styles.chip}
+              onPress={() => handleSearch([...ignored, chip.field])}
+            >
+              <Text style={styles.chipText}>{chip.value ?? chip.text} ✕</Text>
+            </Pressable>
+          ))}
+        </View>
+      ) : null}
       {loading ? (
         <ActivityIndicator size="large" color="#0ff" style={{ flex: 1 }} />
       ) : (
@@ -137,6 +161,18 @@ const styles = StyleSheet.create({
     borderRadius: 8
   },
   buttonText: { color: '#121212', fontWeight: '700' },
+  chips: { flexDirection: 'row', flexWrap: 'wrap', paddingHorizontal: 16 },
+  chip: {
+    backgroundColor: '#1e1e1e',
+    borderColor: '#0ff',
+    borderWidth: 1,
+    borderRadius: 16,
+    paddingHorizontal: 10,
+    paddingVertical: 4,
+    marginRight: 8,
+    marginBottom: 8
+  },
+  chipText: { color: '#0ff', fontSize: 12 },
   list: { paddingHorizontal: 8, paddingBottom: 16 },
   card: { flex: 1, margin: 8, borderRadius: 12, overflow: 'hidden' },
   image: { width: '100%', aspectRatio: 1 },